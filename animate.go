@@ -0,0 +1,92 @@
+package picrocess
+
+import (
+	"context"
+	"math"
+)
+
+// AnimateDelay is the delay, in 100ths of a second, given to every frame created by Animate (25 frames per second).
+const AnimateDelay = 4
+
+// Easing maps linear progress t (0 to 1) to eased progress, which usually also starts at 0 and ends at 1.
+type Easing func(t float64) float64
+
+// Linear moves at a constant speed.
+func Linear(t float64) float64 {
+	return t
+}
+
+// EaseIn starts slowly and speeds up towards the end.
+func EaseIn(t float64) float64 {
+	return t * t * t
+}
+
+// EaseOut starts quickly and slows down towards the end.
+func EaseOut(t float64) float64 {
+	t = 1 - t
+	return 1 - t*t*t
+}
+
+// EaseInOut starts slowly, speeds up in the middle, and slows down again towards the end.
+func EaseInOut(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	t = -2*t + 2
+	return 1 - t*t*t/2
+}
+
+// Bounce ends like a ball dropped on the floor, bouncing a few times before it settles.
+func Bounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	default:
+		t -= 2.625 / d
+		return n*t*t + 0.984375
+	}
+}
+
+// Elastic overshoots the end and springs back and forth around it before it settles.
+func Elastic(t float64) float64 {
+	if t <= 0 || t >= 1 {
+		return t
+	}
+	return math.Pow(2, -10*t)*math.Sin((t*10-0.75)*(2*math.Pi/3)) + 1
+}
+
+// Interpolate returns the value between from and to at progress t (0 to 1), shaped by the easing function.
+// If ease is nil, the value moves linearly.
+//
+// from: The value at t = 0.
+// to: The value at t = 1.
+// t: The progress of the animation, from 0 to 1.
+// ease: The easing function to apply to t.
+//
+// Returns: The interpolated value.
+func Interpolate(from, to, t float64, ease Easing) float64 {
+	if ease == nil {
+		ease = Linear
+	}
+	return from + (to-from)*ease(math.Max(0, math.Min(1, t)))
+}
+
+// Animate builds a GIF by calling fn once per frame with the progress of the animation,
+// so smooth motion does not require interpolation code by hand. Every frame gets a delay of AnimateDelay,
+// unless a different delay is given with WithDelay.
+//
+// frames: The number of frames to render.
+// fn: Renders the frame at progress t, which runs from 0 (first frame) to 1 (last frame).
+// opts: Optional settings; WithDelay sets the delay of every frame.
+//
+// Returns: A new GIF containing the rendered frames, or an error if the Renderer fails to render a frame.
+func Animate(frames int, fn func(t float64) *Image, opts ...Option) (*GIF, error) {
+	return AnimateContext(context.Background(), frames, fn, opts...)
+}
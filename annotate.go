@@ -0,0 +1,96 @@
+package picrocess
+
+import "math"
+
+// DrawArrow draws an arrow with anti-aliased edges, such as for pointing at a detail in a screenshot.
+//
+// from: The pixel the arrow starts at.
+// to: The pixel the tip of the arrowhead points at.
+// c: The color (RGBA) of the arrow.
+// thickness: The thickness of the shaft, in pixels.
+// headSize: The length of the arrowhead, in pixels. It is limited to the length of the arrow.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the arrow, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawArrow(from, to Offset, c RGBA, thickness float64, headSize uint, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawArrow(from, to, c, thickness, headSize)
+	}) {
+		return
+	}
+	x1, y1 := float64(from.W)+0.5, float64(from.H)+0.5
+	x2, y2 := float64(to.W)+0.5, float64(to.H)+0.5
+	length := math.Hypot(x2-x1, y2-y1)
+	if length == 0 || thickness <= 0 {
+		return
+	}
+	ux, uy := (x2-x1)/length, (y2-y1)/length
+	head := math.Min(float64(headSize), length)
+	// The head is at least wide enough to stand out from the shaft.
+	halfHead := math.Max(head/2, thickness)
+	baseX, baseY := x2-ux*head, y2-uy*head
+	path := NewPath()
+	if head < length {
+		// Let the shaft reach a little into the head so no seam shows between them.
+		endX, endY := baseX+ux*math.Min(head/2, 1), baseY+uy*math.Min(head/2, 1)
+		hw := thickness / 2
+		path.addPolygon(point{x1 - uy*hw, y1 + ux*hw}, point{endX - uy*hw, endY + ux*hw},
+			point{endX + uy*hw, endY - ux*hw}, point{x1 + uy*hw, y1 - ux*hw})
+	}
+	path.addPolygon(point{x2, y2}, point{baseX - uy*halfHead, baseY + ux*halfHead}, point{baseX + uy*halfHead, baseY - ux*halfHead})
+	i.FillPath(path, c, NonZero)
+}
+
+// DrawCallout draws a speech bubble: a rounded rectangle with a tail pointing at a spot of the image,
+// such as for labeling part of a screenshot. Text can then be drawn inside the rectangle.
+// The tail leaves the side of the rectangle that faces the tip; if the tip is inside the rectangle, there is no tail.
+//
+// r: The rectangle of the bubble body. Its edges are corners between pixels.
+// radius: The radius of the corners, in pixels. It is limited to half of the shorter side.
+// tip: The pixel the tail points at.
+// fill: The color (RGBA) of the bubble.
+// border: The color (RGBA) of the border, drawn around the outside of the bubble.
+// thickness: The thickness of the border, in pixels. If it is 0, no border is drawn.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the bubble, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawCallout(r Rect, radius uint, tip Offset, fill, border RGBA, thickness float64, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawCallout(r, radius, tip, fill, border, thickness)
+	}) {
+		return
+	}
+	if r.Dx() == 0 || r.Dy() == 0 {
+		return
+	}
+	x1, y1, x2, y2 := float64(r.W1), float64(r.H1), float64(r.W2), float64(r.H2)
+	rounded := math.Min(float64(radius), math.Min(x2-x1, y2-y1)/2)
+	// The tail winds the same way as the rounded rectangle, so NonZero fills their union.
+	path := NewPath().RoundedRect(x1, y1, x2-x1, y2-y1, rounded)
+	tx, ty := float64(tip.W)+0.5, float64(tip.H)+0.5
+	// Pick the side the tip is furthest beyond, and center the base of the tail on the point of that side nearest to the tip.
+	outX := math.Max(x1-tx, tx-x2)
+	outY := math.Max(y1-ty, ty-y2)
+	if outX > 0 || outY > 0 {
+		// The base sits inside the body, deep enough to hide the border of the tail's base.
+		depth := math.Max(2*thickness, 1)
+		if outY >= outX {
+			half := math.Max(math.Min((x2-x1)/2-rounded, math.Max(4, (x2-x1)/8)), math.Min(4, (x2-x1)/4))
+			cx := math.Max(x1+rounded+half, math.Min(x2-rounded-half, tx))
+			cy := y1 + depth
+			if ty > y2 {
+				cy = y2 - depth
+			}
+			path.addPolygon(point{cx - half, cy}, point{tx, ty}, point{cx + half, cy})
+		} else {
+			half := math.Max(math.Min((y2-y1)/2-rounded, math.Max(4, (y2-y1)/8)), math.Min(4, (y2-y1)/4))
+			cy := math.Max(y1+rounded+half, math.Min(y2-rounded-half, ty))
+			cx := x1 + depth
+			if tx > x2 {
+				cx = x2 - depth
+			}
+			path.addPolygon(point{cx, cy - half}, point{tx, ty}, point{cx, cy + half})
+		}
+	}
+	if thickness > 0 {
+		// Stroke twice as wide and cover the inner half with the fill, which also hides the seams between body and tail.
+		i.StrokePath(path, border, 2*thickness, CapRound, JoinRound)
+	}
+	i.FillPath(path, fill, NonZero)
+}
@@ -0,0 +1,368 @@
+package picrocess
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ansiColumns is the width of the screen ANSI art is drawn for.
+	ansiColumns = 80
+	// ansiCellWidth and ansiCellHeight are the size of a character cell of the VGA text mode, in pixels.
+	ansiCellWidth  = 8
+	ansiCellHeight = 16
+)
+
+// ansiPalette holds the 16 colors of the VGA text mode, in ANSI order (black, red, green, yellow, blue, magenta, cyan, white),
+// followed by their bright variants.
+var ansiPalette = [16]RGBA{
+	{0, 0, 0, 255}, {170, 0, 0, 255}, {0, 170, 0, 255}, {170, 85, 0, 255},
+	{0, 0, 170, 255}, {170, 0, 170, 255}, {0, 170, 170, 255}, {170, 170, 170, 255},
+	{85, 85, 85, 255}, {255, 85, 85, 255}, {85, 255, 85, 255}, {255, 255, 85, 255},
+	{85, 85, 255, 255}, {255, 85, 255, 255}, {85, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// cp437 holds the characters of code page 437 from 0x80 to 0xFF, the encoding of classic .ans files.
+var cp437 = []rune("ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ ")
+
+// ansiFallback maps accented letters the bundled font lacks to the letter they are based on.
+var ansiFallback = map[rune]rune{
+	'Ç': 'C', 'ü': 'u', 'é': 'e', 'â': 'a', 'ä': 'a', 'à': 'a', 'å': 'a', 'ç': 'c', 'ê': 'e', 'ë': 'e', 'è': 'e',
+	'ï': 'i', 'î': 'i', 'ì': 'i', 'Ä': 'A', 'Å': 'A', 'É': 'E', 'ô': 'o', 'ö': 'o', 'ò': 'o', 'û': 'u', 'ù': 'u',
+	'ÿ': 'y', 'Ö': 'O', 'Ü': 'U', 'á': 'a', 'í': 'i', 'ó': 'o', 'ú': 'u', 'ñ': 'n', 'Ñ': 'N', 'ß': 'B', 'µ': 'u',
+	'·': '.', '∙': '.', ' ': ' ',
+}
+
+// ansiBox holds the arms of the box-drawing characters as up, down, left, and right, where 1 is a single line and 2 a double line.
+var ansiBox = map[rune][4]uint8{
+	'│': {1, 1, 0, 0}, '┤': {1, 1, 1, 0}, '╡': {1, 1, 2, 0}, '╢': {2, 2, 1, 0}, '╖': {0, 2, 1, 0}, '╕': {0, 1, 2, 0},
+	'╣': {2, 2, 2, 0}, '║': {2, 2, 0, 0}, '╗': {0, 2, 2, 0}, '╝': {2, 0, 2, 0}, '╜': {2, 0, 1, 0}, '╛': {1, 0, 2, 0},
+	'┐': {0, 1, 1, 0}, '└': {1, 0, 0, 1}, '┴': {1, 0, 1, 1}, '┬': {0, 1, 1, 1}, '├': {1, 1, 0, 1}, '─': {0, 0, 1, 1},
+	'┼': {1, 1, 1, 1}, '╞': {1, 1, 0, 2}, '╟': {2, 2, 0, 1}, '╚': {2, 0, 0, 2}, '╔': {0, 2, 0, 2}, '╩': {2, 0, 2, 2},
+	'╦': {0, 2, 2, 2}, '╠': {2, 2, 0, 2}, '═': {0, 0, 2, 2}, '╬': {2, 2, 2, 2}, '╧': {1, 0, 2, 2}, '╨': {2, 0, 1, 1},
+	'╤': {0, 1, 2, 2}, '╥': {0, 2, 1, 1}, '╙': {2, 0, 0, 1}, '╘': {1, 0, 0, 2}, '╒': {0, 1, 0, 2}, '╓': {0, 2, 0, 1},
+	'╫': {2, 2, 1, 1}, '╪': {1, 1, 2, 2}, '┘': {1, 0, 1, 0}, '┌': {0, 1, 0, 1},
+}
+
+type ansiCell struct {
+	char   rune
+	fg, bg RGBA
+}
+
+// ansiColor is a color set by an SGR sequence: an index into ansiPalette, or a direct color if index is -1.
+type ansiColor struct {
+	index int
+	rgb   RGBA
+}
+
+// resolve returns the color, using the bright variant of palette colors if bright is set.
+func (c ansiColor) resolve(bright bool) RGBA {
+	if c.index < 0 {
+		return c.rgb
+	}
+	if bright && c.index < 8 {
+		return ansiPalette[c.index+8]
+	}
+	return ansiPalette[c.index]
+}
+
+// xterm256 returns a color of the 256-color palette: the 16 ANSI colors, a 6×6×6 color cube, and 24 grays.
+func xterm256(n int) ansiColor {
+	switch {
+	case n < 16:
+		return ansiColor{index: n}
+	case n < 232:
+		n -= 16
+		level := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		return ansiColor{index: -1, rgb: RGBA{level(n / 36), level(n / 6 % 6), level(n % 6), 255}}
+	default:
+		v := uint8(8 + (min(n, 255)-232)*10)
+		return ansiColor{index: -1, rgb: RGBA{v, v, v, 255}}
+	}
+}
+
+// LoadANSI loads an ANSI art file (.ans) and renders it like ParseANSI.
+//
+// filename: The path to the ANSI art file to load.
+//
+// Returns: A pointer to an Image struct containing the rendered art, or an error if any issue occurs.
+func LoadANSI(filename string) (*Image, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseANSI(bytes.NewReader(data))
+}
+
+// ParseANSI renders ANSI art (text with ANSI escape sequences, as in .ans files) into an image,
+// using 8×16 VGA-style character cells on an 80-column screen. It supports the 16 ANSI colors with bold
+// and blink as bright colors, the 256-color and 24-bit color extensions, and cursor movement.
+// Text is read as UTF-8 if it is valid UTF-8, and as code page 437 otherwise; a SAUCE record at the end is ignored.
+//
+// r: The reader to read the ANSI text from.
+//
+// Returns: A pointer to an Image struct containing the rendered art, or an error if it cannot be read or is too large.
+func ParseANSI(r io.Reader) (*Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if end := bytes.IndexByte(data, 0x1A); end >= 0 {
+		data = data[:end]
+	}
+	var text []rune
+	if utf8.Valid(data) {
+		text = []rune(string(data))
+	} else {
+		text = make([]rune, len(data))
+		for k, b := range data {
+			text[k] = rune(b)
+			if b >= 0x80 {
+				text[k] = cp437[b-0x80]
+			}
+		}
+	}
+	maxRows := int(^uint(0) >> 1)
+	if MaxPixels > 0 {
+		maxRows = MaxPixels / (ansiColumns * ansiCellWidth * ansiCellHeight)
+	}
+	var rows [][]ansiCell
+	fg, bg := ansiColor{index: 7}, ansiColor{index: 0}
+	bold, blink, reverse := false, false, false
+	row, col, savedRow, savedCol := 0, 0, 0, 0
+	for k := 0; k < len(text); k++ {
+		c := text[k]
+		switch c {
+		case '\r':
+			col = 0
+			continue
+		case '\n':
+			row, col = row+1, 0
+			continue
+		case '\t':
+			col = min((col/8+1)*8, ansiColumns-1)
+			continue
+		case 0x1B:
+			if k+1 >= len(text) || text[k+1] != '[' {
+				k++
+				continue
+			}
+			end := k + 2
+			for end < len(text) && (text[end] < 0x40 || text[end] > 0x7E) {
+				end++
+			}
+			if end >= len(text) {
+				k = end
+				continue
+			}
+			params := make([]int, 0)
+			for _, p := range strings.Split(strings.TrimLeft(string(text[k+2:end]), "?"), ";") {
+				n, _ := strconv.Atoi(p)
+				params = append(params, n)
+			}
+			param := func(index, def int) int {
+				if index < len(params) && params[index] > 0 {
+					return params[index]
+				}
+				return def
+			}
+			switch text[end] {
+			case 'A':
+				row = max(0, row-param(0, 1))
+			case 'B':
+				row += param(0, 1)
+			case 'C':
+				col = min(ansiColumns-1, col+param(0, 1))
+			case 'D':
+				col = max(0, col-param(0, 1))
+			case 'H', 'f':
+				row, col = param(0, 1)-1, min(ansiColumns, param(1, 1))-1
+			case 's':
+				savedRow, savedCol = row, col
+			case 'u':
+				row, col = savedRow, savedCol
+			case 'J':
+				if param(0, 0) == 2 {
+					rows, row, col = nil, 0, 0
+				}
+			case 'm':
+				if len(params) == 0 {
+					params = append(params, 0)
+				}
+				for p := 0; p < len(params); p++ {
+					switch n := params[p]; {
+					case n == 0:
+						fg, bg = ansiColor{index: 7}, ansiColor{index: 0}
+						bold, blink, reverse = false, false, false
+					case n == 1:
+						bold = true
+					case n == 5:
+						blink = true
+					case n == 7:
+						reverse = true
+					case n == 22:
+						bold = false
+					case n == 25:
+						blink = false
+					case n == 27:
+						reverse = false
+					case n >= 30 && n <= 37:
+						fg = ansiColor{index: n - 30}
+					case n == 39:
+						fg = ansiColor{index: 7}
+					case n >= 40 && n <= 47:
+						bg = ansiColor{index: n - 40}
+					case n == 49:
+						bg = ansiColor{index: 0}
+					case n >= 90 && n <= 97:
+						fg = ansiColor{index: n - 90 + 8}
+					case n >= 100 && n <= 107:
+						bg = ansiColor{index: n - 100 + 8}
+					case (n == 38 || n == 48) && p+2 < len(params) && params[p+1] == 5:
+						color := xterm256(params[p+2])
+						p += 2
+						if n == 38 {
+							fg = color
+						} else {
+							bg = color
+						}
+					case (n == 38 || n == 48) && p+4 < len(params) && params[p+1] == 2:
+						color := ansiColor{index: -1, rgb: RGBA{clampUint8(float64(params[p+2])), clampUint8(float64(params[p+3])), clampUint8(float64(params[p+4])), 255}}
+						p += 4
+						if n == 38 {
+							fg = color
+						} else {
+							bg = color
+						}
+					}
+				}
+			}
+			k = end
+			continue
+		}
+		if c < 0x20 {
+			continue
+		}
+		if col >= ansiColumns {
+			row, col = row+1, 0
+		}
+		if row >= maxRows {
+			return nil, &LimitError{Err: ErrImageTooLarge, Value: (row + 1) * ansiColumns * ansiCellWidth * ansiCellHeight, Limit: MaxPixels}
+		}
+		for len(rows) <= row {
+			rows = append(rows, make([]ansiCell, ansiColumns))
+		}
+		front, back := fg.resolve(bold), bg.resolve(blink)
+		if reverse {
+			front, back = back, front
+		}
+		rows[row][col] = ansiCell{char: c, fg: front, bg: back}
+		col++
+	}
+	img := NewImage(ansiColumns*ansiCellWidth, uint(len(rows))*ansiCellHeight, ansiPalette[0])
+	for y, cells := range rows {
+		for x, cell := range cells {
+			img.drawANSICell(uint(x)*ansiCellWidth, uint(y)*ansiCellHeight, cell)
+		}
+	}
+	return img, nil
+}
+
+// drawANSICell draws a character cell with its top-left corner at (x, y).
+func (i *Image) drawANSICell(x, y uint, cell ansiCell) {
+	if cell.char == 0 {
+		return
+	}
+	for dx := uint(0); dx < ansiCellWidth; dx++ {
+		for dy := uint(0); dy < ansiCellHeight; dy++ {
+			i.Set(x+dx, y+dy, cell.bg)
+		}
+	}
+	// on reports whether the pixel (dx, dy) of the cell is part of a block or shade character.
+	var on func(dx, dy uint) bool
+	switch cell.char {
+	case '█':
+		on = func(dx, dy uint) bool { return true }
+	case '▀':
+		on = func(dx, dy uint) bool { return dy < ansiCellHeight/2 }
+	case '▄':
+		on = func(dx, dy uint) bool { return dy >= ansiCellHeight/2 }
+	case '▌':
+		on = func(dx, dy uint) bool { return dx < ansiCellWidth/2 }
+	case '▐':
+		on = func(dx, dy uint) bool { return dx >= ansiCellWidth/2 }
+	case '■':
+		on = func(dx, dy uint) bool { return dx >= 1 && dx < 7 && dy >= 5 && dy < 11 }
+	case '░':
+		on = func(dx, dy uint) bool { return dy%2 == 0 && dx%4 == 0 || dy%2 == 1 && dx%4 == 2 }
+	case '▒':
+		on = func(dx, dy uint) bool { return (dx+dy)%2 == 0 }
+	case '▓':
+		on = func(dx, dy uint) bool { return !(dy%2 == 0 && dx%4 == 0 || dy%2 == 1 && dx%4 == 2) }
+	}
+	if arms, ok := ansiBox[cell.char]; ok {
+		on = func(dx, dy uint) bool {
+			return ansiBoxPixel(arms, int(dx), int(dy))
+		}
+	}
+	if on != nil {
+		for dx := uint(0); dx < ansiCellWidth; dx++ {
+			for dy := uint(0); dy < ansiCellHeight; dy++ {
+				if on(dx, dy) {
+					i.Set(x+dx, y+dy, cell.fg)
+				}
+			}
+		}
+		return
+	}
+	char := cell.char
+	if base, ok := ansiFallback[char]; ok {
+		char = base
+	}
+	if char == ' ' {
+		return
+	}
+	i.drawBitmapText(x, y+12, string(char), cell.fg)
+}
+
+// ansiBoxPixel reports whether the pixel (dx, dy) of a cell is on a box-drawing line with the given arms.
+// Single lines run through the center of the cell; double lines run one pixel on either side of it.
+func ansiBoxPixel(arms [4]uint8, dx, dy int) bool {
+	const cx, cy = 3, 7
+	vertical, horizontal := max(arms[0], arms[1]), max(arms[2], arms[3])
+	onLine := func(pos, center int, style uint8) bool {
+		if style == 2 {
+			return pos == center-1 || pos == center+1
+		}
+		return pos == center
+	}
+	// Vertical arms reach from the edge to the far side of the horizontal lines, and the other way around.
+	reach := func(style uint8) int {
+		if style == 2 {
+			return 1
+		}
+		return 0
+	}
+	if arms[0] > 0 && onLine(dx, cx, arms[0]) && dy <= cy+reach(horizontal) {
+		return true
+	}
+	if arms[1] > 0 && onLine(dx, cx, arms[1]) && dy >= cy-reach(horizontal) {
+		return true
+	}
+	if arms[2] > 0 && onLine(dy, cy, arms[2]) && dx <= cx+reach(vertical) {
+		return true
+	}
+	if arms[3] > 0 && onLine(dy, cy, arms[3]) && dx >= cx-reach(vertical) {
+		return true
+	}
+	return false
+}
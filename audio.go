@@ -0,0 +1,102 @@
+package picrocess
+
+import "math"
+
+type AudioFrame struct {
+	Amplitude float64   // Overall loudness of the frame, from 0 to 1
+	Spectrum  []float64 // Energy per frequency band, from the lowest to the highest band, each from 0 to 1
+}
+
+// Band returns the average energy of a part of the spectrum.
+//
+// from: The start of the part, as a fraction of the spectrum (0 is the lowest band).
+// to: The end of the part, as a fraction of the spectrum (1 is the highest band).
+//
+// Returns: The average energy of the bands in that part, or 0 if there are none.
+func (f AudioFrame) Band(from, to float64) float64 {
+	n := len(f.Spectrum)
+	if n == 0 {
+		return 0
+	}
+	start := max(0, min(n-1, int(from*float64(n))))
+	end := max(start+1, min(n, int(math.Ceil(to*float64(n)))))
+	sum := 0.0
+	for _, v := range f.Spectrum[start:end] {
+		sum += v
+	}
+	return sum / float64(end-start)
+}
+
+// Bass returns the average energy of the lowest eighth of the spectrum.
+func (f AudioFrame) Bass() float64 {
+	return f.Band(0, 0.125)
+}
+
+// Treble returns the average energy of the highest half of the spectrum.
+func (f AudioFrame) Treble() float64 {
+	return f.Band(0.5, 1)
+}
+
+// Centroid returns the spectral centroid, the "center of mass" of the spectrum, from 0 (all energy in the
+// lowest band) to 1 (all energy in the highest band). It follows how high the sound is perceived to be.
+func (f AudioFrame) Centroid() float64 {
+	if len(f.Spectrum) < 2 {
+		return 0
+	}
+	var weighted, total float64
+	for k, v := range f.Spectrum {
+		weighted += float64(k) * v
+		total += v
+	}
+	if total == 0 {
+		return 0
+	}
+	return weighted / total / float64(len(f.Spectrum)-1)
+}
+
+// AudioFeature extracts a value from 0 to 1 from an audio frame, such as AudioFrame.Bass.
+type AudioFeature func(f AudioFrame) float64
+
+// AudioBinding drives a layer property with an audio feature: the property is Min when the feature is 0
+// and Max when the feature is 1, such as scaling a logo with the bass or shifting its hue with the pitch.
+type AudioBinding struct {
+	Layer    *Layer
+	Property LayerProperty
+	Feature  AudioFeature
+	Min, Max float64
+}
+
+// RenderAudio renders the timeline into an animated GIF with one frame per audio frame,
+// where the bound layer properties follow the precomputed audio features instead of their keyframes.
+// Properties that are not bound keep their keyframed animation.
+//
+// frames: The audio features of every frame, in order.
+// fps: The number of frames per second the audio features were computed for.
+// bindings: The layer properties to drive with audio features.
+//
+// Returns: A new GIF containing one frame per audio frame, or an error if the Renderer fails to render a frame.
+func (tl *Timeline) RenderAudio(frames []AudioFrame, fps int, bindings ...AudioBinding) (*GIF, error) {
+	gf := NewGIF()
+	if fps <= 0 {
+		return gf, nil
+	}
+	sources := tl.prepare()
+	for k, frame := range frames {
+		// The values driven by the audio are kept per frame, so the layers themselves are never changed.
+		overrides := make(map[*Layer]map[LayerProperty]float64)
+		for _, b := range bindings {
+			if b.Layer == nil || b.Feature == nil {
+				continue
+			}
+			if overrides[b.Layer] == nil {
+				overrides[b.Layer] = make(map[LayerProperty]float64)
+			}
+			v := math.Max(0, math.Min(1, b.Feature(frame)))
+			overrides[b.Layer][b.Property] = b.Min + (b.Max-b.Min)*v
+		}
+		if err := gf.Append(tl.renderFrame(sources, float64(k)/float64(fps), overrides), frameDelay(k, fps)); err != nil {
+			return nil, err
+		}
+	}
+	return gf, nil
+}
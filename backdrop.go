@@ -0,0 +1,173 @@
+package picrocess
+
+import "math"
+
+type backdrop struct {
+	color    RGBA
+	padding  float64
+	radius   float64
+	gradient bool
+	// perLine draws a box behind every line instead of one behind the whole block.
+	perLine bool
+}
+
+// WithBackdrop draws a rounded, usually translucent box behind text, sized to the laid-out text,
+// so captions stay readable over busy photos.
+//
+// c: The color of the box, such as RGBA{0, 0, 0, 160}.
+// padding: The space between the text and the edge of the box, in pixels.
+// radius: The corner radius of the box, in pixels.
+func WithBackdrop(c RGBA, padding, radius uint) Option {
+	return func(o *Options) {
+		o.backdrop = &backdrop{color: c, padding: float64(padding), radius: float64(radius)}
+	}
+}
+
+// WithScrim draws a gradient scrim behind text: solid behind the text and fading out above it,
+// the usual way to lay captions over photos without a hard-edged box.
+//
+// c: The color of the scrim where it is solid, such as RGBA{0, 0, 0, 180}.
+// padding: The space between the text and the edge of the solid part, in pixels.
+func WithScrim(c RGBA, padding uint) Option {
+	return func(o *Options) {
+		o.backdrop = &backdrop{color: c, padding: float64(padding), gradient: true}
+	}
+}
+
+// WithHighlight draws a padded, rounded box behind every line of text, each as wide as its line,
+// such as for subtitles or pill-shaped labels. The boxes of lines that touch merge into one shape.
+//
+// c: The color of the boxes, such as RGBA{0, 0, 0, 160}.
+// padding: The space between the text and the edge of its box, in pixels.
+// radius: The corner radius of the boxes, in pixels; a radius of half the box height or more draws pills.
+func WithHighlight(c RGBA, padding, radius uint) Option {
+	return func(o *Options) {
+		o.backdrop = &backdrop{color: c, padding: float64(padding), radius: float64(radius), perLine: true}
+	}
+}
+
+type textOutline struct {
+	color RGBA
+	width float64
+}
+
+// WithTextOutline outlines the glyphs of text, such as the classic white text with a black outline
+// that stays readable over any photo.
+//
+// c: The color of the outline.
+// width: The width of the outline around the glyphs, in pixels.
+func WithTextOutline(c RGBA, width uint) Option {
+	return func(o *Options) {
+		o.textOutline = &textOutline{color: c, width: float64(width)}
+	}
+}
+
+type textFill struct {
+	pattern Pattern
+	// stops and angle describe a gradient that spans the glyphs, used when pattern is nil.
+	stops []GradientStop
+	angle float64
+}
+
+// WithTextFill fills the glyphs of text with a pattern instead of a flat color, such as a texture made with
+// NewImagePattern or a gradient made with NewLinearGradient. The alpha of the text color still sets the opacity.
+//
+// p: The pattern to fill the glyphs with, in image coordinates.
+func WithTextFill(p Pattern) Option {
+	return func(o *Options) {
+		o.textFill = &textFill{pattern: p}
+	}
+}
+
+// WithTextGradient fills the glyphs of text with a linear gradient that spans the drawn glyphs, such as for the gold
+// or chrome look of headlines. The alpha of the text color still sets the opacity.
+//
+// angle: The direction of the gradient in degrees; 0 runs left to right and 90 runs top to bottom.
+// stops: The colors of the gradient, sorted by position.
+func WithTextGradient(angle float64, stops ...GradientStop) Option {
+	return func(o *Options) {
+		o.textFill = &textFill{stops: stops, angle: angle}
+	}
+}
+
+// apply recolors the glyphs drawn on a layer with the fill, keeping their coverage.
+func (f *textFill) apply(layer *Image) {
+	p := f.pattern
+	if p == nil {
+		// Span the gradient across the bounding box of the glyphs.
+		minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for x := range layer.Pixel {
+			for y := range layer.Pixel[x] {
+				if layer.Pixel[x][y].A > 0 {
+					minX, minY = math.Min(minX, float64(x)), math.Min(minY, float64(y))
+					maxX, maxY = math.Max(maxX, float64(x+1)), math.Max(maxY, float64(y+1))
+				}
+			}
+		}
+		if math.IsInf(minX, 1) {
+			return
+		}
+		rad := f.angle * math.Pi / 180
+		dx, dy := math.Cos(rad), math.Sin(rad)
+		cx, cy := (minX+maxX)/2, (minY+maxY)/2
+		half := (math.Abs(dx)*(maxX-minX) + math.Abs(dy)*(maxY-minY)) / 2
+		p = NewLinearGradient(NewOffsetF(cx-dx*half, cy-dy*half), NewOffsetF(cx+dx*half, cy+dy*half), f.stops...)
+	}
+	for x := range layer.Pixel {
+		for y := range layer.Pixel[x] {
+			coverage := layer.Pixel[x][y].A
+			if coverage == 0 {
+				continue
+			}
+			c := p.ColorAt(float64(x)+0.5, float64(y)+0.5)
+			c.A = uint8(uint(c.A) * uint(coverage) / 255)
+			layer.Pixel[x][y] = c
+		}
+	}
+}
+
+// drawBackdrop draws the backdrop behind text whose top-left corner is drawn at (x, y), with step pixels between lines
+// that are aligned within the block by align, from 0 (left) to 1 (right).
+func (i *Image) drawBackdrop(b *backdrop, font *Font, size float64, text string, step, x, y, align float64) {
+	m := font.MeasureText(size, text)
+	if b.perLine {
+		width, _ := font.TextSize(size, text)
+		path := NewPath()
+		for k, line := range textLines(text) {
+			lineWidth, _ := font.TextSize(size, line)
+			if lineWidth == 0 {
+				continue
+			}
+			// Place every box around its line the way drawTextLines places the line.
+			lx := x + math.Round(float64(width-lineWidth)*align)
+			baseline := y + size + float64(k)*step
+			top := baseline - m.Ascent - b.padding
+			path.RoundedRect(lx-b.padding, top, float64(lineWidth)+2*b.padding, m.Ascent+m.Descent+2*b.padding, b.radius)
+		}
+		i.FillPath(path, b.color, NonZero)
+		return
+	}
+	// Text is drawn with its first baseline one font size below the offset.
+	top := y + size - m.Ascent - b.padding
+	bottom := y + size + float64(m.Lines-1)*step + m.Descent + b.padding
+	left, right := x-b.padding, x+math.Ceil(m.Width)+b.padding
+	if !b.gradient {
+		i.FillPath(NewPath().RoundedRect(left, top, right-left, bottom-top, b.radius), b.color, NonZero)
+		return
+	}
+	// The scrim fades in over the height of the box above it.
+	fade := bottom - top
+	x1, x2 := int(math.Max(0, math.Floor(left))), int(math.Min(float64(i.Width), math.Ceil(right)))
+	y1, y2 := int(math.Max(0, math.Floor(top-fade))), int(math.Min(float64(i.Height), math.Ceil(bottom)))
+	for py := y1; py < y2; py++ {
+		t := math.Max(0, math.Min(1, (float64(py)+0.5-(top-fade))/fade))
+		c := b.color
+		c.A = clampUint8(float64(c.A) * t * t * (3 - 2*t))
+		if c.A == 0 {
+			continue
+		}
+		for px := x1; px < x2; px++ {
+			i.Set(uint(px), uint(py), c.over(i.At(uint(px), uint(py))))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package picrocess
+
+// BlendMode is how drawn colors are combined with the colors already in the image.
+type BlendMode int
+
+const (
+	// BlendNormal paints the drawn color over the image, as draw operations do by default.
+	BlendNormal BlendMode = iota
+	// BlendMultiply multiplies the drawn color with the image, which only darkens it, such as for shading.
+	BlendMultiply
+	// BlendScreen multiplies the inverse of the drawn color with the inverse of the image, which only lightens it,
+	// such as for soft light overlays.
+	BlendScreen
+	// BlendAdditive adds the drawn color to the image, which lightens it quickly towards white, such as for glows and flares.
+	BlendAdditive
+)
+
+// WithBlendMode makes draw and fill operations combine what they draw with the image using the given blend mode
+// instead of painting over it.
+//
+// mode: The blend mode to use, such as BlendScreen or BlendAdditive.
+func WithBlendMode(mode BlendMode) Option {
+	return func(o *Options) {
+		o.blend = mode
+	}
+}
+
+// blend combines the source color with the destination color. Where the destination is opaque, the blended color
+// replaces the source color; where it is transparent, the source color is kept. The result is then painted over
+// the destination by the alpha of the source.
+func (m BlendMode) blend(src, dst RGBA) RGBA {
+	if m == BlendNormal || src.A == 0 || dst.A == 0 {
+		return src.over(dst)
+	}
+	mix := func(s, d uint8) uint8 {
+		fs, fd := float64(s)/255, float64(d)/255
+		var b float64
+		switch m {
+		case BlendMultiply:
+			b = fs * fd
+		case BlendScreen:
+			b = fs + fd - fs*fd
+		case BlendAdditive:
+			b = min(1, fs+fd)
+		default:
+			b = fs
+		}
+		da := float64(dst.A) / 255
+		return clampUint8(((1-da)*fs + da*b) * 255)
+	}
+	return RGBA{mix(src.R, dst.R), mix(src.G, dst.G), mix(src.B, dst.B), src.A}.over(dst)
+}
+
+// newLayer returns an empty layer of the same size and transformation as the image, for draw operations that
+// paint separately before being composited onto the image.
+func (i *Image) newLayer() *Image {
+	layer := NewImage(i.Width, i.Height, RGBA{})
+	layer.transform, layer.scaleFactor = i.transform, i.scaleFactor
+	return layer
+}
+
+// layered applies the options of a draw operation that need a separate layer: it casts the shadow requested
+// with WithShadow, and if a blend mode other than BlendNormal is requested with WithBlendMode, it paints draw
+// onto a layer and blends the layer onto the image.
+//
+// Returns: Whether the drawing is done, in which case the draw operation must not paint again.
+func (i *Image) layered(opts []Option, draw func(layer *Image)) bool {
+	if len(opts) == 0 {
+		return false
+	}
+	o := NewOptions(opts...)
+	if o.shadow != nil {
+		i.castShadow(*o.shadow, draw)
+	}
+	if o.blend == BlendNormal {
+		return false
+	}
+	layer := i.newLayer()
+	draw(layer)
+	i.compositeLayer(layer, o.blend)
+	return true
+}
+
+// compositeLayer blends a layer of the same size as the image onto the image with the blend mode.
+func (i *Image) compositeLayer(layer *Image, mode BlendMode) {
+	for x := range layer.Pixel {
+		for y := range layer.Pixel[x] {
+			if p := layer.Pixel[x][y]; p.A > 0 {
+				i.Set(uint(x), uint(y), mode.blend(p, i.Pixel[x][y]))
+			}
+		}
+	}
+}
+
+// unlayered returns the options without the ones handled by layered, for drawing onto the layer itself.
+func unlayered(opts []Option) []Option {
+	return append(opts[:len(opts):len(opts)], func(o *Options) {
+		o.shadow, o.blend = nil, BlendNormal
+	})
+}
@@ -0,0 +1,136 @@
+package picrocess
+
+import "sort"
+
+// BrickStud is the width and height of one stud of a brick mosaic rendered by BrickMosaic, in pixels.
+const BrickStud = 16
+
+// BrickCount is the number of bricks of one color and size needed to build a mosaic.
+type BrickCount struct {
+	Color RGBA
+	// Size is the size of the brick in studs, as given to BrickMosaic. Bricks may be placed rotated.
+	Size  Offset
+	Count uint
+}
+
+// BrickMosaic plans a brick mosaic of the image, where every pixel of the image becomes one stud.
+// The pixels are mapped to the nearest palette color, and areas of the same color are covered with the
+// largest bricks that fit, placed in either orientation; 1×1 bricks fill what remains.
+// Transparent pixels are left without a brick. Resize the image first to choose the size of the mosaic.
+//
+// palette: The colors of the available bricks. If it is empty, the colors of the image are used as they are.
+// brickSizes: The sizes of the available bricks, in studs, such as {2, 4} for a 2×4 brick.
+//
+// Returns: A new image rendering the mosaic with BrickStud pixels per stud, and the parts list sorted by color and size.
+func (i *Image) BrickMosaic(palette []RGBA, brickSizes []Offset) (*Image, []BrickCount) {
+	w, h := i.Width, i.Height
+	grid := make([][]RGBA, w)
+	for x := range grid {
+		grid[x] = make([]RGBA, h)
+		for y := range grid[x] {
+			p := i.Pixel[x][y]
+			if p.A < 128 {
+				continue
+			}
+			c := RGBA{p.R, p.G, p.B, 255}
+			if len(palette) > 0 {
+				c = palette[nearestColor(palette, float64(p.R), float64(p.G), float64(p.B))]
+				c.A = 255
+			}
+			grid[x][y] = c
+		}
+	}
+	// Try the largest bricks first; every size is also tried rotated.
+	sizes := make([]Offset, 0, len(brickSizes)+1)
+	for _, s := range brickSizes {
+		if s.W > 0 && s.H > 0 {
+			sizes = append(sizes, s)
+		}
+	}
+	sort.SliceStable(sizes, func(a, b int) bool {
+		return sizes[a].W*sizes[a].H > sizes[b].W*sizes[b].H
+	})
+	sizes = append(sizes, NewOffset(1, 1))
+	covered := make([][]bool, w)
+	for x := range covered {
+		covered[x] = make([]bool, h)
+	}
+	fits := func(x, y, bw, bh uint, c RGBA) bool {
+		if x+bw > w || y+bh > h {
+			return false
+		}
+		for dx := x; dx < x+bw; dx++ {
+			for dy := y; dy < y+bh; dy++ {
+				if covered[dx][dy] || grid[dx][dy] != c {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	out := NewImage(w*BrickStud, h*BrickStud, RGBA{0, 0, 0, 0})
+	counts := make(map[BrickCount]uint)
+	for y := uint(0); y < h; y++ {
+		for x := uint(0); x < w; x++ {
+			c := grid[x][y]
+			if covered[x][y] || c.A == 0 {
+				continue
+			}
+			for _, s := range sizes {
+				bw, bh := s.W, s.H
+				if !fits(x, y, bw, bh, c) {
+					bw, bh = s.H, s.W
+					if !fits(x, y, bw, bh, c) {
+						continue
+					}
+				}
+				for dx := x; dx < x+bw; dx++ {
+					for dy := y; dy < y+bh; dy++ {
+						covered[dx][dy] = true
+					}
+				}
+				out.drawBrick(NewRect(x*BrickStud, y*BrickStud, (x+bw)*BrickStud, (y+bh)*BrickStud), c)
+				counts[BrickCount{Color: c, Size: s}]++
+				break
+			}
+		}
+	}
+	parts := make([]BrickCount, 0, len(counts))
+	for part, n := range counts {
+		part.Count = n
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(a, b int) bool {
+		pa, pb := parts[a], parts[b]
+		if pa.Color != pb.Color {
+			return uint32(pa.Color.R)<<16|uint32(pa.Color.G)<<8|uint32(pa.Color.B) < uint32(pb.Color.R)<<16|uint32(pb.Color.G)<<8|uint32(pb.Color.B)
+		}
+		if pa.Size.W*pa.Size.H != pb.Size.W*pb.Size.H {
+			return pa.Size.W*pa.Size.H > pb.Size.W*pb.Size.H
+		}
+		return pa.Size.W > pb.Size.W
+	})
+	return out, parts
+}
+
+// drawBrick draws a brick seen from above covering the rectangle: a darker outline and a lighter stud on every cell.
+func (i *Image) drawBrick(r Rect, c RGBA) {
+	edge := lerpRGBA(c, RGBA{0, 0, 0, 255}, 0.35)
+	stud := lerpRGBA(c, RGBA{255, 255, 255, 255}, 0.2)
+	for x := r.W1; x < r.W2; x++ {
+		for y := r.H1; y < r.H2; y++ {
+			if x == r.W1 || y == r.H1 || x == r.W2-1 || y == r.H2-1 {
+				i.Set(x, y, edge)
+			} else {
+				i.Set(x, y, c)
+			}
+		}
+	}
+	for x := r.W1; x < r.W2; x += BrickStud {
+		for y := r.H1; y < r.H2; y += BrickStud {
+			center := NewOffset(x+BrickStud/2-1, y+BrickStud/2-1)
+			i.DrawCircle(center, BrickStud*3/10, edge, 1)
+			i.FillCircle(center, BrickStud*3/10-1, stud)
+		}
+	}
+}
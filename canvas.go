@@ -0,0 +1,316 @@
+package picrocess
+
+import "errors"
+
+// ErrNoFont is returned when text is drawn on a Canvas before a font is set with SetFont.
+var ErrNoFont = errors.New("picrocess: no font set")
+
+// Canvas is a drawing context for an Image that keeps the current fill, stroke, font, transformation, and clip region,
+// so complex compositions can set them once instead of passing them to every draw call.
+// Shapes are filled with the fill and then outlined with the stroke, if either is set.
+// Coordinates are continuous, like those of a Path: (0, 0) is the top left corner of the top left pixel.
+type Canvas struct {
+	// Image is the image that is drawn on.
+	Image *Image
+
+	state canvasState
+	stack []canvasState
+}
+
+// canvasState is the part of a Canvas saved by Push and restored by Pop.
+type canvasState struct {
+	fill      Pattern
+	stroke    RGBA
+	lineWidth float64
+	lineCap   LineCap
+	lineJoin  LineJoin
+	font      *Font
+	fontSize  float64
+	anchor    Anchor
+	blend     BlendMode
+	shadow    *Shadow
+	// clips is the number of clip regions of the image when the state was saved.
+	clips int
+}
+
+// NewCanvas creates a Canvas that draws on the image. It starts with an opaque black fill, no stroke,
+// a line width of 1, and a font size of 16.
+//
+// img: The image to draw on.
+//
+// Returns: A new Canvas.
+func NewCanvas(img *Image) *Canvas {
+	return &Canvas{
+		Image: img,
+		state: canvasState{
+			fill:      RGBA{0, 0, 0, 255},
+			lineWidth: 1,
+			lineCap:   CapButt,
+			lineJoin:  JoinMiter,
+			fontSize:  16,
+			anchor:    AnchorTopLeft,
+		},
+	}
+}
+
+// SetFillColor sets the color that shapes are filled with and that text is drawn with.
+// A fully transparent color turns filling off.
+//
+// color: The fill color.
+func (c *Canvas) SetFillColor(color RGBA) {
+	c.state.fill = color
+}
+
+// SetFillPattern sets the pattern that shapes and text are filled with, such as a hatch or a texture.
+//
+// p: The fill pattern. If it is nil, filling is turned off.
+func (c *Canvas) SetFillPattern(p Pattern) {
+	c.state.fill = p
+}
+
+// SetStrokeColor sets the color that shapes are outlined with. A fully transparent color turns outlining off.
+//
+// color: The stroke color.
+func (c *Canvas) SetStrokeColor(color RGBA) {
+	c.state.stroke = color
+}
+
+// SetLineWidth sets the width of outlines and lines, in the current coordinate system.
+//
+// width: The line width. A width of 0 turns outlining off.
+func (c *Canvas) SetLineWidth(width float64) {
+	c.state.lineWidth = width
+}
+
+// SetLineCap sets how the ends of lines are drawn.
+//
+// lineCap: The cap, such as CapRound.
+func (c *Canvas) SetLineCap(lineCap LineCap) {
+	c.state.lineCap = lineCap
+}
+
+// SetLineJoin sets how connected segments of lines and outlines are joined.
+//
+// join: The join, such as JoinRound.
+func (c *Canvas) SetLineJoin(join LineJoin) {
+	c.state.lineJoin = join
+}
+
+// SetFont sets the font and size that text is drawn with.
+//
+// font: The font to use.
+// size: The font size.
+func (c *Canvas) SetFont(font *Font, size float64) {
+	c.state.font, c.state.fontSize = font, size
+}
+
+// SetTextAnchor sets which point of drawn text is placed at the position given to Text, such as AnchorCenter.
+//
+// a: The anchor.
+func (c *Canvas) SetTextAnchor(a Anchor) {
+	c.state.anchor = a
+}
+
+// SetBlendMode sets how everything drawn is blended with the image.
+//
+// mode: The blend mode, such as BlendMultiply.
+func (c *Canvas) SetBlendMode(mode BlendMode) {
+	c.state.blend = mode
+}
+
+// SetShadow sets the drop shadow cast by everything drawn.
+//
+// s: The shadow to cast. If it is nil, no shadow is cast.
+func (c *Canvas) SetShadow(s *Shadow) {
+	c.state.shadow = s
+}
+
+// Translate moves the origin of everything drawn afterwards by (x, y).
+func (c *Canvas) Translate(x, y float64) {
+	c.Image.Translate(x, y)
+}
+
+// Scale scales everything drawn afterwards by (sx, sy) around the current origin.
+func (c *Canvas) Scale(sx, sy float64) {
+	c.Image.Scale(sx, sy)
+}
+
+// Rotate rotates everything drawn afterwards clockwise by the given angle, in degrees, around the current origin.
+func (c *Canvas) Rotate(deg float64) {
+	c.Image.Rotate(deg)
+}
+
+// ClipRect restricts everything drawn afterwards to the rectangle, intersected with the current clip region.
+// The clip region is restored by Pop.
+//
+// x, y: The top left corner of the rectangle.
+// w, h: The width and height of the rectangle.
+func (c *Canvas) ClipRect(x, y, w, h float64) {
+	c.Image.PushClipPath(NewPath().MoveTo(x, y).LineTo(x+w, y).LineTo(x+w, y+h).LineTo(x, y+h).Close())
+}
+
+// ClipPath restricts everything drawn afterwards to the filled area of the path, intersected with the current
+// clip region. The clip region is restored by Pop.
+//
+// path: The path defining the region that can still be drawn on.
+func (c *Canvas) ClipPath(path *Path) {
+	c.Image.PushClipPath(path)
+}
+
+// Push saves the current state (fill, stroke, font, blend mode, shadow, transformation, and clip region),
+// so it can be changed for a part of the composition and restored with Pop.
+func (c *Canvas) Push() {
+	c.state.clips = len(c.Image.clips)
+	c.stack = append(c.stack, c.state)
+	c.Image.Push()
+}
+
+// Pop restores the state saved by the most recent Push. If there is nothing to restore, it does nothing.
+func (c *Canvas) Pop() {
+	if len(c.stack) == 0 {
+		return
+	}
+	c.state = c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.Image.clips) > c.state.clips {
+		c.Image.PopClip()
+	}
+	c.Image.Pop()
+}
+
+// options returns the draw options for the current blend mode and shadow.
+func (c *Canvas) options() []Option {
+	var opts []Option
+	if c.state.blend != BlendNormal {
+		opts = append(opts, WithBlendMode(c.state.blend))
+	}
+	if c.state.shadow != nil {
+		opts = append(opts, WithShadow(*c.state.shadow))
+	}
+	return opts
+}
+
+// filled reports whether the fill draws anything.
+func (c *Canvas) filled() bool {
+	color, ok := c.state.fill.(RGBA)
+	return c.state.fill != nil && (!ok || color.A > 0)
+}
+
+// stroked reports whether the stroke draws anything.
+func (c *Canvas) stroked() bool {
+	return c.state.stroke.A > 0 && c.state.lineWidth > 0
+}
+
+// paint fills and strokes the path on the image as one drawing, so both share a single shadow and blend.
+func (c *Canvas) paint(path *Path, fill, stroke bool) {
+	draw := func(img *Image) {
+		if fill {
+			img.FillPathPattern(path, c.state.fill, NonZero)
+		}
+		if stroke {
+			img.StrokePath(path, c.state.stroke, c.state.lineWidth, c.state.lineCap, c.state.lineJoin)
+		}
+	}
+	if !fill && !stroke {
+		return
+	}
+	if c.Image.layered(c.options(), draw) {
+		return
+	}
+	draw(c.Image)
+}
+
+// DrawPath fills and outlines the path with the current fill and stroke.
+//
+// path: The path to draw.
+func (c *Canvas) DrawPath(path *Path) {
+	c.paint(path, c.filled(), c.stroked())
+}
+
+// Rect draws a rectangle with the current fill and stroke.
+//
+// x, y: The top left corner of the rectangle.
+// w, h: The width and height of the rectangle.
+func (c *Canvas) Rect(x, y, w, h float64) {
+	c.DrawPath(NewPath().MoveTo(x, y).LineTo(x+w, y).LineTo(x+w, y+h).LineTo(x, y+h).Close())
+}
+
+// RoundedRect draws a rectangle with rounded corners with the current fill and stroke.
+//
+// x, y: The top left corner of the rectangle.
+// w, h: The width and height of the rectangle.
+// radius: The radius of the corners.
+func (c *Canvas) RoundedRect(x, y, w, h, radius float64) {
+	c.DrawPath(NewPath().RoundedRect(x, y, w, h, radius))
+}
+
+// Circle draws a circle with the current fill and stroke.
+//
+// x, y: The center of the circle.
+// radius: The radius of the circle.
+func (c *Canvas) Circle(x, y, radius float64) {
+	c.DrawPath(NewPath().Ellipse(x, y, radius, radius))
+}
+
+// Ellipse draws an ellipse with the current fill and stroke.
+//
+// x, y: The center of the ellipse.
+// rx, ry: The horizontal and vertical radius of the ellipse.
+func (c *Canvas) Ellipse(x, y, rx, ry float64) {
+	c.DrawPath(NewPath().Ellipse(x, y, rx, ry))
+}
+
+// Polygon draws a closed polygon through the points with the current fill and stroke.
+//
+// points: The corners of the polygon, in order. At least three are needed.
+func (c *Canvas) Polygon(points ...OffsetF) {
+	if len(points) < 3 {
+		return
+	}
+	path := NewPath().MoveTo(points[0].W, points[0].H)
+	for _, p := range points[1:] {
+		path.LineTo(p.W, p.H)
+	}
+	c.DrawPath(path.Close())
+}
+
+// Line draws a line between two points with the current stroke. It is not filled.
+//
+// x1, y1: The start point of the line.
+// x2, y2: The end point of the line.
+func (c *Canvas) Line(x1, y1, x2, y2 float64) {
+	c.paint(NewPath().MoveTo(x1, y1).LineTo(x2, y2), false, c.stroked())
+}
+
+// Text draws text with the current font, fill, and text anchor.
+//
+// x, y: The position of the text; by default, its top left corner.
+// text: The text to draw.
+//
+// Returns: An error if no font is set or the text cannot be rendered.
+func (c *Canvas) Text(x, y float64, text string) error {
+	if c.state.font == nil {
+		return ErrNoFont
+	}
+	if !c.filled() {
+		return nil
+	}
+	opts := append(c.options(), WithAnchor(c.state.anchor))
+	color, ok := c.state.fill.(RGBA)
+	if !ok {
+		color = RGBA{255, 255, 255, 255}
+		opts = append(opts, WithTextFill(c.state.fill))
+	}
+	c.Image.Push()
+	defer c.Image.Pop()
+	c.Image.Translate(x, y)
+	return c.Image.Text(c.state.font, color, NewOffset(0, 0), c.state.fontSize, text, opts...)
+}
+
+// DrawImage draws an image with its top left corner at (x, y), blended with the current blend mode and shadow.
+//
+// img: The image to draw.
+// x, y: The position of the top left corner of the image.
+func (c *Canvas) DrawImage(img *Image, x, y float64) {
+	c.Image.OverlayF(img, NewOffsetF(x, y), c.options()...)
+}
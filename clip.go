@@ -0,0 +1,130 @@
+package picrocess
+
+import (
+	"image"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// clipToCoverage returns a copy of the image where every pixel's alpha is multiplied by its coverage (0 to 1).
+// Pixels outside of the coverage map become transparent.
+func (i *Image) clipToCoverage(m coverageMap) *Image {
+	respond := NewImage(i.Width, i.Height, RGBA{})
+	for x := m.bounds.Min.X; x < m.bounds.Max.X; x++ {
+		for y := m.bounds.Min.Y; y < m.bounds.Max.Y; y++ {
+			c := m.at(x, y)
+			if c <= 0 {
+				continue
+			}
+			pixel := i.Pixel[x][y]
+			pixel.A = clampUint8(float64(pixel.A) * c)
+			respond.Pixel[x][y] = pixel
+		}
+	}
+	return respond
+}
+
+// ClipToText creates a copy of the image that only shows through the glyphs of the given text,
+// such as for "image inside text" headers. The text is centered on the image and everything
+// outside of the glyphs becomes transparent.
+//
+// font: The Font object to use for the glyph shapes.
+// size: The font size to use for the glyphs.
+// text: The string of text whose silhouette is used as the clip region.
+//
+// Returns: A new Image of the same size, clipped to the text silhouette, or an error if the text cannot be rendered.
+func (i *Image) ClipToText(font *Font, size float64, text string) (*Image, error) {
+	mask := image.NewAlpha(image.Rect(0, 0, int(i.Width), int(i.Height)))
+	metrics := font.newFace(size).Metrics()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	width, _ := font.TextSize(size, text)
+	x := (int(i.Width) - int(width)) / 2
+	baseline := (int(i.Height)-(ascent+descent))/2 + ascent
+	if err := font.drawLine(mask, image.Opaque, size, fixed.P(x, baseline), text); err != nil {
+		return nil, err
+	}
+	m := newCoverageMap(mask.Bounds())
+	for x := range m.cov {
+		for y := range m.cov[x] {
+			m.cov[x][y] = float64(mask.AlphaAt(x, y).A) / 255
+		}
+	}
+	return i.clipToCoverage(m), nil
+}
+
+// ClipToPath creates a copy of the image that only shows inside the filled area of the path.
+// Everything outside of the path becomes transparent, and the path edge is anti-aliased.
+//
+// path: The Path defining the clip region, in image coordinates. Open sub-paths are treated as closed.
+//
+// Returns: A new Image of the same size, clipped to the path.
+func (i *Image) ClipToPath(path *Path) *Image {
+	return i.clipToCoverage(path.coverage(i.Width, i.Height, NonZero))
+}
+
+// PushClip restricts all following draw operations (lines, paths, text, overlays, and Set) to the rectangle (r).
+// Clip regions stack: the new region is intersected with the current one until PopClip is called.
+//
+// r: The rectangle defining the region that can still be drawn on.
+func (i *Image) PushClip(r Rect) {
+	if _, ok := i.activeTransform(); ok {
+		x1, y1, x2, y2 := float64(r.W1), float64(r.H1), float64(r.W2), float64(r.H2)
+		i.PushClipPath(NewPath().MoveTo(x1, y1).LineTo(x2, y1).LineTo(x2, y2).LineTo(x1, y2).Close())
+		return
+	}
+	i.pushClipCoverage(coverageMap{bounds: image.Rect(int(min(r.W1, i.Width)), int(min(r.H1, i.Height)), int(min(r.W2, i.Width)), int(min(r.H2, i.Height)))})
+}
+
+// PushClipPath restricts all following draw operations to the filled area of the path, with anti-aliased edges.
+// Clip regions stack: the new region is intersected with the current one until PopClip is called.
+//
+// path: The Path defining the region that can still be drawn on, in image coordinates.
+func (i *Image) PushClipPath(path *Path) {
+	if t, ok := i.activeTransform(); ok {
+		path = path.transformed(t)
+	}
+	i.pushClipCoverage(path.coverage(i.Width, i.Height, NonZero))
+}
+
+// PopClip removes the most recently pushed clip region, restoring the previous one.
+// If there is no clip region, it does nothing.
+func (i *Image) PopClip() {
+	if len(i.clips) == 0 {
+		return
+	}
+	i.clips = i.clips[:len(i.clips)-1]
+}
+
+// pushClipCoverage intersects the coverage map with the current clip region and pushes the result onto the clip stack.
+// Two rectangles intersect into a rectangle; otherwise, only the intersection of their bounds keeps a coverage map.
+func (i *Image) pushClipCoverage(m coverageMap) {
+	if len(i.clips) > 0 {
+		top := i.clips[len(i.clips)-1]
+		bounds := m.bounds.Intersect(top.bounds)
+		if m.cov == nil && top.cov == nil {
+			m = coverageMap{bounds: bounds}
+		} else {
+			clipped := newCoverageMap(bounds)
+			for x := range clipped.cov {
+				for y := range clipped.cov[x] {
+					px, py := bounds.Min.X+x, bounds.Min.Y+y
+					clipped.cov[x][y] = m.at(px, py) * top.at(px, py)
+				}
+			}
+			m = clipped
+		}
+	}
+	i.clips = append(i.clips, m)
+}
+
+// clipAt returns how much of the pixel at (x, y) can be drawn on, from 0 (clipped) to 1 (fully drawable).
+// Without a clip region, every pixel is fully drawable.
+func (i *Image) clipAt(x, y uint) float64 {
+	if len(i.clips) == 0 {
+		return 1
+	}
+	if x >= i.Width || y >= i.Height {
+		return 0
+	}
+	return i.clips[len(i.clips)-1].at(int(x), int(y))
+}
@@ -0,0 +1,286 @@
+package picrocess
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownFormat is returned when no encoder is registered for the requested format or file extension.
+var ErrUnknownFormat = errors.New("picrocess: unknown format")
+
+// Encoder writes an Image in a specific file format.
+type Encoder interface {
+	Encode(w io.Writer, img *Image, o Options) error
+}
+
+// Decoder reads an Image from a specific file format.
+// Decoders that do not also implement ConfigDecoder allocate the whole image before MaxPixels is checked,
+// so they should reject images that are too large themselves.
+type Decoder interface {
+	Decode(r io.Reader) (*Image, error)
+}
+
+// ConfigDecoder is a Decoder that can also read the size of an image from its header, so DecodeImage
+// can check MaxPixels before any pixels are allocated.
+type ConfigDecoder interface {
+	Decoder
+	DecodeConfig(r io.Reader) (image.Config, error)
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(w io.Writer, img *Image, o Options) error
+
+// Encode calls f(w, img, o).
+func (f EncoderFunc) Encode(w io.Writer, img *Image, o Options) error {
+	return f(w, img, o)
+}
+
+// DecoderFunc adapts a function to the Decoder interface.
+type DecoderFunc func(r io.Reader) (*Image, error)
+
+// Decode calls f(r).
+func (f DecoderFunc) Decode(r io.Reader) (*Image, error) {
+	return f(r)
+}
+
+type registeredEncoder struct {
+	name       string
+	extensions []string
+	encoder    Encoder
+}
+
+type registeredDecoder struct {
+	name    string
+	magic   string
+	decoder Decoder
+}
+
+var (
+	codecMu  sync.RWMutex
+	encoders = make([]registeredEncoder, 0)
+	decoders = make([]registeredDecoder, 0)
+)
+
+func init() {
+	RegisterEncoder("png", []string{".png"}, EncoderFunc(func(w io.Writer, img *Image, o Options) error {
+		if o.deterministic {
+			return img.encodeTarget(o).encodeDeterministicPNG(w, o.activeRenderer())
+		}
+		return img.encodeTarget(o).encodePNG(w, o.activeRenderer())
+	}))
+	RegisterEncoder("jpeg", []string{".jpg", ".jpeg"}, EncoderFunc(func(w io.Writer, img *Image, o Options) error {
+		return img.encodeTarget(o).roiTarget(o).encodeJPEG(w, o.Quality, o.activeRenderer())
+	}))
+	RegisterEncoder("gif", []string{".gif"}, EncoderFunc(func(w io.Writer, img *Image, o Options) error {
+		frame, err := img.encodeTarget(o).renderRGBA(o.activeRenderer())
+		if err != nil {
+			return err
+		}
+		gf := &GIF{Delay: []int{0}, Image: []*image.RGBA{frame}}
+		buffer, err := gf.encode(o)
+		if err != nil {
+			return err
+		}
+		_, err = buffer.WriteTo(w)
+		return err
+	}))
+}
+
+// RegisterEncoder registers an encoder for a format, so images can be saved in it with EncodeFormat or Save.
+// External modules can use it to add formats (such as AVIF or JPEG XL) without the core depending on them.
+// Registering a name again replaces the earlier encoder.
+//
+// name: The name of the format, such as "avif".
+// extensions: The file extensions of the format, including the dot, such as ".avif".
+// e: The encoder that writes the format.
+func RegisterEncoder(name string, extensions []string, e Encoder) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	for k, r := range encoders {
+		if r.name == name {
+			encoders[k] = registeredEncoder{name, extensions, e}
+			return
+		}
+	}
+	encoders = append(encoders, registeredEncoder{name, extensions, e})
+}
+
+// RegisterDecoder registers a decoder for a format, so LoadImage, ImageURL, and DecodeImage can read it.
+// Registered decoders are tried before the formats supported by the standard image package.
+// Decoders that implement ConfigDecoder have their size checked against MaxPixels before they decode.
+//
+// name: The name of the format, such as "avif".
+// magic: The magic prefix identifying the format; each "?" matches any single byte.
+// d: The decoder that reads the format.
+func RegisterDecoder(name, magic string, d Decoder) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	decoders = append(decoders, registeredDecoder{name, magic, d})
+}
+
+// matchMagic reports whether the data starts with the magic prefix, where "?" matches any byte.
+func matchMagic(magic string, data []byte) bool {
+	if len(magic) != len(data) {
+		return false
+	}
+	for k, b := range data {
+		if magic[k] != b && magic[k] != '?' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeImage reads an image in any registered format, or any format supported by the standard image package.
+// Images larger than MaxPixels are rejected with a LimitError, and decoders that panic on corrupt input
+// return a DecodeError instead, so untrusted uploads can be decoded safely. The size is checked before any pixels
+// are allocated for the standard formats and for registered decoders that implement ConfigDecoder.
+//
+// r: The reader to decode the image from.
+//
+// Returns: A pointer to an Image struct containing the decoded image, the name of its format, or an error if any issue occurs.
+func DecodeImage(r io.Reader) (*Image, string, error) {
+	reader := bufio.NewReader(r)
+	codecMu.RLock()
+	registered := append([]registeredDecoder(nil), decoders...)
+	codecMu.RUnlock()
+	for _, d := range registered {
+		data, err := reader.Peek(len(d.magic))
+		if err == nil && matchMagic(d.magic, data) {
+			var source io.Reader = reader
+			if c, ok := d.decoder.(ConfigDecoder); ok {
+				// Read the header first and check the size before any pixels are allocated.
+				var header bytes.Buffer
+				config, err := safeDecode(d.name, func() (image.Config, error) {
+					return c.DecodeConfig(io.TeeReader(reader, &header))
+				})
+				if err != nil {
+					return nil, d.name, err
+				}
+				if err := checkPixels(config.Width, config.Height); err != nil {
+					return nil, d.name, err
+				}
+				source = io.MultiReader(&header, reader)
+			}
+			img, err := safeDecode(d.name, func() (*Image, error) {
+				return d.decoder.Decode(source)
+			})
+			if err != nil {
+				return nil, d.name, err
+			}
+			if img == nil {
+				return nil, d.name, &DecodeError{Format: d.name, Panic: "decoder returned no image"}
+			}
+			if err := checkPixels(int(img.Width), int(img.Height)); err != nil {
+				return nil, d.name, err
+			}
+			return img, d.name, nil
+		}
+	}
+	// Read the header first and check the size before any pixels are allocated.
+	var header bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(reader, &header))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkPixels(config.Width, config.Height); err != nil {
+		return nil, format, err
+	}
+	// Keep the start of the file to read the embedded color profile, which comes before the pixel data.
+	head := &headWriter{limit: 1 << 18}
+	img, err := safeDecode(format, func() (image.Image, error) {
+		img, _, err := image.Decode(io.TeeReader(io.MultiReader(&header, reader), head))
+		return img, err
+	})
+	if err != nil {
+		return nil, format, err
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
+	respond := Render(rgba)
+	respond.ColorSpace = detectColorSpace(head.data)
+	return respond, format, nil
+}
+
+// EncodeFormat writes the image in the named format, using a registered encoder.
+// The formats "png", "jpeg", and "gif" are always available.
+//
+// w: The writer to encode the image to.
+// format: The name of the format.
+// opts: Optional settings passed to the encoder, such as WithQuality.
+//
+// Returns: An error if the format is unknown or encoding fails.
+func (i *Image) EncodeFormat(w io.Writer, format string, opts ...Option) error {
+	codecMu.RLock()
+	var encoder Encoder
+	for _, r := range encoders {
+		if r.name == format {
+			encoder = r.encoder
+		}
+	}
+	codecMu.RUnlock()
+	if encoder == nil {
+		return ErrUnknownFormat
+	}
+	return encoder.Encode(w, i, NewOptions(opts...))
+}
+
+// Save saves the image to a file, choosing the format from the file extension (such as ".png" or ".jpg")
+// among the registered encoders.
+//
+// filename: The path of the file to write.
+// opts: Optional settings passed to the encoder, such as WithQuality.
+//
+// Returns: An error if no encoder handles the extension, or if the file cannot be written.
+func (i *Image) Save(filename string, opts ...Option) error {
+	return i.SaveContext(context.Background(), filename, opts...)
+}
+
+// formatForExtension returns the name of the registered format that handles the extension of the filename,
+// or an empty string if there is none.
+func formatForExtension(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	for _, r := range encoders {
+		for _, e := range r.extensions {
+			if strings.ToLower(e) == ext {
+				return r.name
+			}
+		}
+	}
+	return ""
+}
+
+// extensionForFormat returns the first file extension of the registered format, such as ".png" for "png".
+// The format can also be given as one of its extensions, such as "jpg". It returns empty strings if there is no such format.
+//
+// Returns: The name of the format and its extension.
+func extensionForFormat(format string) (string, string) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+	ext := "." + strings.TrimPrefix(strings.ToLower(format), ".")
+	for _, r := range encoders {
+		if len(r.extensions) == 0 {
+			continue
+		}
+		if r.name == format {
+			return r.name, r.extensions[0]
+		}
+		for _, e := range r.extensions {
+			if strings.ToLower(e) == ext {
+				return r.name, r.extensions[0]
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,302 @@
+package picrocess
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"sync"
+)
+
+type ColorSpace int
+
+const (
+	// ColorSpaceSRGB is the standard gamut of the web, assumed for untagged images.
+	ColorSpaceSRGB ColorSpace = iota
+	// ColorSpaceDisplayP3 is the wide gamut used by Apple devices and modern displays.
+	ColorSpaceDisplayP3
+)
+
+// WithColorSpace converts images to the given color space before they are encoded.
+// Without it, images are encoded in their own color space, tagged with the matching profile.
+//
+// cs: The color space to encode in, such as ColorSpaceSRGB for maximum compatibility.
+func WithColorSpace(cs ColorSpace) Option {
+	return func(o *Options) {
+		o.colorSpace = &cs
+	}
+}
+
+// Linear-light conversion matrices between sRGB and Display P3, which share the D65 white point.
+var (
+	srgbToP3 = [3][3]float64{
+		{0.8224621, 0.1775380, 0},
+		{0.0331941, 0.9668058, 0},
+		{0.0170827, 0.0723974, 0.9105199},
+	}
+	p3ToSRGB = [3][3]float64{
+		{1.2249401, -0.2249404, 0},
+		{-0.0420569, 1.0420571, 0},
+		{-0.0196376, -0.0786361, 1.0982735},
+	}
+)
+
+// srgbToLinear decodes an sRGB transfer-encoded channel (0 to 1) to linear light. Display P3 uses the same curve.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear light channel (0 to 1) with the sRGB transfer curve.
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// ConvertColorSpace converts the pixel values of the image to another color space and tags the image with it.
+// Colors that are outside the target gamut are clipped. The alpha channel of every pixel is kept intact.
+//
+// cs: The color space to convert to.
+func (i *Image) ConvertColorSpace(cs ColorSpace) {
+	if cs == i.ColorSpace {
+		return
+	}
+	m := p3ToSRGB
+	if cs == ColorSpaceDisplayP3 {
+		m = srgbToP3
+	}
+	var lut [256]float64
+	for k := range lut {
+		lut[k] = srgbToLinear(float64(k) / 255)
+	}
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			rgb := [3]float64{lut[pixel.R], lut[pixel.G], lut[pixel.B]}
+			var out [3]uint8
+			for ch := range out {
+				v := m[ch][0]*rgb[0] + m[ch][1]*rgb[1] + m[ch][2]*rgb[2]
+				out[ch] = clampUint8(linearToSRGB(math.Max(0, math.Min(1, v))) * 255)
+			}
+			i.Pixel[x][y] = RGBA{out[0], out[1], out[2], pixel.A}
+		}
+	}
+	i.ColorSpace = cs
+}
+
+// encodeTarget returns the image to encode for the options, converted to the requested color space if needed.
+func (i *Image) encodeTarget(o Options) *Image {
+	if o.colorSpace == nil || *o.colorSpace == i.ColorSpace {
+		return i
+	}
+	converted := i.clone()
+	converted.ColorSpace = i.ColorSpace
+	converted.ConvertColorSpace(*o.colorSpace)
+	return converted
+}
+
+// encodePNG writes the image as a PNG rendered by r, embedding an ICC profile if it is not in sRGB.
+func (i *Image) encodePNG(w io.Writer, r Renderer) error {
+	rendered, err := r.Render(i)
+	if err != nil {
+		return err
+	}
+	if i.ColorSpace == ColorSpaceSRGB {
+		return png.Encode(w, rendered)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rendered); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	// The signature is followed by the IHDR chunk (8 + 13 + 4 bytes); iCCP must come right after it.
+	end := 8 + 25
+	var profile bytes.Buffer
+	profile.WriteString("Display P3\x00\x00")
+	zw := zlib.NewWriter(&profile)
+	zw.Write(displayP3Profile())
+	zw.Close()
+	chunk := pngChunk("iCCP", profile.Bytes())
+	for _, part := range [][]byte{data[:end], chunk, data[end:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeJPEG writes the image as a JPEG rendered by r, embedding an ICC profile if it is not in sRGB.
+func (i *Image) encodeJPEG(w io.Writer, quality int, r Renderer) error {
+	rendered, err := r.Render(i)
+	if err != nil {
+		return err
+	}
+	if i.ColorSpace == ColorSpaceSRGB {
+		return jpeg.Encode(w, rendered, &jpeg.Options{Quality: quality})
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, rendered, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	profile := displayP3Profile()
+	// An APP2 segment holding the whole profile goes right after the SOI marker.
+	segment := []byte{0xFF, 0xE2, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+14+len(profile)))
+	segment = append(segment, "ICC_PROFILE\x00\x01\x01"...)
+	segment = append(segment, profile...)
+	for _, part := range [][]byte{data[:2], segment, data[2:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	displayP3Once  sync.Once
+	displayP3Bytes []byte
+)
+
+// displayP3Profile returns a minimal ICC v2 display profile for Display P3.
+func displayP3Profile() []byte {
+	displayP3Once.Do(func() {
+		xyz := func(x, y, z float64) []byte {
+			b := []byte("XYZ \x00\x00\x00\x00")
+			for _, v := range []float64{x, y, z} {
+				b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+			}
+			return b
+		}
+		desc := []byte("desc\x00\x00\x00\x00")
+		desc = binary.BigEndian.AppendUint32(desc, uint32(len("Display P3")+1))
+		desc = append(desc, "Display P3\x00"...)
+		desc = append(desc, make([]byte, 4+4+2+1+67)...)
+		curve := []byte("curv\x00\x00\x00\x00")
+		curve = binary.BigEndian.AppendUint32(curve, 1024)
+		for k := 0; k < 1024; k++ {
+			curve = binary.BigEndian.AppendUint16(curve, uint16(math.Round(srgbToLinear(float64(k)/1023)*65535)))
+		}
+		tags := []struct {
+			signature string
+			data      []byte
+		}{
+			{"desc", desc},
+			{"wtpt", xyz(0.964203, 1, 0.824905)},
+			{"rXYZ", xyz(0.515102, 0.241196, -0.001050)},
+			{"gXYZ", xyz(0.291965, 0.692236, 0.041877)},
+			{"bXYZ", xyz(0.157153, 0.066568, 0.784083)},
+			{"rTRC", curve},
+			{"gTRC", curve},
+			{"bTRC", curve},
+			{"cprt", []byte("text\x00\x00\x00\x00No copyright\x00")},
+		}
+		table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+		var body []byte
+		offset := 128 + 4 + 12*len(tags)
+		for _, tag := range tags {
+			table = append(table, tag.signature...)
+			table = binary.BigEndian.AppendUint32(table, uint32(offset+len(body)))
+			table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+			body = append(body, tag.data...)
+			for len(body)%4 != 0 {
+				body = append(body, 0)
+			}
+		}
+		header := make([]byte, 128)
+		binary.BigEndian.PutUint32(header, uint32(offset+len(body)))
+		copy(header[8:], "\x02\x10\x00\x00mntrRGB XYZ ")
+		copy(header[24:], "\x07\xea\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00acsp")
+		copy(header[68:], xyz(0.964203, 1, 0.824905)[8:])
+		displayP3Bytes = append(append(header, table...), body...)
+	})
+	return displayP3Bytes
+}
+
+// headWriter keeps the first bytes written to it, up to its limit, and discards the rest.
+type headWriter struct {
+	data  []byte
+	limit int
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if n := min(len(p), h.limit-len(h.data)); n > 0 {
+		h.data = append(h.data, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// detectColorSpace reads the color space of a PNG or JPEG file from its embedded color information.
+// Untagged files, other formats, and unrecognized profiles are treated as sRGB.
+func detectColorSpace(data []byte) ColorSpace {
+	var profile []byte
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		for p := 8; p+8 <= len(data); {
+			length := int(binary.BigEndian.Uint32(data[p:]))
+			kind := string(data[p+4 : p+8])
+			if length < 0 || p+8+length > len(data) || kind == "IDAT" {
+				break
+			}
+			chunk := data[p+8 : p+8+length]
+			switch kind {
+			case "cICP":
+				if len(chunk) > 0 && chunk[0] == 12 {
+					return ColorSpaceDisplayP3
+				}
+			case "iCCP":
+				if name := bytes.IndexByte(chunk, 0); name >= 0 && name+2 <= len(chunk) {
+					if zr, err := zlib.NewReader(bytes.NewReader(chunk[name+2:])); err == nil {
+						profile, _ = io.ReadAll(io.LimitReader(zr, 1<<20))
+					}
+				}
+			}
+			p += 12 + length
+		}
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		for p := 2; p+4 <= len(data) && data[p] == 0xFF; {
+			marker := data[p+1]
+			length := int(binary.BigEndian.Uint16(data[p+2:]))
+			if marker == 0xDA || p+2+length > len(data) {
+				break
+			}
+			segment := data[p+4 : p+2+length]
+			if marker == 0xE2 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")) && len(segment) >= 14 {
+				profile = append(profile, segment[14:]...)
+			}
+			p += 2 + length
+		}
+	}
+	return profileColorSpace(profile)
+}
+
+// profileColorSpace recognizes the color space of an ICC profile from its red primary.
+func profileColorSpace(profile []byte) ColorSpace {
+	if len(profile) < 132 {
+		return ColorSpaceSRGB
+	}
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	for k := 0; k < count && 132+12*k+12 <= len(profile); k++ {
+		entry := profile[132+12*k:]
+		if string(entry[:4]) != "rXYZ" {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(entry[4:]))
+		if offset < 0 || offset+12 > len(profile) {
+			break
+		}
+		x := float64(int32(binary.BigEndian.Uint32(profile[offset+8:]))) / 65536
+		if math.Abs(x-0.5151) < 0.02 {
+			return ColorSpaceDisplayP3
+		}
+		break
+	}
+	return ColorSpaceSRGB
+}
@@ -0,0 +1,211 @@
+package picrocess
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"os"
+)
+
+// contextReader is a reader that stops with the context's error once the context is done,
+// so long decodes can be cancelled between reads.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// contextWriter is a writer that stops with the context's error once the context is done,
+// so long encodes can be cancelled between writes.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+// LoadImageContext loads an image from a file like LoadImage, but stops as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// filename: The path to the image file to load.
+//
+// Returns: A pointer to an Image struct containing the decoded image, or an error if any issue occurs or the context is done.
+func LoadImageContext(ctx context.Context, filename string) (*Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := DecodeImage(&contextReader{ctx, file})
+	return img, err
+}
+
+// ImageURLContext loads an image from a URL like ImageURL, but the request and the decoding stop as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// url: The URL of the image to load.
+//
+// Returns: A pointer to an Image struct containing the decoded image, or an error if any issue occurs or the context is done.
+func ImageURLContext(ctx context.Context, url string) (*Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	img, _, err := DecodeImage(&contextReader{ctx, resp.Body})
+	return img, err
+}
+
+// saveContext creates the file and writes it with encode, stopping as soon as the context is done.
+func saveContext(ctx context.Context, filename string, encode func(w io.Writer) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return encode(&contextWriter{ctx, file})
+}
+
+// SaveAsPNGContext saves the Image as a PNG file like SaveAsPNG, but stops as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// filename: The path of the file to write.
+//
+// Returns: An error if the file cannot be written or the context is done.
+func (i *Image) SaveAsPNGContext(ctx context.Context, filename string) error {
+	return saveContext(ctx, filename, func(w io.Writer) error {
+		return i.encodePNG(w, currentRenderer())
+	})
+}
+
+// SaveAsJPGContext saves the Image as a JPG file like SaveAsJPG, but stops as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// filename: The path of the file to write.
+// quality: The JPEG quality, from 1 to 100.
+//
+// Returns: An error if the file cannot be written or the context is done.
+func (i *Image) SaveAsJPGContext(ctx context.Context, filename string, quality int) error {
+	return saveContext(ctx, filename, func(w io.Writer) error {
+		return i.encodeJPEG(w, quality, currentRenderer())
+	})
+}
+
+// SaveContext saves the image like Save, choosing the format from the file extension, but stops as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// filename: The path of the file to write.
+// opts: Optional settings passed to the encoder, such as WithQuality.
+//
+// Returns: An error if no encoder handles the extension, if the file cannot be written, or if the context is done.
+func (i *Image) SaveContext(ctx context.Context, filename string, opts ...Option) error {
+	format := formatForExtension(filename)
+	if format == "" {
+		return ErrUnknownFormat
+	}
+	return saveContext(ctx, filename, func(w io.Writer) error {
+		return i.EncodeFormat(w, format, opts...)
+	})
+}
+
+// ToGIFBufferContext converts the GIF like ToGIFBuffer, but stops between frames as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// opts: Optional settings, such as WithPalette.
+//
+// Returns: A bytes buffer containing the GIF data, or an error if encoding fails or the context is done.
+func (gf *GIF) ToGIFBufferContext(ctx context.Context, opts ...Option) (*bytes.Buffer, error) {
+	return gf.encodeContext(ctx, NewOptions(opts...))
+}
+
+// SaveAsGIFContext saves the GIF to a file like SaveAsGIF, but stops as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// filename: The path of the file to write.
+// opts: Optional settings, such as WithPalette.
+//
+// Returns: An error if encoding fails, the file cannot be written, or the context is done.
+func (gf *GIF) SaveAsGIFContext(ctx context.Context, filename string, opts ...Option) error {
+	buffer, err := gf.ToGIFBufferContext(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	return saveContext(ctx, filename, func(w io.Writer) error {
+		_, err := buffer.WriteTo(w)
+		return err
+	})
+}
+
+// AnimateContext builds a GIF like Animate, but stops between frames as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// frames: The number of frames to render.
+// fn: Renders the frame at progress t, which runs from 0 (first frame) to 1 (last frame).
+// opts: Optional settings; WithDelay sets the delay of every frame.
+//
+// Returns: A new GIF containing the rendered frames, or an error if the context is done or the Renderer fails.
+func AnimateContext(ctx context.Context, frames int, fn func(t float64) *Image, opts ...Option) (*GIF, error) {
+	delay := NewOptions(opts...).Delay
+	gf := NewGIF()
+	for k := 0; k < frames; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		t := 0.0
+		if frames > 1 {
+			t = float64(k) / float64(frames-1)
+		}
+		if err := gf.Append(fn(t), delay); err != nil {
+			return nil, err
+		}
+	}
+	return gf, nil
+}
+
+// RenderTimelineContext renders the timeline like RenderTimeline, but stops between frames as soon as the context is done.
+//
+// ctx: The context that cancels the operation or enforces its deadline.
+// fps: The number of frames per second.
+// duration: The length of the animation, in seconds.
+//
+// Returns: A new GIF containing one frame per time step, or an error if the context is done or the Renderer fails.
+func (tl *Timeline) RenderTimelineContext(ctx context.Context, fps int, duration float64) (*GIF, error) {
+	gf := NewGIF()
+	if fps <= 0 || duration <= 0 {
+		return gf, nil
+	}
+	sources := tl.prepare()
+	frames := int(math.Round(duration * float64(fps)))
+	for k := 0; k < frames; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := gf.Append(tl.renderFrame(sources, float64(k)/float64(fps), nil), frameDelay(k, fps)); err != nil {
+			return nil, err
+		}
+	}
+	return gf, nil
+}
@@ -0,0 +1,267 @@
+package picrocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/adler32"
+	"hash/crc32"
+	"image/color"
+	"io"
+)
+
+// WithDeterministic makes PNG encoding produce the same bytes for the same pixels with every Go version,
+// for content-addressed caches and checksums. The image is compressed by the package's own encoder
+// instead of compress/flate, and only the chunks needed to describe the pixels are written.
+// Files are somewhat larger than with the default encoder.
+func WithDeterministic() Option {
+	return func(o *Options) {
+		o.deterministic = true
+	}
+}
+
+var (
+	lengthBase  = [29]int{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	lengthExtra = [29]uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	distBase    = [30]int{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	distExtra   = [30]uint{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+)
+
+// bitWriter collects bits least significant first, as DEFLATE stores them.
+type bitWriter struct {
+	out  []byte
+	acc  uint64
+	bits uint
+}
+
+// write appends the n lowest bits of v.
+func (b *bitWriter) write(v uint64, n uint) {
+	b.acc |= v << b.bits
+	b.bits += n
+	for b.bits >= 8 {
+		b.out = append(b.out, byte(b.acc))
+		b.acc >>= 8
+		b.bits -= 8
+	}
+}
+
+// code appends a Huffman code of n bits, which DEFLATE stores most significant bit first.
+func (b *bitWriter) code(v uint64, n uint) {
+	reversed := uint64(0)
+	for k := uint(0); k < n; k++ {
+		reversed = reversed<<1 | v>>k&1
+	}
+	b.write(reversed, n)
+}
+
+// flush pads the last byte with zero bits and returns the written bytes.
+func (b *bitWriter) flush() []byte {
+	if b.bits > 0 {
+		b.out = append(b.out, byte(b.acc))
+		b.acc, b.bits = 0, 0
+	}
+	return b.out
+}
+
+// literal writes a literal/length symbol with the fixed Huffman code of DEFLATE.
+func (b *bitWriter) literal(symbol int) {
+	switch {
+	case symbol < 144:
+		b.code(uint64(0x30+symbol), 8)
+	case symbol < 256:
+		b.code(uint64(0x190+symbol-144), 9)
+	case symbol < 280:
+		b.code(uint64(symbol-256), 7)
+	default:
+		b.code(uint64(0xC0+symbol-280), 8)
+	}
+}
+
+// match writes a back reference of the given length and distance.
+func (b *bitWriter) match(length, distance int) {
+	l := len(lengthBase) - 1
+	for lengthBase[l] > length {
+		l--
+	}
+	b.literal(257 + l)
+	b.write(uint64(length-lengthBase[l]), lengthExtra[l])
+	d := len(distBase) - 1
+	for distBase[d] > distance {
+		d--
+	}
+	b.code(uint64(d), 5)
+	b.write(uint64(distance-distBase[d]), distExtra[d])
+}
+
+// deterministicZlib compresses the data into a zlib stream with a single fixed-Huffman DEFLATE block.
+// Matches are found greedily with hash chains of a fixed depth, so the output depends only on the input.
+func deterministicZlib(data []byte) []byte {
+	const (
+		window   = 1 << 15
+		maxChain = 64
+		minMatch = 3
+		maxMatch = 258
+		hashBits = 15
+	)
+	b := &bitWriter{out: []byte{0x78, 0x01}}
+	// BFINAL = 1, BTYPE = 01 (fixed Huffman codes).
+	b.write(1, 1)
+	b.write(1, 2)
+	head := make([]int, 1<<hashBits)
+	for k := range head {
+		head[k] = -1
+	}
+	prev := make([]int, window)
+	hash := func(p int) int {
+		return int((uint32(data[p])<<16|uint32(data[p+1])<<8|uint32(data[p+2]))*2654435761>>(32-hashBits)) & (1<<hashBits - 1)
+	}
+	insert := func(p int) {
+		if p+minMatch > len(data) {
+			return
+		}
+		h := hash(p)
+		prev[p%window] = head[h]
+		head[h] = p
+	}
+	for p := 0; p < len(data); {
+		bestLength, bestDistance := 0, 0
+		if p+minMatch <= len(data) {
+			candidate := head[hash(p)]
+			for chain := 0; candidate >= 0 && p-candidate <= window && chain < maxChain; chain++ {
+				n := 0
+				for n < maxMatch && p+n < len(data) && data[candidate+n] == data[p+n] {
+					n++
+				}
+				if n > bestLength {
+					bestLength, bestDistance = n, p-candidate
+					if n == maxMatch {
+						break
+					}
+				}
+				candidate = prev[candidate%window]
+			}
+		}
+		if bestLength >= minMatch {
+			b.match(bestLength, bestDistance)
+			for end := p + bestLength; p < end; p++ {
+				insert(p)
+			}
+			continue
+		}
+		b.literal(int(data[p]))
+		insert(p)
+		p++
+	}
+	b.literal(256)
+	return binary.BigEndian.AppendUint32(b.flush(), adler32.Checksum(data))
+}
+
+// pngChunk returns a PNG chunk of the given type, with its length and CRC.
+func pngChunk(kind string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], kind)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// encodeDeterministicPNG writes the image as a PNG rendered by r, whose bytes depend only on its pixels and color space.
+// Opaque images are written as 8-bit RGB and others as 8-bit RGBA, and every row uses the filter
+// with the smallest sum of absolute differences.
+func (i *Image) encodeDeterministicPNG(w io.Writer, r Renderer) error {
+	rendered, err := r.Render(i)
+	if err != nil {
+		return err
+	}
+	bounds := rendered.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	pixels := make([]color.NRGBA, 0, width*height)
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// Colors are converted the way image/png does, which un-premultiplies them.
+			n := color.NRGBAModel.Convert(rendered.At(x, y)).(color.NRGBA)
+			opaque = opaque && n.A == 255
+			pixels = append(pixels, n)
+		}
+	}
+	channels, colorType := 4, byte(6)
+	if opaque {
+		channels, colorType = 3, 2
+	}
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header, uint32(width))
+	binary.BigEndian.PutUint32(header[4:], uint32(height))
+	header[8], header[9] = 8, colorType
+	stride := width * channels
+	raw := make([]byte, 0, (stride+1)*height)
+	previous := make([]byte, stride)
+	current := make([]byte, stride)
+	filtered := make([][]byte, 5)
+	for f := range filtered {
+		filtered[f] = make([]byte, stride)
+	}
+	for y := 0; y < height; y++ {
+		for x, n := range pixels[y*width : (y+1)*width] {
+			copy(current[x*channels:], []byte{n.R, n.G, n.B, n.A}[:channels])
+		}
+		best, bestSum := 0, -1
+		for f := range filtered {
+			sum := 0
+			for k := 0; k < stride; k++ {
+				var left, upLeft byte
+				if k >= channels {
+					left, upLeft = current[k-channels], previous[k-channels]
+				}
+				up := previous[k]
+				var predictor byte
+				switch f {
+				case 1:
+					predictor = left
+				case 2:
+					predictor = up
+				case 3:
+					predictor = byte((int(left) + int(up)) / 2)
+				case 4:
+					predictor = paeth(left, up, upLeft)
+				}
+				v := current[k] - predictor
+				filtered[f][k] = v
+				sum += int(min(v, -v))
+			}
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = f, sum
+			}
+		}
+		raw = append(raw, byte(best))
+		raw = append(raw, filtered[best]...)
+		previous, current = current, previous
+	}
+	chunks := [][]byte{[]byte("\x89PNG\r\n\x1a\n"), pngChunk("IHDR", header)}
+	if i.ColorSpace == ColorSpaceDisplayP3 {
+		chunks = append(chunks, pngChunk("iCCP", append([]byte("Display P3\x00\x00"), deterministicZlib(displayP3Profile())...)))
+	}
+	chunks = append(chunks, pngChunk("IDAT", deterministicZlib(raw)), pngChunk("IEND", nil))
+	_, err = io.Copy(w, bytes.NewReader(bytes.Join(chunks, nil)))
+	return err
+}
+
+// paeth returns the Paeth predictor of PNG: whichever of a (left), b (up), and c (up-left) is closest to a + b - c.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
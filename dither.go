@@ -0,0 +1,164 @@
+package picrocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+type DitherMethod int
+
+const (
+	// DitherNone maps every pixel to the nearest palette color without dithering.
+	DitherNone DitherMethod = iota
+	// DitherFloydSteinberg spreads the color error of every pixel to its neighbors, for smooth gradients.
+	DitherFloydSteinberg
+	// DitherOrdered uses a repeating 8x8 Bayer pattern, for a retro cross-hatched look that stays stable across frames.
+	DitherOrdered
+)
+
+var bayer8x8 = [8][8]float64{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// nearestColor returns the index of the palette color closest to the color (r, g, b).
+func nearestColor(palette []RGBA, r, g, b float64) int {
+	best, bestDistance := 0, math.Inf(1)
+	for k, c := range palette {
+		dr, dg, db := r-float64(c.R), g-float64(c.G), b-float64(c.B)
+		d := dr*dr + dg*dg + db*db
+		if d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// ditherer maps pixels to the colors of a palette one at a time, row by row from the top left, keeping the error
+// of Floyd–Steinberg dithering between them. Dither and the GIF encoder both map pixels with it.
+type ditherer struct {
+	palette []RGBA
+	method  DitherMethod
+	// spread is the strength of the Bayer pattern of ordered dithering.
+	spread float64
+	// current and next hold the error spread to the pixels of the current and the next row, with a column of
+	// padding on either side.
+	current, next [][3]float64
+}
+
+// newDitherer returns a ditherer for rows of w pixels.
+func newDitherer(palette []RGBA, method DitherMethod, w int) *ditherer {
+	return &ditherer{
+		palette: palette,
+		method:  method,
+		spread:  255 / math.Max(1, math.Cbrt(float64(len(palette)))-1),
+		current: make([][3]float64, w+2),
+		next:    make([][3]float64, w+2),
+	}
+}
+
+// index returns the index of the palette color the pixel in column col of the current row is mapped to.
+// x and y are the coordinates of the pixel in its image, which place the Bayer pattern of ordered dithering.
+// Fully transparent pixels do not spread any error to their neighbors.
+func (d *ditherer) index(col, x, y int, pixel RGBA) int {
+	r, g, b := float64(pixel.R), float64(pixel.G), float64(pixel.B)
+	switch d.method {
+	case DitherOrdered:
+		offset := (bayer8x8[y%8][x%8]/64 - 0.5) * d.spread
+		r, g, b = r+offset, g+offset, b+offset
+	case DitherFloydSteinberg:
+		e := d.current[col+1]
+		r, g, b = r+e[0], g+e[1], b+e[2]
+	}
+	k := nearestColor(d.palette, r, g, b)
+	if d.method != DitherFloydSteinberg || pixel.A == 0 {
+		return k
+	}
+	c := d.palette[k]
+	err := [3]float64{r - float64(c.R), g - float64(c.G), b - float64(c.B)}
+	for ch := 0; ch < 3; ch++ {
+		d.current[col+2][ch] += err[ch] * 7 / 16
+		d.next[col][ch] += err[ch] * 3 / 16
+		d.next[col+1][ch] += err[ch] * 5 / 16
+		d.next[col+2][ch] += err[ch] * 1 / 16
+	}
+	return k
+}
+
+// endRow moves on to the next row.
+func (d *ditherer) endRow() {
+	d.current, d.next = d.next, d.current
+	clear(d.next)
+}
+
+// Dither reduces the image to the colors of the palette, using dithering to simulate the missing colors.
+// It can be used for retro pixel-art effects, or before encoding to formats with a limited palette such as GIF.
+// The alpha channel of every pixel is kept intact.
+//
+// palette: The colors the image is reduced to. If it is empty, the image is left unchanged.
+// method: The dithering method (DitherNone, DitherFloydSteinberg, or DitherOrdered).
+func (i *Image) Dither(palette []RGBA, method DitherMethod) {
+	if len(palette) == 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	d := newDitherer(palette, method, int(i.Width))
+	for y := 0; y < int(i.Height); y++ {
+		for x := 0; x < int(i.Width); x++ {
+			pixel := i.Pixel[x][y]
+			c := palette[d.index(x, x, y, pixel)]
+			i.Pixel[x][y] = RGBA{c.R, c.G, c.B, pixel.A}
+		}
+		d.endRow()
+	}
+}
+
+// WithDither dithers GIF frames when mapping them to their palette, so gradients stay smooth instead of banding.
+//
+// method: The dithering method, such as DitherFloydSteinberg.
+func WithDither(method DitherMethod) Option {
+	return func(o *Options) {
+		o.dither = method
+	}
+}
+
+// ditherPaletted maps a frame onto a palette with dithering. Fully transparent pixels are mapped to the transparent
+// color of the palette, if it has one, and do not spread any error.
+func ditherPaletted(frame *image.RGBA, palette color.Palette, method DitherMethod) *image.Paletted {
+	bounds := frame.Bounds()
+	respond := image.NewPaletted(bounds, palette)
+	colors := make([]RGBA, 0, len(palette))
+	indices := make([]uint8, 0, len(palette))
+	transparent := -1
+	for k, c := range palette {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		if rgba.A == 0 {
+			transparent = k
+			continue
+		}
+		colors = append(colors, RGBA{rgba.R, rgba.G, rgba.B, rgba.A})
+		indices = append(indices, uint8(k))
+	}
+	if len(colors) == 0 {
+		return respond
+	}
+	d := newDitherer(colors, method, bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := frame.RGBAAt(x, y)
+			if pixel.A == 0 && transparent >= 0 {
+				respond.SetColorIndex(x, y, uint8(transparent))
+				continue
+			}
+			respond.SetColorIndex(x, y, indices[d.index(x-bounds.Min.X, x, y, RGBA{pixel.R, pixel.G, pixel.B, pixel.A})])
+		}
+		d.endRow()
+	}
+	return respond
+}
@@ -0,0 +1,169 @@
+package picrocess
+
+import "math"
+
+type EdgeMethod int
+
+const (
+	// EdgeSobel marks edges with the gradient magnitude of the Sobel operator.
+	EdgeSobel EdgeMethod = iota
+	// EdgeCanny produces thin, binary edges using the Canny edge detector.
+	EdgeCanny
+)
+
+// luminance returns the brightness of every pixel in the image as a 2D slice (X / Y).
+func (i *Image) luminance() [][]float64 {
+	lum := make([][]float64, i.Width)
+	for x := range i.Pixel {
+		lum[x] = make([]float64, i.Height)
+		for y := range i.Pixel[x] {
+			lum[x][y] = float64(i.Pixel[x][y].Brightness())
+		}
+	}
+	return lum
+}
+
+// sobel calculates the horizontal and vertical Sobel gradients of a luminance map.
+// Pixels outside the map are clamped to the nearest edge pixel.
+func sobel(lum [][]float64, w, h uint) ([][]float64, [][]float64) {
+	at := func(x, y int) float64 {
+		x = max(0, min(x, int(w)-1))
+		y = max(0, min(y, int(h)-1))
+		return lum[x][y]
+	}
+	gx := make([][]float64, w)
+	gy := make([][]float64, w)
+	for x := 0; x < int(w); x++ {
+		gx[x] = make([]float64, h)
+		gy[x] = make([]float64, h)
+		for y := 0; y < int(h); y++ {
+			gx[x][y] = -at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1) +
+				at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1)
+			gy[x][y] = -at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1) +
+				at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1)
+		}
+	}
+	return gx, gy
+}
+
+// EdgeDetect creates an edge map of the image using the specified method.
+// Edges are drawn in white on a black, fully opaque background.
+//
+// method: The edge detection algorithm to use (EdgeSobel or EdgeCanny).
+//
+// Returns: A new Image containing the edge map.
+func (i *Image) EdgeDetect(method EdgeMethod) *Image {
+	respond := NewImage(i.Width, i.Height, NewRGBA(0, 0, 0))
+	if i.Width == 0 || i.Height == 0 {
+		return respond
+	}
+	lum := i.luminance()
+	if method == EdgeCanny {
+		lum = gaussianKernelBlur(lum, i.Width, i.Height, 1.4)
+	}
+	gx, gy := sobel(lum, i.Width, i.Height)
+	magnitude := make([][]float64, i.Width)
+	maxMagnitude := 0.0
+	for x := range magnitude {
+		magnitude[x] = make([]float64, i.Height)
+		for y := range magnitude[x] {
+			magnitude[x][y] = math.Hypot(gx[x][y], gy[x][y])
+			maxMagnitude = math.Max(maxMagnitude, magnitude[x][y])
+		}
+	}
+	if maxMagnitude == 0 {
+		return respond
+	}
+	if method != EdgeCanny {
+		for x := range magnitude {
+			for y := range magnitude[x] {
+				v := uint8(magnitude[x][y] / maxMagnitude * 255)
+				respond.Pixel[x][y] = NewRGBA(v, v, v)
+			}
+		}
+		return respond
+	}
+	edges := canny(magnitude, gx, gy, i.Width, i.Height, maxMagnitude)
+	for x := range edges {
+		for y := range edges[x] {
+			if edges[x][y] {
+				respond.Pixel[x][y] = NewRGBA(255, 255, 255)
+			}
+		}
+	}
+	return respond
+}
+
+// canny applies non-maximum suppression and hysteresis thresholding to a gradient magnitude map.
+// The high threshold is 20% of the strongest gradient and the low threshold is half of it.
+func canny(magnitude, gx, gy [][]float64, w, h uint, maxMagnitude float64) [][]bool {
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= int(w) || y >= int(h) {
+			return 0
+		}
+		return magnitude[x][y]
+	}
+	high := maxMagnitude * 0.2
+	low := high * 0.5
+	strong := make([][]bool, w)
+	weak := make([][]bool, w)
+	for x := 0; x < int(w); x++ {
+		strong[x] = make([]bool, h)
+		weak[x] = make([]bool, h)
+		for y := 0; y < int(h); y++ {
+			m := magnitude[x][y]
+			if m < low {
+				continue
+			}
+			angle := math.Atan2(gy[x][y], gx[x][y]) * 180 / math.Pi
+			if angle < 0 {
+				angle += 180
+			}
+			var n1, n2 float64
+			switch {
+			case angle < 22.5 || angle >= 157.5:
+				n1, n2 = at(x-1, y), at(x+1, y)
+			case angle < 67.5:
+				n1, n2 = at(x-1, y-1), at(x+1, y+1)
+			case angle < 112.5:
+				n1, n2 = at(x, y-1), at(x, y+1)
+			default:
+				n1, n2 = at(x+1, y-1), at(x-1, y+1)
+			}
+			if m < n1 || m < n2 {
+				continue
+			}
+			if m >= high {
+				strong[x][y] = true
+			} else {
+				weak[x][y] = true
+			}
+		}
+	}
+	stack := make([][2]int, 0)
+	for x := range strong {
+		for y := range strong[x] {
+			if strong[x][y] {
+				stack = append(stack, [2]int{x, y})
+			}
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				nx, ny := p[0]+dx, p[1]+dy
+				if nx < 0 || ny < 0 || nx >= int(w) || ny >= int(h) {
+					continue
+				}
+				if weak[nx][ny] {
+					weak[nx][ny] = false
+					strong[nx][ny] = true
+					stack = append(stack, [2]int{nx, ny})
+				}
+			}
+		}
+	}
+	return strong
+}
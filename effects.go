@@ -0,0 +1,216 @@
+package picrocess
+
+import "math"
+
+type GradientStop struct {
+	Position float64 // 0 to 1
+	Color    RGBA
+}
+
+// NewGradientStop creates a GradientStop at the given position (0 to 1) with the given color.
+//
+// position: The position of the stop along the gradient, from 0 (start) to 1 (end).
+// c: The color at that position.
+//
+// Returns: A GradientStop struct initialized with the given position and color.
+func NewGradientStop(position float64, c RGBA) GradientStop {
+	return GradientStop{
+		Position: position,
+		Color:    c,
+	}
+}
+
+// gradientAt returns the color of a gradient at position t (0 to 1) by interpolating between the surrounding stops.
+// The stops are expected to be sorted by position.
+func gradientAt(stops []GradientStop, t float64) RGBA {
+	if len(stops) == 0 {
+		return RGBA{}
+	}
+	if t <= stops[0].Position {
+		return stops[0].Color
+	}
+	for s := 1; s < len(stops); s++ {
+		if t > stops[s].Position {
+			continue
+		}
+		a, b := stops[s-1], stops[s]
+		span := b.Position - a.Position
+		if span <= 0 {
+			return b.Color
+		}
+		return lerpRGBA(a.Color, b.Color, (t-a.Position)/span)
+	}
+	return stops[len(stops)-1].Color
+}
+
+// lerpRGBA linearly interpolates between two colors, where t=0 returns a and t=1 returns b.
+func lerpRGBA(a, b RGBA, t float64) RGBA {
+	return RGBA{
+		R: clampUint8(float64(a.R) + (float64(b.R)-float64(a.R))*t),
+		G: clampUint8(float64(a.G) + (float64(b.G)-float64(a.G))*t),
+		B: clampUint8(float64(a.B) + (float64(b.B)-float64(a.B))*t),
+		A: clampUint8(float64(a.A) + (float64(b.A)-float64(a.A))*t),
+	}
+}
+
+// LayerEffect is a Photoshop-style effect that is applied to the alpha silhouette of an image.
+type LayerEffect interface {
+	Apply(i *Image)
+}
+
+// ApplyEffects applies the given layer effects to the image, in order.
+func (i *Image) ApplyEffects(effects ...LayerEffect) {
+	for _, effect := range effects {
+		effect.Apply(i)
+	}
+}
+
+// paintInside blends the color c over every pixel with the given coverage (0 to 1), keeping the pixel's own alpha.
+func (i *Image) paintInside(x, y int, c RGBA, coverage float64) {
+	pixel := i.Pixel[x][y]
+	if pixel.A == 0 || coverage <= 0 {
+		return
+	}
+	c.A = clampUint8(float64(c.A) * math.Min(coverage, 1))
+	blended := c.over(RGBA{pixel.R, pixel.G, pixel.B, 255})
+	blended.A = pixel.A
+	i.Pixel[x][y] = blended
+}
+
+// invertedAlpha returns the transparent area of the image as a 2D value map (X / Y),
+// shifted by the given offset. Pixels shifted in from outside the image count as fully transparent.
+func (i *Image) invertedAlpha(dx, dy int) [][]float64 {
+	values := make([][]float64, i.Width)
+	for x := range values {
+		values[x] = make([]float64, i.Height)
+		for y := range values[x] {
+			sx, sy := x-dx, y-dy
+			if sx < 0 || sy < 0 || sx >= int(i.Width) || sy >= int(i.Height) {
+				values[x][y] = 255
+				continue
+			}
+			values[x][y] = 255 - float64(i.Pixel[sx][sy].A)
+		}
+	}
+	return values
+}
+
+type ColorOverlay struct {
+	Color RGBA
+}
+
+// Apply fills the silhouette of the image with the overlay color, using its alpha as the opacity.
+func (e ColorOverlay) Apply(i *Image) {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			i.paintInside(x, y, e.Color, 1)
+		}
+	}
+}
+
+type GradientOverlay struct {
+	Stops []GradientStop
+	Angle float64 // Degrees, 0 runs left to right and 90 runs top to bottom
+}
+
+// Apply fills the silhouette of the image with a linear gradient spanning the whole image.
+func (e GradientOverlay) Apply(i *Image) {
+	rad := e.Angle * math.Pi / 180
+	dx, dy := math.Cos(rad), math.Sin(rad)
+	cx, cy := float64(i.Width)/2, float64(i.Height)/2
+	half := (math.Abs(dx)*float64(i.Width) + math.Abs(dy)*float64(i.Height)) / 2
+	if half == 0 {
+		return
+	}
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			t := ((float64(x)+0.5-cx)*dx + (float64(y)+0.5-cy)*dy + half) / (2 * half)
+			i.paintInside(x, y, gradientAt(e.Stops, t), 1)
+		}
+	}
+}
+
+type InnerShadow struct {
+	Color            RGBA
+	OffsetX, OffsetY int
+	Blur             float64
+}
+
+// Apply draws a shadow inside the silhouette edge, as if the silhouette were cut out of the surface.
+func (e InnerShadow) Apply(i *Image) {
+	shadow := gaussianKernelBlur(i.invertedAlpha(e.OffsetX, e.OffsetY), i.Width, i.Height, e.Blur/2)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			i.paintInside(x, y, e.Color, shadow[x][y]/255)
+		}
+	}
+}
+
+type InnerGlow struct {
+	Color  RGBA
+	Radius float64
+}
+
+// Apply draws a glow that fades from the silhouette edge towards its center.
+func (e InnerGlow) Apply(i *Image) {
+	glow := gaussianKernelBlur(i.invertedAlpha(0, 0), i.Width, i.Height, e.Radius/2)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			i.paintInside(x, y, e.Color, glow[x][y]/255*2)
+		}
+	}
+}
+
+type BevelEmboss struct {
+	Size      float64 // How far the bevel reaches into the silhouette, in pixels
+	Depth     float64 // The strength of the shading, 1 is the default
+	Angle     float64 // The direction the light comes from, in degrees (0 is from the right, 90 from the bottom)
+	Highlight RGBA
+	Shadow    RGBA
+}
+
+// NewBevelEmboss creates a BevelEmboss with a white highlight and a black shadow, lit from the top left.
+//
+// size: How far the bevel reaches into the silhouette, in pixels.
+// depth: The strength of the shading, 1 is the default.
+//
+// Returns: A BevelEmboss struct initialized with the given size and depth.
+func NewBevelEmboss(size, depth float64) BevelEmboss {
+	return BevelEmboss{
+		Size:      size,
+		Depth:     depth,
+		Angle:     225,
+		Highlight: NewRGBA(255, 255, 255, 191),
+		Shadow:    NewRGBA(0, 0, 0, 191),
+	}
+}
+
+// Apply shades the silhouette as if it were raised from the surface and lit from the configured angle.
+func (e BevelEmboss) Apply(i *Image) {
+	if e.Size <= 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	height := make([][]float64, i.Width)
+	for x := range height {
+		height[x] = make([]float64, i.Height)
+		for y := range height[x] {
+			height[x][y] = float64(i.Pixel[x][y].A)
+		}
+	}
+	height = gaussianKernelBlur(height, i.Width, i.Height, e.Size/2)
+	gx, gy := sobel(height, i.Width, i.Height)
+	rad := e.Angle * math.Pi / 180
+	lx, ly := math.Cos(rad), math.Sin(rad)
+	scale := e.Depth / (255 / e.Size * 4)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			// The edge faces outwards (against the alpha gradient), so it is lit when that points at the light.
+			shade := -(gx[x][y]*lx + gy[x][y]*ly) * scale
+			if shade > 0 {
+				i.paintInside(x, y, e.Highlight, shade)
+			} else if shade < 0 {
+				i.paintInside(x, y, e.Shadow, -shade)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package picrocess
+
+import (
+	"sort"
+	"strings"
+)
+
+// EmojiSquares maps the colors of the colored square emoji to the emoji, for use with ToEmojiArt.
+var EmojiSquares = map[RGBA]string{
+	{221, 46, 68, 255}:   "🟥",
+	{244, 144, 12, 255}:  "🟧",
+	{253, 203, 88, 255}:  "🟨",
+	{120, 177, 89, 255}:  "🟩",
+	{85, 172, 238, 255}:  "🟦",
+	{170, 142, 214, 255}: "🟪",
+	{193, 105, 79, 255}:  "🟫",
+	{49, 55, 61, 255}:    "⬛",
+	{230, 231, 232, 255}: "⬜",
+}
+
+// ToEmojiArt converts the image into colorful "pixel art" made of emoji, for chat platforms that render them in color.
+// The image is resized to the given width and height, and every pixel is replaced with the emoji of the nearest palette color.
+// Transparent pixels use the emoji of the palette's entry closest to transparent black, if the palette has one with alpha 0.
+//
+// w: The number of emoji per line.
+// h: The number of lines.
+// palette: The emoji of every color, such as EmojiSquares. If it is nil or empty, EmojiSquares is used.
+//
+// Returns: The emoji art, with one line per row of the resized image.
+func (i *Image) ToEmojiArt(w, h uint, palette map[RGBA]string) string {
+	if len(palette) == 0 {
+		palette = EmojiSquares
+	}
+	colors := make([]RGBA, 0, len(palette))
+	opaque := make([]RGBA, 0, len(palette))
+	var transparent *RGBA
+	for c := range palette {
+		colors = append(colors, c)
+	}
+	// Sort the colors so ties between equally near colors always resolve the same way.
+	sort.Slice(colors, func(a, b int) bool {
+		ca, cb := colors[a], colors[b]
+		return uint32(ca.R)<<24|uint32(ca.G)<<16|uint32(ca.B)<<8|uint32(ca.A) < uint32(cb.R)<<24|uint32(cb.G)<<16|uint32(cb.B)<<8|uint32(cb.A)
+	})
+	for k, c := range colors {
+		if c.A == 0 {
+			if transparent == nil {
+				transparent = &colors[k]
+			}
+			continue
+		}
+		opaque = append(opaque, c)
+	}
+	if len(opaque) == 0 {
+		opaque = colors
+	}
+	img := i.clone()
+	img.Resize(w, h, WithFilter(FilterBilinear))
+	var sb strings.Builder
+	for y := uint(0); y < h; y++ {
+		for x := uint(0); x < w; x++ {
+			pixel := img.Pixel[x][y]
+			if pixel.A < 128 && transparent != nil {
+				sb.WriteString(palette[*transparent])
+				continue
+			}
+			c := opaque[nearestColor(opaque, float64(pixel.R), float64(pixel.G), float64(pixel.B))]
+			sb.WriteString(palette[c])
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
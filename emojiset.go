@@ -0,0 +1,178 @@
+package picrocess
+
+import (
+	"image"
+	"image/draw"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EmojiSet holds images of emoji, so text can show emoji in color even though fonts are drawn in a single color,
+// such as usernames with emoji on rank cards. Use it with Font.WithEmoji.
+// An EmojiSet is safe for concurrent use.
+type EmojiSet struct {
+	mu    sync.Mutex
+	emoji map[string]*emojiEntry
+	// longest is the number of code points of the longest emoji sequence in the set.
+	longest int
+}
+
+// emojiEntry is an emoji image, decoded from its file the first time it is drawn.
+type emojiEntry struct {
+	img  *Image
+	fsys fs.FS
+	name string
+}
+
+// NewEmojiSet creates an empty EmojiSet. Add emoji with Add, or load a whole set with LoadEmojiDir or LoadEmojiFS.
+//
+// Returns: A new, empty EmojiSet.
+func NewEmojiSet() *EmojiSet {
+	return &EmojiSet{emoji: make(map[string]*emojiEntry)}
+}
+
+// emojiKey returns the key of an emoji sequence: its code points in lowercase hexadecimal, joined with "-".
+// Variation selector 16 (U+FE0F), which only asks for the emoji style, is left out, as emoji image sets do.
+func emojiKey(runes []rune) (string, int) {
+	parts := make([]string, 0, len(runes))
+	for _, r := range runes {
+		if r != 0xFE0F {
+			parts = append(parts, strconv.FormatInt(int64(r), 16))
+		}
+	}
+	return strings.Join(parts, "-"), len(parts)
+}
+
+func (s *EmojiSet) add(runes []rune, entry *emojiEntry) {
+	key, n := emojiKey(runes)
+	if n == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emoji[key] = entry
+	s.longest = max(s.longest, n)
+}
+
+// Add adds the image of an emoji, replacing any image it already had.
+//
+// emoji: The emoji, such as "😀" or a sequence joined with zero-width joiners such as "👩‍💻".
+// img: The image to draw for the emoji. It is scaled to the font size.
+func (s *EmojiSet) Add(emoji string, img *Image) {
+	s.add([]rune(emoji), &emojiEntry{img: img})
+}
+
+// LoadEmojiFS loads an emoji set from a directory of images named after the code points of their emoji in hexadecimal,
+// such as the PNG files of Twemoji ("1f600.png", "1f469-200d-1f4bb.png") or Noto Emoji ("emoji_u1f600.png").
+// The images are decoded the first time their emoji is drawn, so large sets load quickly.
+//
+// fsys: The file system to read the images from, such as an embed.FS.
+// dir: The directory of the images within the file system, such as "72x72".
+//
+// Returns: The EmojiSet, or an error if the directory cannot be read.
+func LoadEmojiFS(fsys fs.FS, dir string) (*EmojiSet, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	s := NewEmojiSet()
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		base := strings.TrimPrefix(strings.TrimSuffix(name, path.Ext(name)), "emoji_u")
+		var runes []rune
+		for _, part := range strings.FieldsFunc(base, func(r rune) bool { return r == '-' || r == '_' }) {
+			r, err := strconv.ParseUint(part, 16, 32)
+			if err != nil {
+				runes = nil
+				break
+			}
+			runes = append(runes, rune(r))
+		}
+		if len(runes) > 0 {
+			s.add(runes, &emojiEntry{fsys: fsys, name: path.Join(dir, name)})
+		}
+	}
+	return s, nil
+}
+
+// LoadEmojiDir loads an emoji set from a directory of images, as LoadEmojiFS does.
+//
+// dir: The path of the directory, such as "twemoji/assets/72x72".
+//
+// Returns: The EmojiSet, or an error if the directory cannot be read.
+func LoadEmojiDir(dir string) (*EmojiSet, error) {
+	return LoadEmojiFS(os.DirFS(dir), ".")
+}
+
+// match returns the image of the longest emoji sequence at the start of the runes, and the number of runes it spans.
+// It returns nil if no emoji of the set starts there, or if its image cannot be decoded.
+func (s *EmojiSet) match(runes []rune) (*Image, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best *emojiEntry
+	length, count := 0, 0
+	for k := 0; k < len(runes) && count < s.longest; k++ {
+		if runes[k] == 0xFE0F {
+			// A trailing variation selector belongs to the emoji before it.
+			if best != nil && length == k {
+				length = k + 1
+			}
+			continue
+		}
+		count++
+		key, _ := emojiKey(runes[:k+1])
+		if entry, ok := s.emoji[key]; ok {
+			best, length = entry, k+1
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	if best.img == nil && best.fsys != nil {
+		file, err := best.fsys.Open(best.name)
+		if err == nil {
+			best.img, _, _ = DecodeImage(file)
+			file.Close()
+		}
+		// Do not try to decode a broken file again.
+		best.fsys = nil
+	}
+	if best.img == nil {
+		return nil, 0
+	}
+	return best.img, length
+}
+
+// WithEmoji returns a copy of the font that draws the emoji of the set as images, in color, instead of
+// as glyphs of the font. Text, TextBox, and the measuring functions all lay the emoji out like glyphs
+// one font size wide.
+//
+// set: The emoji images to use, such as one loaded with LoadEmojiDir.
+//
+// Returns: A new Font using the emoji set.
+func (f *Font) WithEmoji(set *EmojiSet) *Font {
+	font := *f
+	font.emoji = set
+	return &font
+}
+
+// drawEmoji draws an emoji image scaled into a square of the given side, with its top-left corner at (x, y).
+func drawEmoji(dst draw.Image, emoji *Image, x, y, side int) {
+	if side <= 0 || emoji.Width == 0 || emoji.Height == 0 {
+		return
+	}
+	// Halve the image until it is close to the target size, so that scaling it down averages all of its pixels.
+	scaled := emoji
+	for scaled.Width >= uint(side)*2 && scaled.Height >= uint(side)*2 {
+		scaled = scaled.resampled(scaled.Width/2, scaled.Height/2)
+	}
+	scaled = scaled.resampled(uint(side), uint(side))
+	draw.Draw(dst, image.Rect(x, y, x+side, y+side), scaled.Render(), image.Point{}, draw.Over)
+}
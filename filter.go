@@ -0,0 +1,570 @@
+package picrocess
+
+import "math"
+
+// BilateralFilter smooths the image while preserving edges, such as for skin smoothing or noise reduction.
+// Each pixel is replaced by a weighted average of its neighbors, where the weight falls off both with the
+// spatial distance and with the color difference, so pixels across a strong edge barely contribute.
+//
+// sigmaSpace: The spatial standard deviation, in pixels. Larger values smooth over a wider area.
+// sigmaColor: The color standard deviation (0-255 scale). Larger values smooth across stronger edges.
+func (i *Image) BilateralFilter(sigmaSpace, sigmaColor float64) {
+	if sigmaSpace <= 0 || sigmaColor <= 0 {
+		return
+	}
+	radius := int(math.Ceil(sigmaSpace * 2))
+	spatial := make([][]float64, radius*2+1)
+	for dx := -radius; dx <= radius; dx++ {
+		spatial[dx+radius] = make([]float64, radius*2+1)
+		for dy := -radius; dy <= radius; dy++ {
+			spatial[dx+radius][dy+radius] = math.Exp(-float64(dx*dx+dy*dy) / (2 * sigmaSpace * sigmaSpace))
+		}
+	}
+	rangeWeight := make([]float64, 255*255*3+1)
+	for d := range rangeWeight {
+		rangeWeight[d] = math.Exp(-float64(d) / (2 * sigmaColor * sigmaColor))
+	}
+	w, h := int(i.Width), int(i.Height)
+	newPixel := make([][]RGBA, w)
+	for x := 0; x < w; x++ {
+		newPixel[x] = make([]RGBA, h)
+		for y := 0; y < h; y++ {
+			center := i.Pixel[x][y]
+			var sumR, sumG, sumB, sumA, sumW float64
+			for dx := -radius; dx <= radius; dx++ {
+				sx := x + dx
+				if sx < 0 || sx >= w {
+					continue
+				}
+				for dy := -radius; dy <= radius; dy++ {
+					sy := y + dy
+					if sy < 0 || sy >= h {
+						continue
+					}
+					p := i.Pixel[sx][sy]
+					dr := int(p.R) - int(center.R)
+					dg := int(p.G) - int(center.G)
+					db := int(p.B) - int(center.B)
+					weight := spatial[dx+radius][dy+radius] * rangeWeight[dr*dr+dg*dg+db*db]
+					sumR += float64(p.R) * weight
+					sumG += float64(p.G) * weight
+					sumB += float64(p.B) * weight
+					sumA += float64(p.A) * weight
+					sumW += weight
+				}
+			}
+			newPixel[x][y] = RGBA{
+				R: clampUint8(sumR / sumW),
+				G: clampUint8(sumG / sumW),
+				B: clampUint8(sumB / sumW),
+				A: clampUint8(sumA / sumW),
+			}
+		}
+	}
+	i.Pixel = newPixel
+}
+
+type GrayMode int
+
+const (
+	// GrayLuminosity weights the channels by their perceived brightness (0.299 R, 0.587 G, 0.114 B).
+	GrayLuminosity GrayMode = iota
+	// GrayAverage uses the plain average of the red, green, and blue channels.
+	GrayAverage
+	// GrayRed uses only the red channel.
+	GrayRed
+	// GrayGreen uses only the green channel.
+	GrayGreen
+	// GrayBlue uses only the blue channel.
+	GrayBlue
+)
+
+// Grayscale converts the image to grayscale using the specified mode.
+// The alpha channel of every pixel is kept intact.
+//
+// mode: How the red, green, and blue channels are combined into a single gray value.
+func (i *Image) Grayscale(mode GrayMode) {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			var v uint8
+			switch mode {
+			case GrayAverage:
+				v = uint8((uint(pixel.R) + uint(pixel.G) + uint(pixel.B)) / 3)
+			case GrayRed:
+				v = pixel.R
+			case GrayGreen:
+				v = pixel.G
+			case GrayBlue:
+				v = pixel.B
+			default:
+				v = clampUint8(0.299*float64(pixel.R) + 0.587*float64(pixel.G) + 0.114*float64(pixel.B))
+			}
+			i.Pixel[x][y] = RGBA{v, v, v, pixel.A}
+		}
+	}
+}
+
+// Sepia applies a warm, brownish sepia tone to the image, such as for vintage profile cards.
+// The alpha channel of every pixel is kept intact.
+//
+// intensity: How strongly the sepia tone is applied, from 0 (unchanged) to 1 (full sepia).
+func (i *Image) Sepia(intensity float64) {
+	intensity = math.Max(0, math.Min(1, intensity))
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			r, g, b := float64(pixel.R), float64(pixel.G), float64(pixel.B)
+			sepia := RGBA{
+				R: clampUint8(0.393*r + 0.769*g + 0.189*b),
+				G: clampUint8(0.349*r + 0.686*g + 0.168*b),
+				B: clampUint8(0.272*r + 0.534*g + 0.131*b),
+				A: pixel.A,
+			}
+			i.Pixel[x][y] = lerpRGBA(pixel, sepia, intensity)
+		}
+	}
+}
+
+// Duotone recolors the image with two colors, mapping dark pixels to the shadow color
+// and bright pixels to the highlight color, with a smooth blend in between.
+// The alpha channel of every pixel is kept intact.
+//
+// shadow: The color used for the darkest pixels.
+// highlight: The color used for the brightest pixels.
+func (i *Image) Duotone(shadow, highlight RGBA) {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			lum := (0.299*float64(pixel.R) + 0.587*float64(pixel.G) + 0.114*float64(pixel.B)) / 255
+			c := lerpRGBA(shadow, highlight, lum)
+			c.A = pixel.A
+			i.Pixel[x][y] = c
+		}
+	}
+}
+
+// rgbToHSL converts a color to hue (0-360 degrees), saturation (0-1), and lightness (0-1).
+func rgbToHSL(c RGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	l := (maxC + minC) / 2
+	if maxC == minC {
+		return 0, 0, l
+	}
+	d := maxC - minC
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+// hslToRGB converts hue (0-360 degrees), saturation (0-1), and lightness (0-1) to a color with the given alpha.
+func hslToRGB(h, s, l float64, a uint8) RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g, b = c, x, 0
+	case hp < 2:
+		r, g, b = x, c, 0
+	case hp < 3:
+		r, g, b = 0, c, x
+	case hp < 4:
+		r, g, b = 0, x, c
+	case hp < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	m := l - c/2
+	return RGBA{
+		R: clampUint8((r + m) * 255),
+		G: clampUint8((g + m) * 255),
+		B: clampUint8((b + m) * 255),
+		A: a,
+	}
+}
+
+// AdjustHSL shifts the hue and scales the saturation and lightness of every pixel, such as for
+// recoloring a blue template to red. The alpha channel of every pixel is kept intact.
+//
+// hueShift: The number of degrees to rotate the hue by (for example, 120 turns red into green).
+// satFactor: The factor to multiply the saturation by (0 removes all color, 1 keeps it unchanged).
+// lightFactor: The factor to multiply the lightness by (1 keeps it unchanged).
+func (i *Image) AdjustHSL(hueShift, satFactor, lightFactor float64) {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			h, s, l := rgbToHSL(pixel)
+			h = math.Mod(h+hueShift, 360)
+			if h < 0 {
+				h += 360
+			}
+			s = math.Max(0, math.Min(1, s*satFactor))
+			l = math.Max(0, math.Min(1, l*lightFactor))
+			i.Pixel[x][y] = hslToRGB(h, s, l, pixel.A)
+		}
+	}
+}
+
+// kelvinToRGB approximates the color of a black-body light source at the given temperature, in Kelvin.
+// Each channel is returned in the 0-255 range.
+func kelvinToRGB(kelvin float64) (float64, float64, float64) {
+	t := math.Max(1000, math.Min(40000, kelvin)) / 100
+	var r, g, b float64
+	if t <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+	switch {
+	case t >= 66:
+		b = 255
+	case t <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+	clamp := func(v float64) float64 {
+		return math.Max(0, math.Min(255, v))
+	}
+	return clamp(r), clamp(g), clamp(b)
+}
+
+// Temperature warms or cools the colors of the image, like the temperature slider of a photo editor.
+// The image is tinted as if it were lit by a light source that is kelvinShift degrees away from daylight (6500 K).
+// The alpha channel of every pixel is kept intact.
+//
+// kelvinShift: The temperature change in Kelvin. Positive values make the image warmer (more orange),
+// negative values make it cooler (more blue), such as for correcting photos taken under indoor lighting.
+func (i *Image) Temperature(kelvinShift float64) {
+	nr, ng, nb := kelvinToRGB(6500)
+	tr, tg, tb := kelvinToRGB(6500 - kelvinShift)
+	i.scaleChannels(tr/nr, tg/ng, tb/nb)
+}
+
+// AutoWhiteBalance removes color casts from the image using the gray-world assumption:
+// the average color of a scene should be neutral gray, so each channel is scaled until the averages match.
+// Fully transparent pixels are ignored, and the alpha channel of every pixel is kept intact.
+func (i *Image) AutoWhiteBalance() {
+	var sumR, sumG, sumB, count float64
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			if pixel.A == 0 {
+				continue
+			}
+			sumR += float64(pixel.R)
+			sumG += float64(pixel.G)
+			sumB += float64(pixel.B)
+			count++
+		}
+	}
+	if count == 0 || sumR == 0 || sumG == 0 || sumB == 0 {
+		return
+	}
+	gray := (sumR + sumG + sumB) / 3
+	i.scaleChannels(gray/sumR, gray/sumG, gray/sumB)
+}
+
+// scaleChannels multiplies the red, green, and blue channels of every pixel by the given factors.
+func (i *Image) scaleChannels(r, g, b float64) {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			i.Pixel[x][y] = RGBA{
+				R: clampUint8(float64(pixel.R) * r),
+				G: clampUint8(float64(pixel.G) * g),
+				B: clampUint8(float64(pixel.B) * b),
+				A: pixel.A,
+			}
+		}
+	}
+}
+
+// Posterize reduces every color channel to the given number of evenly spaced levels,
+// for pop-art style images or for reducing the number of colors before GIF encoding.
+// The alpha channel of every pixel is kept intact.
+//
+// levels: The number of levels per channel (2 or more; smaller values are treated as 2).
+func (i *Image) Posterize(levels uint8) {
+	if levels < 2 {
+		levels = 2
+	}
+	step := 255 / float64(levels-1)
+	quantize := func(v uint8) uint8 {
+		return clampUint8(math.Round(float64(v)/step) * step)
+	}
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			i.Pixel[x][y] = RGBA{quantize(pixel.R), quantize(pixel.G), quantize(pixel.B), pixel.A}
+		}
+	}
+}
+
+// Threshold converts the image to pure black and white, such as for stencils.
+// Pixels whose brightness is at least the level become white, all others become black.
+// The alpha channel of every pixel is kept intact.
+//
+// level: The brightness (0-255) at which pixels turn white.
+func (i *Image) Threshold(level uint8) {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			v := uint8(0)
+			if pixel.Brightness() >= int(level) {
+				v = 255
+			}
+			i.Pixel[x][y] = RGBA{v, v, v, pixel.A}
+		}
+	}
+}
+
+// AdaptiveThreshold converts the image to pure black and white using a threshold that follows the
+// local brightness, so unevenly lit documents and photos are binarized cleanly. A pixel becomes white
+// when it is brighter than the mean brightness of the block around it minus c.
+// The alpha channel of every pixel is kept intact.
+//
+// blockSize: The width and height of the neighborhood used to compute the local mean, in pixels.
+// c: A constant subtracted from the local mean; larger values turn more pixels white.
+func (i *Image) AdaptiveThreshold(blockSize uint, c int) {
+	if i.Width == 0 || i.Height == 0 {
+		return
+	}
+	w, h := int(i.Width), int(i.Height)
+	lum := i.luminance()
+	// integral[x][y] holds the sum of all brightness values above and to the left of (x, y).
+	integral := make([][]float64, w+1)
+	integral[0] = make([]float64, h+1)
+	for x := 1; x <= w; x++ {
+		integral[x] = make([]float64, h+1)
+		for y := 1; y <= h; y++ {
+			integral[x][y] = lum[x-1][y-1] + integral[x-1][y] + integral[x][y-1] - integral[x-1][y-1]
+		}
+	}
+	half := int(max(blockSize, 1) / 2)
+	for x := 0; x < w; x++ {
+		x1, x2 := max(0, x-half), min(w, x+half+1)
+		for y := 0; y < h; y++ {
+			y1, y2 := max(0, y-half), min(h, y+half+1)
+			sum := integral[x2][y2] - integral[x1][y2] - integral[x2][y1] + integral[x1][y1]
+			mean := sum / float64((x2-x1)*(y2-y1))
+			v := uint8(0)
+			if lum[x][y] > mean-float64(c) {
+				v = 255
+			}
+			i.Pixel[x][y] = RGBA{v, v, v, i.Pixel[x][y].A}
+		}
+	}
+}
+
+// Pixelate turns the image into a mosaic of square blocks, each filled with the average color of its pixels.
+//
+// blockSize: The width and height of every block, in pixels.
+func (i *Image) Pixelate(blockSize uint) {
+	i.PixelateRegion(NewRect(0, 0, i.Width, i.Height), blockSize)
+}
+
+// PixelateRegion pixelates only the pixels inside the rectangle (r), such as for censoring faces
+// or license plates in screenshots. The blocks are aligned to the top left corner of the rectangle.
+//
+// r: The rectangle defining the region to pixelate.
+// blockSize: The width and height of every block, in pixels.
+func (i *Image) PixelateRegion(r Rect, blockSize uint) {
+	if blockSize <= 1 {
+		return
+	}
+	r.W2 = min(r.W2, i.Width)
+	r.H2 = min(r.H2, i.Height)
+	for bx := r.W1; bx < r.W2; bx += blockSize {
+		for by := r.H1; by < r.H2; by += blockSize {
+			ex, ey := min(bx+blockSize, r.W2), min(by+blockSize, r.H2)
+			var sumR, sumG, sumB, sumA float64
+			for x := bx; x < ex; x++ {
+				for y := by; y < ey; y++ {
+					p := i.Pixel[x][y]
+					a := float64(p.A)
+					sumR += float64(p.R) * a
+					sumG += float64(p.G) * a
+					sumB += float64(p.B) * a
+					sumA += a
+				}
+			}
+			var c RGBA
+			if sumA > 0 {
+				c = RGBA{
+					R: clampUint8(sumR / sumA),
+					G: clampUint8(sumG / sumA),
+					B: clampUint8(sumB / sumA),
+					A: clampUint8(sumA / float64((ex-bx)*(ey-by))),
+				}
+			}
+			for x := bx; x < ex; x++ {
+				for y := by; y < ey; y++ {
+					i.Pixel[x][y] = c
+				}
+			}
+		}
+	}
+}
+
+// Vignette darkens (or tints) the edges and corners of the image with a smooth radial falloff,
+// drawing attention to the center. The alpha channel of every pixel is kept intact.
+//
+// strength: How strongly the corners are covered, from 0 (no effect) to 1 (corners fully in the vignette color).
+// c: The color of the vignette, usually black; its alpha scales the strength further.
+func (i *Image) Vignette(strength float64, c RGBA) {
+	strength = math.Max(0, math.Min(1, strength))
+	cx, cy := float64(i.Width)/2, float64(i.Height)/2
+	radius := math.Hypot(cx, cy)
+	if radius == 0 || strength == 0 {
+		return
+	}
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) / radius
+			// Smoothstep from a quarter of the radius to the corners.
+			t := math.Max(0, math.Min(1, (d-0.25)/0.75))
+			weight := t * t * (3 - 2*t) * strength
+			if weight == 0 {
+				continue
+			}
+			pixel := i.Pixel[x][y]
+			tint := c
+			tint.A = clampUint8(float64(c.A) * weight)
+			blended := tint.over(RGBA{pixel.R, pixel.G, pixel.B, 255})
+			blended.A = pixel.A
+			i.Pixel[x][y] = blended
+		}
+	}
+}
+
+// Scanlines darkens every few rows of the image, imitating the visible lines of an old CRT screen.
+// The alpha channel of every pixel is kept intact.
+//
+// spacing: The distance between scanlines in pixels; every spacing-th row is darkened. Values below 2 are treated as 2.
+// darkness: How much the scanlines are darkened, from 0 (unchanged) to 1 (black).
+func (i *Image) Scanlines(spacing uint, darkness float64) {
+	spacing = max(spacing, 2)
+	factor := 1 - math.Max(0, math.Min(1, darkness))
+	for x := range i.Pixel {
+		for y := uint(0); y < i.Height; y += spacing {
+			pixel := i.Pixel[x][y]
+			i.Pixel[x][y] = RGBA{
+				R: clampUint8(float64(pixel.R) * factor),
+				G: clampUint8(float64(pixel.G) * factor),
+				B: clampUint8(float64(pixel.B) * factor),
+				A: pixel.A,
+			}
+		}
+	}
+}
+
+// OilPaint turns the image into a painterly version of itself. Every pixel takes the average color of the
+// most common intensity level in its neighborhood, which flattens details into brush-like patches of color.
+// The alpha channel of every pixel is kept intact.
+//
+// radius: The radius of the neighborhood in pixels; larger values give broader strokes.
+// intensityLevels: The number of intensity levels (usually 10 to 30); fewer levels give flatter patches.
+func (i *Image) OilPaint(radius uint, intensityLevels uint) {
+	if radius == 0 || intensityLevels == 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	w, h, r := int(i.Width), int(i.Height), int(radius)
+	levels := int(min(intensityLevels, 256))
+	level := make([][]int, w)
+	for x := range level {
+		level[x] = make([]int, h)
+		for y := range level[x] {
+			p := i.Pixel[x][y]
+			level[x][y] = (int(p.R) + int(p.G) + int(p.B)) * levels / (3 * 256)
+		}
+	}
+	count := make([]int, levels)
+	sum := make([][3]int, levels)
+	// add counts the pixels of a row of the neighborhood in the histogram, or removes them if sign is -1.
+	add := func(y, x0, x1, sign int) {
+		if y < 0 || y >= h {
+			return
+		}
+		for x := max(x0, 0); x <= min(x1, w-1); x++ {
+			p := i.Pixel[x][y]
+			if p.A == 0 {
+				continue
+			}
+			l := level[x][y]
+			count[l] += sign
+			sum[l][0] += sign * int(p.R)
+			sum[l][1] += sign * int(p.G)
+			sum[l][2] += sign * int(p.B)
+		}
+	}
+	respond := make([][]RGBA, w)
+	for x := range respond {
+		respond[x] = make([]RGBA, h)
+		for l := range count {
+			count[l], sum[l] = 0, [3]int{}
+		}
+		for y := -r; y < r; y++ {
+			add(y, x-r, x+r, 1)
+		}
+		for y := 0; y < h; y++ {
+			add(y+r, x-r, x+r, 1)
+			add(y-r-1, x-r, x+r, -1)
+			best := 0
+			for l := range count {
+				if count[l] > count[best] {
+					best = l
+				}
+			}
+			pixel := i.Pixel[x][y]
+			if n := count[best]; n > 0 {
+				pixel = RGBA{uint8(sum[best][0] / n), uint8(sum[best][1] / n), uint8(sum[best][2] / n), pixel.A}
+			}
+			respond[x][y] = pixel
+		}
+	}
+	i.Pixel = respond
+}
+
+// GradientMap recolors the image by mapping the luminance of every pixel onto a gradient,
+// from the start of the gradient for black to its end for white. It can be used for heatmaps,
+// duotones with more than two colors, and other stylized recolorings.
+// The alpha of every pixel is multiplied by the alpha of its gradient color.
+//
+// stops: The color stops of the gradient, sorted by position. If it is empty, the image is left unchanged.
+func (i *Image) GradientMap(stops []GradientStop) {
+	if len(stops) == 0 {
+		return
+	}
+	var lut [256]RGBA
+	for k := range lut {
+		lut[k] = gradientAt(stops, float64(k)/255)
+	}
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			c := lut[clampUint8(0.299*float64(pixel.R)+0.587*float64(pixel.G)+0.114*float64(pixel.B))]
+			c.A = uint8(uint(pixel.A) * uint(c.A) / 255)
+			i.Pixel[x][y] = c
+		}
+	}
+}
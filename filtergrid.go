@@ -0,0 +1,50 @@
+package picrocess
+
+import "sort"
+
+// Filter changes an image in place, such as func(i *Image) { i.Sepia(0.8) }.
+type Filter func(i *Image)
+
+// FilterGrid applies every named filter to its own copy of the image and lays out the results in a grid,
+// each with its name below it, so filter parameters can be compared side by side.
+// The filters are placed in alphabetical order of their names; the input image is not modified.
+//
+// img: The image to apply the filters to.
+// filters: The filters to compare, by name.
+// cols: The number of columns of the grid. If it is 0, all results are placed in one row.
+// font: The font of the labels. If it is nil, the results are not labeled.
+//
+// Returns: A new image containing the grid of filtered results.
+func FilterGrid(img *Image, filters map[string]Filter, cols uint, font *Font) *Image {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	count := uint(len(names))
+	if cols == 0 || cols > count {
+		cols = max(count, 1)
+	}
+	rows := max((count+cols-1)/cols, 1)
+	const padding = 8
+	size := max(12, float64(img.Width)/16)
+	label := uint(0)
+	if font != nil {
+		label = uint(size * 1.5)
+	}
+	cellW, cellH := img.Width+padding, img.Height+label+padding
+	grid := NewImage(cols*cellW+padding, rows*cellH+padding, RGBA{255, 255, 255, 255})
+	for k, name := range names {
+		x := padding + uint(k)%cols*cellW
+		y := padding + uint(k)/cols*cellH
+		result := img.clone()
+		if filter := filters[name]; filter != nil {
+			filter(result)
+		}
+		grid.Overlay(result, NewOffset(x, y))
+		if font != nil {
+			grid.Text(font, RGBA{0, 0, 0, 255}, NewOffset(x+img.Width/2, y+img.Height+uint(size/4)), size, name, WithAnchor(AnchorTop))
+		}
+	}
+	return grid
+}
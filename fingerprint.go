@@ -0,0 +1,93 @@
+package picrocess
+
+import "hash/fnv"
+
+// RegionHash computes a 64-bit hash of the pixels inside a rectangle of the image.
+// The same pixels always give the same hash, so comparing the hashes of two captures
+// tells whether that region changed. The rectangle is clipped to the image.
+//
+// r: The rectangle to hash.
+//
+// Returns: The FNV-1a hash of the region's size and pixels.
+func (i *Image) RegionHash(r Rect) uint64 {
+	x1, y1 := min(r.W1, i.Width), min(r.H1, i.Height)
+	x2, y2 := min(r.W2, i.Width), min(r.H2, i.Height)
+	x2, y2 = max(x1, x2), max(y1, y2)
+	h := fnv.New64a()
+	buf := make([]byte, 0, 8+4*(y2-y1))
+	buf = append(buf, byte(x2-x1), byte((x2-x1)>>8), byte((x2-x1)>>16), byte((x2-x1)>>24))
+	buf = append(buf, byte(y2-y1), byte((y2-y1)>>8), byte((y2-y1)>>16), byte((y2-y1)>>24))
+	h.Write(buf)
+	for x := x1; x < x2; x++ {
+		buf = buf[:0]
+		for y := y1; y < y2; y++ {
+			p := i.Pixel[x][y]
+			buf = append(buf, p.R, p.G, p.B, p.A)
+		}
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// Fingerprint holds the hashes of a grid of cells covering an image, created by GridFingerprint.
+type Fingerprint struct {
+	Width, Height uint
+	Cols, Rows    uint
+	Hashes        [][]uint64 // Col / Row
+}
+
+// GridFingerprint splits the image into a grid of cells and hashes each of them with RegionHash.
+// Fingerprints of two captures of the same size can be compared with Changed to find the parts that differ.
+//
+// cols: The number of cells across the image.
+// rows: The number of cells down the image.
+//
+// Returns: A pointer to the Fingerprint of the image.
+func (i *Image) GridFingerprint(cols, rows uint) *Fingerprint {
+	cols, rows = max(cols, 1), max(rows, 1)
+	f := &Fingerprint{
+		Width:  i.Width,
+		Height: i.Height,
+		Cols:   cols,
+		Rows:   rows,
+		Hashes: make([][]uint64, cols),
+	}
+	for c := range f.Hashes {
+		f.Hashes[c] = make([]uint64, rows)
+		for r := range f.Hashes[c] {
+			f.Hashes[c][r] = i.RegionHash(f.Cell(uint(c), uint(r)))
+		}
+	}
+	return f
+}
+
+// Cell returns the rectangle of the image covered by a cell of the grid.
+//
+// col: The column of the cell.
+// row: The row of the cell.
+//
+// Returns: The rectangle of the cell.
+func (f *Fingerprint) Cell(col, row uint) Rect {
+	return NewRect(col*f.Width/f.Cols, row*f.Height/f.Rows, (col+1)*f.Width/f.Cols, (row+1)*f.Height/f.Rows)
+}
+
+// Changed compares two fingerprints and returns the cells whose pixels differ.
+// If the fingerprints have different image sizes or grids, the whole image is reported as changed.
+//
+// other: The fingerprint to compare with, such as the one of the previous capture.
+//
+// Returns: The rectangles of the changed cells, in column order.
+func (f *Fingerprint) Changed(other *Fingerprint) []Rect {
+	if other == nil || f.Width != other.Width || f.Height != other.Height || f.Cols != other.Cols || f.Rows != other.Rows {
+		return []Rect{NewRect(0, 0, f.Width, f.Height)}
+	}
+	respond := make([]Rect, 0)
+	for c := range f.Hashes {
+		for r := range f.Hashes[c] {
+			if f.Hashes[c][r] != other.Hashes[c][r] {
+				respond = append(respond, f.Cell(uint(c), uint(r)))
+			}
+		}
+	}
+	return respond
+}
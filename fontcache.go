@@ -0,0 +1,83 @@
+package picrocess
+
+import (
+	"image"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// maxCachedSizes is the number of font sizes a font keeps faces for. When more sizes are used,
+// such as while searching for a size that fits, the cache starts over.
+const maxCachedSizes = 32
+
+// faceCache keeps the faces of a font by size, so measuring and drawing text many times, such as for the labels
+// of a chart, reuses the faces and the glyphs they have already rasterized.
+type faceCache struct {
+	mu    sync.Mutex
+	faces map[float64]*lockedFace
+}
+
+func newFaceCache() *faceCache {
+	return &faceCache{faces: make(map[float64]*lockedFace)}
+}
+
+// face returns the cached face of the size, creating it with create the first time.
+func (c *faceCache) face(size float64, create func() font.Face) *lockedFace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if face, ok := c.faces[size]; ok {
+		return face
+	}
+	if len(c.faces) >= maxCachedSizes {
+		c.faces = make(map[float64]*lockedFace)
+	}
+	face := &lockedFace{face: create()}
+	c.faces[size] = face
+	return face
+}
+
+// lockedFace is a face that can be shared between goroutines, since the faces of freetype and the OpenType parser
+// cache glyphs and cannot be used by several goroutines at once.
+type lockedFace struct {
+	mu   sync.Mutex
+	face font.Face
+}
+
+// Close does nothing, since the face stays in the cache of its font.
+func (l *lockedFace) Close() error {
+	return nil
+}
+
+// Glyph returns the glyph of the rune. The mask is only valid until the face is used again, so drawing
+// locks the face for as long as it uses the mask instead of calling Glyph.
+func (l *lockedFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.face.Glyph(dot, r)
+}
+
+func (l *lockedFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.face.GlyphBounds(r)
+}
+
+func (l *lockedFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.face.GlyphAdvance(r)
+}
+
+func (l *lockedFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.face.Kern(r0, r1)
+}
+
+func (l *lockedFace) Metrics() font.Metrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.face.Metrics()
+}
@@ -0,0 +1,128 @@
+package picrocess
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/golang/freetype"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// parseFont parses a TrueType font with freetype, falling back to the OpenType parser for the fonts freetype
+// rejects, such as OpenType fonts with CFF outlines (.otf).
+func parseFont(data []byte) (*Font, error) {
+	ttf, err := truetype.Parse(data)
+	if err == nil {
+		return &Font{face: ttf, cache: newFaceCache()}, nil
+	}
+	otf, otfErr := opentype.Parse(data)
+	if otfErr != nil {
+		return nil, err
+	}
+	return &Font{otf: otf, cache: newFaceCache()}, nil
+}
+
+// newFace returns a face of the font at the given size, at 72 DPI so one point is one pixel.
+// Faces are cached, so they keep the glyphs they have rasterized for the next text of the same size.
+func (f *Font) newFace(size float64) font.Face {
+	if f.cache != nil {
+		return f.cache.face(size, func() font.Face {
+			return f.createFace(size)
+		})
+	}
+	return f.createFace(size)
+}
+
+// createFace creates a new face of the font at the given size.
+func (f *Font) createFace(size float64) font.Face {
+	if f.otf != nil {
+		if face, err := opentype.NewFace(f.otf, &opentype.FaceOptions{Size: size, DPI: 72}); err == nil {
+			return face
+		}
+	}
+	return truetype.NewFace(f.face, &truetype.Options{Size: size})
+}
+
+// hasGlyph reports whether the font has a glyph for the rune, rather than drawing it as a missing glyph box.
+func (f *Font) hasGlyph(r rune) bool {
+	if f.otf != nil {
+		var buf sfnt.Buffer
+		index, err := f.otf.GlyphIndex(&buf, r)
+		return err == nil && index != 0
+	}
+	return f.face.Index(r) != 0
+}
+
+// WithFallback returns a copy of the font that draws the characters it has no glyphs for with the first of the
+// fallback fonts that has them, such as a Latin display font combined with a Korean, Japanese, or Chinese font
+// for mixed-script text. Text is drawn on the baseline of the font, and measured with the fonts that draw it.
+//
+// fonts: The fallback fonts, in order of preference.
+//
+// Returns: A new Font with the fallback chain.
+func (f *Font) WithFallback(fonts ...*Font) *Font {
+	font := *f
+	font.fallbacks = append(append([]*Font(nil), f.fallbacks...), fonts...)
+	return &font
+}
+
+// fontFor returns the font of the fallback chain that draws the rune: the font itself if it has a glyph for it,
+// or else the first fallback font that does. Runes that no font has are drawn with the font itself.
+func (f *Font) fontFor(r rune) *Font {
+	if len(f.fallbacks) == 0 || f.hasGlyph(r) {
+		return f
+	}
+	for _, fallback := range f.fallbacks {
+		if fallback != nil && fallback.hasGlyph(r) {
+			return fallback
+		}
+	}
+	return f
+}
+
+// drawLine draws a line of text onto dst, with its baseline starting at the dot. Text is drawn in the colors of src,
+// and emoji of the emoji set of the font as images.
+func (f *Font) drawLine(dst draw.Image, src image.Image, size float64, dot fixed.Point26_6, line string) error {
+	face := f.newFace(size)
+	for _, run := range f.layoutLine(face, size, line) {
+		if run.emoji != nil {
+			r := emojiRect(face.Metrics(), size, dot)
+			drawEmoji(dst, run.emoji, r.Min.X, r.Min.Y, r.Dx())
+		} else if err := run.font.drawString(dst, src, size, dot, run.text); err != nil {
+			return err
+		}
+		dot.X += run.advance
+	}
+	return nil
+}
+
+// drawString draws text onto dst in the colors of src, with its baseline starting at the dot.
+func (f *Font) drawString(dst draw.Image, src image.Image, size float64, dot fixed.Point26_6, text string) error {
+	if f.otf != nil {
+		face := f.newFace(size)
+		if locked, ok := face.(*lockedFace); ok {
+			// Keep the face to this drawing until it has used the glyph masks.
+			locked.mu.Lock()
+			defer locked.mu.Unlock()
+			face = locked.face
+		}
+		drawer := font.Drawer{Dst: dst, Src: src, Face: face, Dot: dot}
+		drawer.DrawString(text)
+		return nil
+	}
+	// A new context for every string keeps the glyphs at their exact subpixel positions, since the glyph cache
+	// of a context reuses the glyph drawn at the first position it saw for nearby ones.
+	ctx := freetype.NewContext()
+	ctx.SetDPI(72)
+	ctx.SetFont(f.face)
+	ctx.SetFontSize(size)
+	ctx.SetClip(dst.Bounds())
+	ctx.SetDst(dst)
+	ctx.SetSrc(src)
+	_, err := ctx.DrawString(text, dot)
+	return err
+}
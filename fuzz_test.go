@@ -0,0 +1,158 @@
+package picrocess
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fuzzLimits lowers the safety limits for the duration of a fuzz test, so inputs that claim huge sizes
+// are rejected quickly instead of allocating a lot of memory.
+func fuzzLimits(f *testing.F) {
+	pixels, frames, gifBytes, gifPixels := MaxPixels, MaxGIFFrames, MaxGIFBytes, MaxGIFPixels
+	MaxPixels, MaxGIFFrames, MaxGIFBytes, MaxGIFPixels = 1<<20, 64, 1<<20, 1<<22
+	f.Cleanup(func() {
+		MaxPixels, MaxGIFFrames, MaxGIFBytes, MaxGIFPixels = pixels, frames, gifBytes, gifPixels
+	})
+}
+
+// checkFuzzError fails the test unless err is nil, a LimitError, a DecodeError, or an error that own reports
+// as an error of the format itself.
+func checkFuzzError(t *testing.T, err error, own func(err error) bool) {
+	if err == nil {
+		return
+	}
+	var limitErr *LimitError
+	var decodeErr *DecodeError
+	if errors.As(err, &limitErr) || errors.As(err, &decodeErr) || errors.Is(err, ErrMalformed) || own(err) {
+		return
+	}
+	t.Fatalf("unexpected error %T: %v", err, err)
+}
+
+// isStreamError reports whether err is an error of a truncated input.
+func isStreamError(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isGIFError reports whether err is an error of the GIF decoder.
+func isGIFError(err error) bool {
+	return isStreamError(err) || strings.HasPrefix(err.Error(), "gif:")
+}
+
+// isImageError reports whether err is an error of one of the decoders of the standard image package.
+func isImageError(err error) bool {
+	var pngFormat png.FormatError
+	var pngUnsupported png.UnsupportedError
+	var jpegFormat jpeg.FormatError
+	var jpegUnsupported jpeg.UnsupportedError
+	var flateCorrupt flate.CorruptInputError
+	return errors.Is(err, image.ErrFormat) || errors.Is(err, zlib.ErrHeader) || errors.Is(err, zlib.ErrChecksum) || errors.Is(err, zlib.ErrDictionary) ||
+		errors.As(err, &pngFormat) || errors.As(err, &pngUnsupported) || errors.As(err, &jpegFormat) ||
+		errors.As(err, &jpegUnsupported) || errors.As(err, &flateCorrupt) || isGIFError(err)
+}
+
+// fuzzImage returns a small image with a gradient and a transparent corner.
+func fuzzImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 60), uint8(y * 60), 128, 255})
+		}
+	}
+	img.SetNRGBA(0, 0, color.NRGBA{})
+	return img
+}
+
+// fuzzGIFBomb returns a small GIF with a large logical screen and many one-pixel frames, which would take far more
+// memory to coalesce than its size suggests.
+func fuzzGIFBomb(f *testing.F) []byte {
+	palette := color.Palette{color.Black, color.White}
+	bomb := &gif.GIF{Config: image.Config{ColorModel: palette, Width: 1024, Height: 1024}}
+	for k := 0; k < 60; k++ {
+		bomb.Image = append(bomb.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette))
+		bomb.Delay = append(bomb.Delay, 0)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, bomb); err != nil {
+		f.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// fuzzGIF returns a small valid animated GIF.
+func fuzzGIF(f *testing.F) []byte {
+	g := NewGIF()
+	g.Append(NewImage(4, 4, RGBA{255, 0, 0, 255}), 5)
+	g.Append(NewImage(4, 4, RGBA{0, 0, 255, 255}), 5)
+	data, err := g.ToGIFByte()
+	if err != nil {
+		f.Fatal(err)
+	}
+	return data
+}
+
+func FuzzDecodeImage(f *testing.F) {
+	fuzzLimits(f)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fuzzImage()); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	buf.Reset()
+	if err := jpeg.Encode(&buf, fuzzImage(), nil); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Add(fuzzGIF(f))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, err := DecodeImage(bytes.NewReader(data))
+		checkFuzzError(t, err, isImageError)
+	})
+}
+
+func FuzzLoadGIFBytes(f *testing.F) {
+	fuzzLimits(f)
+	f.Add(fuzzGIF(f))
+	bomb := fuzzGIFBomb(f)
+	if _, err := LoadGIFBytes(bomb); !errors.Is(err, ErrImageTooLarge) {
+		f.Fatalf("loading a GIF bomb: got %v, want %v", err, ErrImageTooLarge)
+	}
+	f.Add(bomb)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := LoadGIFBytes(data)
+		checkFuzzError(t, err, isGIFError)
+		_, err = DecodeGIF(bytes.NewReader(data))
+		checkFuzzError(t, err, isGIFError)
+	})
+}
+
+func FuzzReadLUT(f *testing.F) {
+	fuzzLimits(f)
+	f.Add([]byte("TITLE \"identity\"\nLUT_3D_SIZE 2\n0 0 0\n1 0 0\n0 1 0\n1 1 0\n0 0 1\n1 0 1\n0 1 1\n1 1 1\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := ReadLUT(bytes.NewReader(data))
+		checkFuzzError(t, err, func(err error) bool {
+			return errors.Is(err, ErrInvalidLUT) || errors.Is(err, bufio.ErrTooLong)
+		})
+	})
+}
+
+func FuzzParseANSI(f *testing.F) {
+	fuzzLimits(f)
+	f.Add([]byte("\x1b[1;31mHello\x1b[0m \x1b[38;5;82mworld\x1b[0m\r\n\x1b[2C\x1b[48;2;0;0;255m  \x1b[0m\x1a"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := ParseANSI(bytes.NewReader(data))
+		checkFuzzError(t, err, func(error) bool { return false })
+	})
+}
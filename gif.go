@@ -0,0 +1,755 @@
+package picrocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MinFrameDelay is the shortest frame delay, in 100ths of a second, that browsers and Discord play as-is.
+// Shorter delays (0 or 1) are usually replaced with a much slower 10.
+const MinFrameDelay = 2
+
+// effectiveDelay returns how long a frame delay is actually shown by browsers, in 100ths of a second.
+func effectiveDelay(delay int) int {
+	if delay < MinFrameDelay {
+		return 10
+	}
+	return delay
+}
+
+// NormalizeTiming resamples the GIF to a constant frame rate by dropping or duplicating frames,
+// so it plays at the intended speed everywhere. Delays below MinFrameDelay are treated the way
+// browsers play them, and every resulting delay is at least MinFrameDelay.
+//
+// targetFPS: The frame rate to resample to. If it is 0 or less, the most common frame delay
+// of the GIF (the peak of its delay histogram) is used instead.
+func (gf *GIF) NormalizeTiming(targetFPS int) {
+	if len(gf.Image) == 0 {
+		return
+	}
+	gf.flatten()
+	total := 0
+	histogram := make(map[int]int)
+	for k := range gf.Image {
+		d := effectiveDelay(gf.delay(k))
+		total += d
+		histogram[d]++
+	}
+	var target int
+	if targetFPS > 0 {
+		target = int(math.Round(100 / float64(targetFPS)))
+	} else {
+		for d, count := range histogram {
+			if count > histogram[target] || count == histogram[target] && d < target {
+				target = d
+			}
+		}
+	}
+	target = max(target, MinFrameDelay)
+	frames := max(1, int(math.Round(float64(total)/float64(target))))
+	images := make([]*image.RGBA, 0, frames)
+	delays := make([]int, 0, frames)
+	source, end := 0, effectiveDelay(gf.delay(0))
+	for k := 0; k < frames; k++ {
+		t := k * target
+		for t >= end && source < len(gf.Image)-1 {
+			source++
+			end += effectiveDelay(gf.delay(source))
+		}
+		images = append(images, gf.Image[source])
+		delays = append(delays, target)
+	}
+	// Give the remainder to the last frame so the total duration stays the same.
+	if last := total - (frames-1)*target; last >= MinFrameDelay {
+		delays[frames-1] = last
+	}
+	gf.Image = images
+	gf.Delay = delays
+}
+
+// Speed changes how fast the GIF plays by rescaling its frame delays, such as 2 for a version at double speed.
+// Delays below MinFrameDelay are treated the way browsers play them. Since browsers do not play frames faster than
+// MinFrameDelay, frames that would be shown for less are dropped instead, keeping the total duration.
+//
+// factor: The speed factor; above 1 plays faster and below 1 slower. Factors of 0 or less leave the GIF unchanged.
+func (gf *GIF) Speed(factor float64) {
+	if factor <= 0 || len(gf.Image) == 0 {
+		return
+	}
+	gf.flatten()
+	// Keep the frames that start at least MinFrameDelay after the frame kept before them.
+	images := make([]*image.RGBA, 0, len(gf.Image))
+	starts := make([]float64, 0, len(gf.Image))
+	t := 0.0
+	for k, img := range gf.Image {
+		if len(starts) == 0 || t-starts[len(starts)-1] >= MinFrameDelay {
+			images = append(images, img)
+			starts = append(starts, t)
+		}
+		t += float64(effectiveDelay(gf.delay(k))) / factor
+	}
+	// Round the start times rather than the delays, so rounding errors do not add up.
+	delays := make([]int, len(images))
+	for k := range images {
+		end := t
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		delays[k] = max(MinFrameDelay, int(math.Round(end))-int(math.Round(starts[k])))
+	}
+	gf.Image = images
+	gf.Delay = delays
+}
+
+// WithPalette encodes every frame with the given palette instead of computing a palette per frame.
+// Skipping quantization makes encoding much faster for templated animations whose colors are known,
+// and the same option value can be reused across encodes to share its color lookup cache.
+// Colors that are not in the palette are mapped to the nearest palette color.
+//
+// p: The palette to use, with at most 256 colors.
+func WithPalette(p color.Palette) Option {
+	fixed := newFixedPalette(p)
+	return func(o *Options) {
+		o.palette = fixed
+	}
+}
+
+// WithOptimize makes GIFs much smaller by only encoding what changes between frames: every frame is cropped to the
+// rectangle that differs from the frame before it, pixels that did not change within it are made transparent so they
+// compress well, and frames that change nothing are merged into the frame before them. Animations with transparent
+// pixels are encoded in full, since a frame cannot make the pixels drawn before it transparent again, and so are frames
+// after one that is cleared with WithDisposal.
+func WithOptimize() Option {
+	return func(o *Options) {
+		o.optimize = true
+	}
+}
+
+// Disposal is what happens to a frame of a GIF before the next frame is drawn over it.
+type Disposal byte
+
+const (
+	// DisposalAuto lets the encoder choose: frames are cleared, or kept when only their changes are encoded with WithOptimize.
+	DisposalAuto Disposal = iota
+	// DisposalNone keeps the frame, so the transparent pixels of the next frame show it, for overlay-style animations.
+	DisposalNone
+	// DisposalBackground clears the frame to transparent.
+	DisposalBackground
+	// DisposalPrevious restores what was shown before the frame, such as for a sprite moving over a fixed scene.
+	DisposalPrevious
+)
+
+// WithDisposal sets what happens to a frame appended to a GIF before the next frame is drawn.
+//
+// d: The disposal method (DisposalAuto, DisposalNone, DisposalBackground, or DisposalPrevious).
+func WithDisposal(d Disposal) Option {
+	return func(o *Options) {
+		o.disposal = d
+	}
+}
+
+// WithTransparentColor makes the pixels of a color transparent in a frame appended to a GIF,
+// such as the key color of a sprite drawn over the frames before it.
+//
+// c: The color to make transparent.
+func WithTransparentColor(c RGBA) Option {
+	return func(o *Options) {
+		o.transparent = &c
+	}
+}
+
+// clearColor makes the pixels of the frame that have exactly the color c transparent.
+func clearColor(frame *image.RGBA, c RGBA) {
+	for k := 0; k+3 < len(frame.Pix); k += 4 {
+		if frame.Pix[k] == c.R && frame.Pix[k+1] == c.G && frame.Pix[k+2] == c.B && frame.Pix[k+3] == c.A {
+			frame.Pix[k], frame.Pix[k+1], frame.Pix[k+2], frame.Pix[k+3] = 0, 0, 0, 0
+		}
+	}
+}
+
+// disposal returns the disposal method of the frame at index k.
+func (gf *GIF) disposal(k int) Disposal {
+	if k < len(gf.Disposal) {
+		return gf.Disposal[k]
+	}
+	return DisposalAuto
+}
+
+// keepsFrame reports whether the disposal method keeps a frame when encoding with WithOptimize,
+// so the next frame can be encoded as its changes.
+func keepsFrame(d Disposal) bool {
+	return d == DisposalAuto || d == DisposalNone
+}
+
+// framesOpaque reports whether every pixel of every frame is fully opaque.
+func framesOpaque(frames []*image.RGBA) bool {
+	for _, frame := range frames {
+		for k := 3; k < len(frame.Pix); k += 4 {
+			if frame.Pix[k] != 255 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// diffFrame returns the part of a frame that differs from the frame before it, cropped to the rectangle of the changes.
+// If transparent is true, the pixels within the rectangle that did not change are made transparent.
+//
+// Returns: The changed part of the frame, or false if nothing changed.
+func diffFrame(previous, frame *image.RGBA, transparent bool) (*image.RGBA, bool) {
+	bounds := frame.Bounds()
+	if previous.Bounds() != bounds {
+		return frame, true
+	}
+	changed := image.Rectangle{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if previous.RGBAAt(x, y) != frame.RGBAAt(x, y) {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if changed.Empty() {
+		return nil, false
+	}
+	respond := image.NewRGBA(changed)
+	for y := changed.Min.Y; y < changed.Max.Y; y++ {
+		for x := changed.Min.X; x < changed.Max.X; x++ {
+			c := frame.RGBAAt(x, y)
+			if transparent && previous.RGBAAt(x, y) == c {
+				c = color.RGBA{}
+			}
+			respond.SetRGBA(x, y, c)
+		}
+	}
+	return respond, true
+}
+
+type fixedPalette struct {
+	mu      sync.Mutex
+	palette color.Palette
+	lookup  map[color.RGBA]uint8
+}
+
+func newFixedPalette(p color.Palette) *fixedPalette {
+	return &fixedPalette{
+		palette: p,
+		lookup:  make(map[color.RGBA]uint8),
+	}
+}
+
+// transparent reports whether the palette has a fully transparent color.
+func (p *fixedPalette) transparent() bool {
+	for _, c := range p.palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// paletted maps a frame onto the fixed palette, remembering the palette index of every color it has seen.
+func (p *fixedPalette) paletted(img *image.RGBA) *image.Paletted {
+	bounds := img.Bounds()
+	respond := image.NewPaletted(bounds, p.palette)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			index, ok := p.lookup[c]
+			if !ok {
+				index = uint8(p.palette.Index(c))
+				p.lookup[c] = index
+			}
+			respond.SetColorIndex(x, y, index)
+		}
+	}
+	return respond
+}
+
+// LoadGIF loads an animated GIF from a file, with every frame coalesced into a full image and the frame delays preserved,
+// so existing animations can be edited and encoded again. Files larger than MaxGIFBytes are rejected with a LimitError
+// before they are read completely.
+//
+// filename: The path to the GIF file to load.
+//
+// Returns: A pointer to a GIF struct containing the frames, or an error if any issue occurs.
+func LoadGIF(filename string) (*GIF, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return DecodeGIF(file)
+}
+
+// DecodeGIF reads an animated GIF from a reader, as LoadGIF does.
+//
+// r: The reader to decode the GIF from.
+//
+// Returns: A pointer to a GIF struct containing the frames, or an error if any issue occurs.
+func DecodeGIF(r io.Reader) (*GIF, error) {
+	data, err := readLimited(r, MaxGIFBytes)
+	if err != nil {
+		return nil, err
+	}
+	return LoadGIFBytes(data)
+}
+
+// LoadGIFBytes decodes an animated GIF from bytes, as LoadGIF does. GIFs larger than MaxPixels, with more frames
+// than MaxGIFFrames, or whose coalesced frames would have more than MaxGIFPixels pixels in total are rejected
+// with a LimitError before their frames are decoded.
+//
+// data: The bytes of the GIF file.
+//
+// Returns: A pointer to a GIF struct containing the frames, or an error if any issue occurs.
+func LoadGIFBytes(data []byte) (*GIF, error) {
+	frames, err := countGIFFrames(data)
+	if err != nil {
+		return nil, err
+	}
+	if MaxGIFFrames > 0 && frames > MaxGIFFrames {
+		return nil, &LimitError{Err: ErrTooManyFrames, Value: frames, Limit: MaxGIFFrames}
+	}
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPixels(config.Width, config.Height); err != nil {
+		return nil, err
+	}
+	if err := checkGIFPixels(config.Width, config.Height, frames); err != nil {
+		return nil, err
+	}
+	decoded, err := safeDecode("gif", func() (*gif.GIF, error) {
+		return gif.DecodeAll(bytes.NewReader(data))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return coalesceGIF(decoded), nil
+}
+
+// ErrNoFrames is returned by GIFFromFiles when no files match its pattern.
+var ErrNoFrames = errors.New("picrocess: no frames found")
+
+// GIFFromFiles assembles a GIF from a sequence of image files, such as the frames a renderer writes out.
+// The files are sorted by name, with runs of digits compared by their value, so "frame2.png" comes before
+// "frame10.png" even without zero padding. Sequences with more than MaxGIFFrames files are rejected with a LimitError.
+//
+// pattern: A glob pattern such as "render/frame_*.png", or a directory to use every file in it.
+// delay: The delay of every frame in 100ths of a second.
+//
+// Returns: A pointer to a GIF struct containing the frames, or an error if no files match or a file cannot be loaded.
+func GIFFromFiles(pattern string, delay int) (*GIF, error) {
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := matches[:0]
+	for _, name := range matches {
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			files = append(files, name)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoFrames, pattern)
+	}
+	if MaxGIFFrames > 0 && len(files) > MaxGIFFrames {
+		return nil, &LimitError{Err: ErrTooManyFrames, Value: len(files), Limit: MaxGIFFrames}
+	}
+	sort.Slice(files, func(a, b int) bool {
+		return naturalLess(files[a], files[b])
+	})
+	respond := NewGIF()
+	for _, name := range files {
+		img, err := LoadImage(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := respond.Append(img, delay); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return respond, nil
+}
+
+// naturalLess reports whether the string a sorts before b, comparing runs of digits by their value.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da > 0 && db > 0 {
+			na, nb := strings.TrimLeft(a[:da], "0"), strings.TrimLeft(b[:db], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// digitPrefix returns the number of ASCII digits at the start of s.
+func digitPrefix(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// countGIFFrames counts the image blocks of a GIF by walking its block structure, without decoding any pixels.
+func countGIFFrames(data []byte) (int, error) {
+	// The header and the logical screen descriptor, followed by the global color table, if any.
+	if len(data) < 13 {
+		return 0, ErrMalformed
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+	// skipSubBlocks skips a chain of data sub-blocks, which ends with an empty one.
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos += size + 1
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: its label, then sub-blocks.
+			pos += 2
+			if !skipSubBlocks() {
+				return frames, ErrMalformed
+			}
+		case 0x2C: // Image descriptor, its local color table, if any, the LZW code size, then sub-blocks.
+			if pos+10 > len(data) {
+				return frames, ErrMalformed
+			}
+			packed := data[pos+9]
+			pos += 10
+			if packed&0x80 != 0 {
+				pos += 3 << (packed&0x07 + 1)
+			}
+			pos++
+			if !skipSubBlocks() {
+				return frames, ErrMalformed
+			}
+			frames++
+		case 0x3B: // Trailer.
+			return frames, nil
+		default:
+			return frames, ErrMalformed
+		}
+	}
+	// Many GIFs in the wild are cut off before their trailer; the decoder decides whether that is acceptable.
+	return frames, nil
+}
+
+// coalesceGIF draws every frame of a decoded GIF over the frames before it, following their disposal methods,
+// so every frame becomes a full image of the logical screen.
+func coalesceGIF(decoded *gif.GIF) *GIF {
+	bounds := image.Rect(0, 0, decoded.Config.Width, decoded.Config.Height)
+	if bounds.Empty() {
+		// Some encoders leave the logical screen size at 0; use the area the frames cover instead.
+		for _, frame := range decoded.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+	respond := NewGIF()
+	respond.LoopCount = decoded.LoopCount
+	canvas := image.NewRGBA(bounds)
+	for k, frame := range decoded.Image {
+		var disposal byte
+		if k < len(decoded.Disposal) {
+			disposal = decoded.Disposal[k]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		snapshot := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		copy(snapshot.Pix, canvas.Pix)
+		delay := 0
+		if k < len(decoded.Delay) {
+			delay = decoded.Delay[k]
+		}
+		respond.Delay = append(respond.Delay, delay)
+		respond.Image = append(respond.Image, snapshot)
+		switch disposal {
+		case gif.DisposalBackground:
+			// Browsers clear the area of the frame to transparent rather than to the background color.
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return respond
+}
+
+// mapFrames replaces every frame of the GIF with the image fn returns for it, rendered with the Renderer set with
+// SetRenderer. It stops at the first frame the Renderer fails on, leaving it and the frames after it unchanged.
+func (gf *GIF) mapFrames(fn func(frame *Image, index int) *Image) error {
+	for k, img := range gf.Image {
+		frame := fn(Render(img), k)
+		rendered, err := frame.renderRGBA(currentRenderer())
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", k, err)
+		}
+		gf.Image[k] = rendered
+	}
+	return nil
+}
+
+// Map applies a function to every frame of the GIF, so any drawing or filter can be applied to a whole animation
+// without rebuilding it frame by frame. The frame delays are kept.
+//
+// fn: The function to apply. It receives every frame as an Image to change in place, and the index of the frame.
+//
+// Returns: An error if the Renderer fails to render a changed frame; the frames from that one on are left unchanged.
+func (gf *GIF) Map(fn func(frame *Image, index int)) error {
+	return gf.mapFrames(func(frame *Image, index int) *Image {
+		fn(frame, index)
+		return frame
+	})
+}
+
+// ResizeAll resizes every frame of the GIF to the specified width (w) and height (h), as Resize does.
+//
+// w: The new width of the frames.
+// h: The new height of the frames.
+// opts: Optional settings; WithFilter(FilterBilinear) interpolates between pixels instead.
+//
+// Returns: An error if the Renderer fails to render a resized frame.
+func (gf *GIF) ResizeAll(w, h uint, opts ...Option) error {
+	return gf.Map(func(frame *Image, _ int) {
+		frame.Resize(w, h, opts...)
+	})
+}
+
+// Resize resizes the whole animation to the specified width (w) and height (h), such as to fit the file size limits
+// of chat apps. Frames smaller than the animation, such as ones appended with WithDisposal(DisposalNone) to draw
+// over the frames before them, are scaled by the same factor so they stay in proportion.
+//
+// w: The new width of the animation.
+// h: The new height of the animation.
+// filter: The resampling filter, such as FilterBilinear for photos or FilterNearest for pixel art.
+//
+// Returns: An error if the Renderer fails to render a resized frame.
+func (gf *GIF) Resize(w, h uint, filter ResizeFilter) error {
+	bounds := gf.bounds()
+	if bounds.Empty() {
+		return nil
+	}
+	sx, sy := float64(w)/float64(bounds.Dx()), float64(h)/float64(bounds.Dy())
+	return gf.Map(func(frame *Image, _ int) {
+		fw := uint(max(1, math.Round(float64(frame.Width)*sx)))
+		fh := uint(max(1, math.Round(float64(frame.Height)*sy)))
+		frame.Resize(fw, fh, WithFilter(filter))
+	})
+}
+
+// CropAll crops every frame of the GIF to the rectangle (r), as Crop does.
+//
+// r: The rectangle defining the region to keep.
+//
+// Returns: An error if the Renderer fails to render a cropped frame.
+func (gf *GIF) CropAll(r Rect) error {
+	return gf.mapFrames(func(frame *Image, _ int) *Image {
+		return frame.Crop(r)
+	})
+}
+
+// OverlayAll overlays an image onto every frame of the GIF at the specified offset, such as a watermark or a logo.
+//
+// img: The image to overlay on top of the frames.
+// o: The offset to position the image on top of the frames.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the overlaid image, and WithBlendMode sets how
+// it is blended with the frames.
+//
+// Returns: An error if the Renderer fails to render a frame.
+func (gf *GIF) OverlayAll(img *Image, o Offset, opts ...Option) error {
+	return gf.Map(func(frame *Image, _ int) {
+		frame.Overlay(img, o, opts...)
+	})
+}
+
+// bounds returns the area covered by the frames of the GIF.
+func (gf *GIF) bounds() image.Rectangle {
+	var bounds image.Rectangle
+	for _, img := range gf.Image {
+		bounds = bounds.Union(img.Bounds())
+	}
+	return bounds
+}
+
+// delay returns the delay of the frame at index k.
+func (gf *GIF) delay(k int) int {
+	if k < len(gf.Delay) {
+		return gf.Delay[k]
+	}
+	return 0
+}
+
+// coalesced returns every frame of the GIF drawn over the frames before it, as their disposal methods require, as a
+// full image of the size of the animation. The GIF is left unchanged. If no frame is drawn over another, these are
+// the frames of the GIF themselves.
+func (gf *GIF) coalesced() []*image.RGBA {
+	if !gf.layered() {
+		return append([]*image.RGBA(nil), gf.Image...)
+	}
+	bounds := gf.bounds()
+	canvas := image.NewRGBA(bounds)
+	frames := make([]*image.RGBA, len(gf.Image))
+	for k, frame := range gf.Image {
+		var previous *image.RGBA
+		disposal := gf.disposal(k)
+		if disposal == DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		snapshot := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		copy(snapshot.Pix, canvas.Pix)
+		frames[k] = snapshot
+		switch disposal {
+		case DisposalNone:
+		case DisposalPrevious:
+			canvas = previous
+		default:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		}
+	}
+	return frames
+}
+
+// flatten draws every frame that is kept with DisposalNone or DisposalPrevious into the frames after it, as a player
+// shows them, so every frame becomes a full image that can be reordered. The frame delays are kept.
+func (gf *GIF) flatten() {
+	if !gf.layered() {
+		return
+	}
+	gf.Image = gf.coalesced()
+	gf.Disposal = nil
+}
+
+// layered reports whether a frame of the GIF is drawn over by the frames after it, with DisposalNone or DisposalPrevious.
+func (gf *GIF) layered() bool {
+	for k := range gf.Image {
+		if d := gf.disposal(k); d == DisposalNone || d == DisposalPrevious {
+			return true
+		}
+	}
+	return false
+}
+
+// Reverse reverses the order of the frames of the GIF, so the animation plays backward. The frame delays are kept.
+func (gf *GIF) Reverse() {
+	gf.flatten()
+	n := len(gf.Image)
+	delays := make([]int, n)
+	for k := range gf.Image {
+		delays[n-1-k] = gf.delay(k)
+	}
+	for a, b := 0, n-1; a < b; a, b = a+1, b-1 {
+		gf.Image[a], gf.Image[b] = gf.Image[b], gf.Image[a]
+	}
+	gf.Delay = delays
+	gf.Disposal = nil
+}
+
+// Boomerang makes the GIF play forward and then backward, so it loops back and forth seamlessly.
+// The first and last frames are not repeated at the turns.
+func (gf *GIF) Boomerang() {
+	n := len(gf.Image)
+	if n < 2 {
+		// A single frame plays the same both ways.
+		return
+	}
+	gf.flatten()
+	delays := make([]int, n, 2*n-2)
+	for k := range delays {
+		delays[k] = gf.delay(k)
+	}
+	for k := n - 2; k > 0; k-- {
+		delays = append(delays, delays[k])
+		gf.Image = append(gf.Image, gf.Image[k])
+	}
+	gf.Delay = delays
+	gf.Disposal = nil
+}
+
+// Frames returns every frame of the GIF as an Image, such as to inspect or edit a frame or to use it as a thumbnail.
+// Frames that are drawn over the frames before them are returned as they are shown. The GIF is left unchanged.
+//
+// Returns: A new Image for every frame, in order.
+func (gf *GIF) Frames() []*Image {
+	coalesced := gf.coalesced()
+	frames := make([]*Image, len(coalesced))
+	for k, img := range coalesced {
+		frames[k] = Render(img)
+	}
+	return frames
+}
+
+// ExportFrames saves every frame of the GIF to its own file in a directory, named by its index with zero padding
+// so the files sort in order, such as "frame_000.png", "frame_001.png", and so on. The directory is created if needed.
+//
+// dir: The directory to save the frames to.
+// format: The name of a registered format, such as "png" or "jpeg", or one of its extensions, such as "jpg".
+// opts: Optional settings passed to the encoder, such as WithQuality.
+//
+// Returns: An error if the format is unknown, or if a file cannot be written.
+func (gf *GIF) ExportFrames(dir, format string, opts ...Option) error {
+	name, ext := extensionForFormat(format)
+	if name == "" {
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	digits := max(3, len(fmt.Sprint(len(gf.Image)-1)))
+	for k, frame := range gf.Frames() {
+		filename := filepath.Join(dir, fmt.Sprintf("frame_%0*d%s", digits, k, ext))
+		err := saveContext(context.Background(), filename, func(w io.Writer) error {
+			return frame.EncodeFormat(w, name, opts...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package picrocess
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// ErrGIFTooLarge is returned by ToGIFByteMaxSize when the GIF cannot be made small enough.
+var ErrGIFTooLarge = errors.New("picrocess: GIF does not fit the size limit")
+
+const (
+	// minSizeColors is the fewest colors ToGIFByteMaxSize reduces frames to before it scales them down.
+	minSizeColors = 64
+	// minSizeScale is the smallest factor ToGIFByteMaxSize scales frames down by.
+	minSizeScale = 0.25
+	// maxSizeFrameStep is the largest n for which ToGIFByteMaxSize keeps only every nth frame.
+	maxSizeFrameStep = 4
+)
+
+// GIFReduction describes what ToGIFByteMaxSize gave up to make a GIF fit its size limit.
+type GIFReduction struct {
+	// Colors is the largest number of colors in the palette of a frame.
+	Colors int
+	// Scale is the factor the frames were resized by; 1 if they kept their size.
+	Scale float64
+	// Width and Height are the size of the encoded animation.
+	Width, Height uint
+	// FrameStep is n if only every nth frame was kept; 1 if every frame was kept.
+	FrameStep int
+	// Frames is the number of frames that were encoded.
+	Frames int
+}
+
+// ToGIFByteMaxSize encodes the GIF to at most maxBytes bytes, such as to fit the upload limits of chat apps.
+// It first encodes only the changes between frames as WithOptimize does, then reduces the number of colors down to 64,
+// then scales the frames down, and finally keeps only every second, third, or fourth frame, with longer delays
+// so the animation keeps its speed. The GIF itself is left unchanged.
+//
+// maxBytes: The largest size of the encoded GIF, in bytes.
+// opts: Optional settings, as for ToGIFByte; WithColors sets the number of colors to start from.
+//
+// Returns: The encoded GIF, what was reduced to make it fit, and ErrGIFTooLarge if it does not fit even when reduced
+// as far as possible.
+func (gf *GIF) ToGIFByteMaxSize(maxBytes int, opts ...Option) ([]byte, GIFReduction, error) {
+	options := NewOptions(opts...)
+	reduction := GIFReduction{Colors: options.colors, Scale: 1, FrameStep: 1}
+	if reduction.Colors == 0 {
+		reduction.Colors = 256
+	}
+	if options.palette != nil {
+		reduction.Colors = len(options.palette.palette)
+	}
+	bounds := gf.bounds()
+	encode := func() ([]byte, error) {
+		reduced, err := gf.reduced(bounds, reduction.Scale, reduction.FrameStep)
+		if err != nil {
+			return nil, err
+		}
+		reduction.Width, reduction.Height = uint(reduced.bounds().Dx()), uint(reduced.bounds().Dy())
+		reduction.Frames = len(reduced.Image)
+		encodeOpts := append([]Option{WithOptimize()}, opts...)
+		return reduced.ToGIFByte(append(encodeOpts, WithColors(reduction.Colors))...)
+	}
+	data, err := encode()
+	if err != nil {
+		return nil, reduction, err
+	}
+	// Fewer colors first, since they cost the least detail.
+	for len(data) > maxBytes && options.palette == nil && reduction.Colors > minSizeColors {
+		reduction.Colors = max(minSizeColors, reduction.Colors/2)
+		if data, err = encode(); err != nil {
+			return nil, reduction, err
+		}
+	}
+	// Then a smaller size, guessing the scale from how much too large the GIF is, and fewer frames once the frames
+	// would be scaled to less than half their size.
+	for len(data) > maxBytes {
+		// The size of a GIF grows roughly with its number of pixels; aim a little lower to avoid another attempt.
+		scale := reduction.Scale * math.Min(0.9, math.Sqrt(0.9*float64(maxBytes)/float64(len(data))))
+		switch {
+		case scale >= 0.5 || reduction.FrameStep >= maxSizeFrameStep && scale >= minSizeScale:
+			reduction.Scale = scale
+		case reduction.FrameStep < maxSizeFrameStep:
+			reduction.FrameStep++
+		case reduction.Scale > minSizeScale:
+			reduction.Scale = minSizeScale
+		default:
+			return nil, reduction, ErrGIFTooLarge
+		}
+		if data, err = encode(); err != nil {
+			return nil, reduction, err
+		}
+	}
+	return data, reduction, nil
+}
+
+// reduced returns a copy of the GIF with its frames scaled by scale from the size of bounds, keeping only every
+// step-th frame with the delays of the frames left out added to it.
+func (gf *GIF) reduced(bounds image.Rectangle, scale float64, step int) (*GIF, error) {
+	reduced := &GIF{
+		Delay:     append([]int(nil), gf.Delay...),
+		Image:     append([]*image.RGBA(nil), gf.Image...),
+		Disposal:  append([]Disposal(nil), gf.Disposal...),
+		LoopCount: gf.LoopCount,
+	}
+	if step > 1 {
+		reduced.flatten()
+		images := make([]*image.RGBA, 0, (len(reduced.Image)+step-1)/step)
+		delays := make([]int, 0, cap(images))
+		for k := 0; k < len(reduced.Image); k += step {
+			delay := 0
+			for j := k; j < min(k+step, len(reduced.Image)); j++ {
+				delay += effectiveDelay(reduced.delay(j))
+			}
+			images = append(images, reduced.Image[k])
+			delays = append(delays, delay)
+		}
+		reduced.Image, reduced.Delay = images, delays
+	}
+	if scale < 1 {
+		w := uint(max(1, math.Round(float64(bounds.Dx())*scale)))
+		h := uint(max(1, math.Round(float64(bounds.Dy())*scale)))
+		if err := reduced.Resize(w, h, FilterBilinear); err != nil {
+			return nil, err
+		}
+	}
+	return reduced, nil
+}
@@ -0,0 +1,63 @@
+package picrocess
+
+// Glow adds a soft glow around the alpha silhouette of the image, such as a neon light effect.
+// The silhouette is filled with the glow color, blurred, and placed underneath the original pixels.
+// The image size does not change, so leave enough transparent padding for the glow to spread.
+//
+// c: The color (RGBA) of the glow.
+// radius: How far the glow spreads from the silhouette, in pixels.
+// intensity: The strength of the glow; 1 keeps the blurred alpha as-is and larger values make it brighter.
+func (i *Image) Glow(c RGBA, radius float64, intensity float64) {
+	if radius <= 0 || intensity <= 0 {
+		return
+	}
+	silhouette := NewImage(i.Width, i.Height, RGBA{})
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			silhouette.Pixel[x][y] = RGBA{c.R, c.G, c.B, uint8(uint(c.A) * uint(i.Pixel[x][y].A) / 255)}
+		}
+	}
+	glow := silhouette.gaussianBlur(radius)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			g := glow.Pixel[x][y]
+			g.A = clampUint8(float64(g.A) * intensity)
+			i.Pixel[x][y] = i.Pixel[x][y].over(g)
+		}
+	}
+}
+
+// Bloom makes bright regions of the image bleed light into their surroundings.
+// Pixels brighter than the threshold are extracted, blurred, and added back on top of the image.
+//
+// threshold: The minimum brightness (0-255) of a pixel to contribute to the bloom.
+// radius: How far the light spreads, in pixels.
+// intensity: The strength of the added light; 1 adds the blurred highlights as-is.
+func (i *Image) Bloom(threshold uint8, radius float64, intensity float64) {
+	if radius <= 0 || intensity <= 0 {
+		return
+	}
+	highlights := NewImage(i.Width, i.Height, RGBA{})
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			if pixel.Brightness() >= int(threshold) {
+				highlights.Pixel[x][y] = pixel
+			}
+		}
+	}
+	light := highlights.gaussianBlur(radius)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			l := light.Pixel[x][y]
+			amount := float64(l.A) / 255 * intensity
+			i.Pixel[x][y] = RGBA{
+				R: clampUint8(float64(pixel.R) + float64(l.R)*amount),
+				G: clampUint8(float64(pixel.G) + float64(l.G)*amount),
+				B: clampUint8(float64(pixel.B) + float64(l.B)*amount),
+				A: pixel.A,
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package picrocess
+
+import (
+	"image"
+	"strconv"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// drawBitmapText draws text with the bundled 7×13 bitmap font, for labels that need no font file.
+// Characters the font lacks are drawn as a replacement character.
+func (i *Image) drawBitmapText(x, baseline uint, text string, c RGBA) {
+	face := basicfont.Face7x13
+	dot := fixed.P(int(x), int(baseline))
+	for _, r := range text {
+		dr, mask, maskp, advance, ok := face.Glyph(dot, r)
+		if !ok {
+			dr, mask, maskp, advance, _ = face.Glyph(dot, '�')
+		}
+		if alpha, _ := mask.(*image.Alpha); alpha != nil {
+			for py := max(dr.Min.Y, 0); py < dr.Max.Y; py++ {
+				for px := max(dr.Min.X, 0); px < dr.Max.X; px++ {
+					if alpha.AlphaAt(maskp.X+px-dr.Min.X, maskp.Y+py-dr.Min.Y).A >= 128 {
+						i.Set(uint(px), uint(py), c)
+					}
+				}
+			}
+		}
+		dot.X += advance
+	}
+}
+
+// DrawGrid draws evenly spaced vertical and horizontal lines over the whole image, such as for debug overlays
+// or checking the alignment of a layout. The lines start at the top left corner; the edges themselves are not drawn.
+//
+// spacing: The distance between lines, in pixels.
+// c: The color (RGBA) of the lines.
+// thickness: The thickness of the lines, in pixels.
+// opts: Optional settings; WithBlendMode sets how the grid is blended with the image.
+func (i *Image) DrawGrid(spacing uint, c RGBA, thickness float64, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawGrid(spacing, c, thickness)
+	}) {
+		return
+	}
+	if spacing == 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	for x := spacing; x < i.Width; x += spacing {
+		i.Line(NewRect(x, 0, x, i.Height-1), c, thickness, true)
+	}
+	for y := spacing; y < i.Height; y += spacing {
+		i.Line(NewRect(0, y, i.Width-1, y), c, thickness, true)
+	}
+}
+
+// DrawRulers draws pixel-coordinate rulers along the top and left edges of the image, such as for design spec exports.
+// Every ruler has a long tick labeled with its coordinate at every multiple of spacing, and short ticks between them.
+// The labels use a bundled bitmap font, so no font is needed.
+//
+// spacing: The distance between labeled ticks, in pixels. Labels need about 40 pixels to stay readable.
+// c: The color (RGBA) of the ticks and labels.
+// background: The color (RGBA) of the ruler bands.
+// opts: Optional settings; WithBlendMode sets how the rulers are blended with the image.
+func (i *Image) DrawRulers(spacing uint, c, background RGBA, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawRulers(spacing, c, background)
+	}) {
+		return
+	}
+	if spacing == 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	const band = 16
+	side := uint(7*len(strconv.Itoa(int(i.Height))) + 6)
+	minor := spacing / 5
+	if minor < 2 {
+		minor = spacing
+	}
+	fill := func(r Rect) {
+		for x := r.W1; x < min(r.W2, i.Width); x++ {
+			for y := r.H1; y < min(r.H2, i.Height); y++ {
+				i.Set(x, y, background.over(i.Pixel[x][y]))
+			}
+		}
+	}
+	fill(NewRect(0, 0, i.Width, band))
+	fill(NewRect(0, band, side, i.Height))
+	for x := uint(0); x < i.Width; x += minor {
+		length := uint(4)
+		if x%spacing == 0 {
+			length = band
+			if x > 0 {
+				i.drawBitmapText(x+2, 11, strconv.Itoa(int(x)), c)
+			}
+		}
+		for y := band - length; y < band; y++ {
+			i.Set(x, y, c)
+		}
+	}
+	for y := uint(0); y < i.Height; y += minor {
+		if y < band {
+			continue
+		}
+		length := uint(4)
+		if y%spacing == 0 {
+			length = side
+			i.drawBitmapText(2, y+12, strconv.Itoa(int(y)), c)
+		}
+		for x := side - length; x < side; x++ {
+			i.Set(x, y, c)
+		}
+	}
+}
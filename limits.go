@@ -0,0 +1,119 @@
+package picrocess
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	// MaxPixels is the largest number of pixels (width × height) an image may have to be loaded.
+	// Larger images are rejected before their pixels are decoded, so untrusted uploads cannot exhaust memory.
+	// Set it to 0 to disable the limit.
+	MaxPixels = 64 * 1024 * 1024
+	// MaxGIFFrames is the largest number of frames a GIF may have to be loaded by the GIF load paths.
+	// Set it to 0 to disable the limit.
+	MaxGIFFrames = 1000
+	// MaxGIFBytes is the largest size, in bytes, of a GIF file to be loaded by LoadGIF and DecodeGIF.
+	// Larger files are rejected before they are read into memory completely. Set it to 0 to disable the limit.
+	MaxGIFBytes = 64 * 1024 * 1024
+	// MaxGIFPixels is the largest total number of pixels (width × height × frames) of the frames of a GIF
+	// loaded by the GIF load paths, since every frame is coalesced into a full image of the GIF's size.
+	// Set it to 0 to disable the limit.
+	MaxGIFPixels = 256 * 1024 * 1024
+)
+
+var (
+	// ErrImageTooLarge is returned (wrapped in a LimitError) when an image has more than MaxPixels pixels,
+	// or the frames of a GIF more than MaxGIFPixels pixels.
+	ErrImageTooLarge = errors.New("picrocess: image too large")
+	// ErrTooManyFrames is returned (wrapped in a LimitError) when a GIF has more than MaxGIFFrames frames.
+	ErrTooManyFrames = errors.New("picrocess: too many frames")
+	// ErrFileTooLarge is returned (wrapped in a LimitError) when a GIF file has more than MaxGIFBytes bytes,
+	// or a project archive or a file inside it is larger than MaxProjectBytes or MaxProjectEntryBytes.
+	ErrFileTooLarge = errors.New("picrocess: file too large")
+	// ErrMalformed is returned (wrapped in a DecodeError) when a decoder fails on corrupt input.
+	ErrMalformed = errors.New("picrocess: malformed image data")
+)
+
+// LimitError reports that decoded data exceeds one of the safety limits.
+// Use errors.Is with ErrImageTooLarge, ErrTooManyFrames, or ErrFileTooLarge to find out which one.
+type LimitError struct {
+	Err   error
+	Value int
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %d exceeds the limit of %d", e.Err, e.Value, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError reports that a decoder panicked on corrupt input instead of returning an error,
+// or that it returned no image and no error.
+// errors.Is(err, ErrMalformed) reports true for it.
+type DecodeError struct {
+	Format string
+	Panic  any
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v (%s): %v", ErrMalformed, e.Format, e.Panic)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return ErrMalformed
+}
+
+// checkPixels returns a LimitError if an image of the given size exceeds MaxPixels.
+func checkPixels(w, h int) error {
+	if w < 0 || h < 0 {
+		return ErrMalformed
+	}
+	if MaxPixels > 0 && (h != 0 && w > MaxPixels/h) {
+		return &LimitError{Err: ErrImageTooLarge, Value: w * h, Limit: MaxPixels}
+	}
+	return nil
+}
+
+// checkGIFPixels returns a LimitError if the frames of a GIF, every one as large as its logical screen of the given
+// size, have more than MaxGIFPixels pixels in total.
+func checkGIFPixels(w, h, frames int) error {
+	if MaxGIFPixels <= 0 || w <= 0 || h <= 0 || frames <= 0 {
+		return nil
+	}
+	if total := int64(w) * int64(h) * int64(frames); total > int64(MaxGIFPixels) {
+		return &LimitError{Err: ErrImageTooLarge, Value: int(min(total, math.MaxInt)), Limit: MaxGIFPixels}
+	}
+	return nil
+}
+
+// safeDecode calls decode, turning a panic of the decoder into a DecodeError.
+func safeDecode[T any](format string, decode func() (T, error)) (result T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &DecodeError{Format: format, Panic: r}
+		}
+	}()
+	return decode()
+}
+
+// readLimited reads all of r, returning a LimitError as soon as it has read more than limit bytes.
+// A limit of 0 or less reads without a limit.
+func readLimited(r io.Reader, limit int) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, &LimitError{Err: ErrFileTooLarge, Value: len(data), Limit: limit}
+	}
+	return data, nil
+}
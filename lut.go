@@ -0,0 +1,175 @@
+package picrocess
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLUT is returned (wrapped with details) when a .cube file cannot be parsed.
+var ErrInvalidLUT = errors.New("picrocess: invalid LUT")
+
+// LUT is a 3D color lookup table, as exported for color grading by DaVinci Resolve, Photoshop, and similar tools.
+type LUT struct {
+	Title string
+	Size  int // Number of samples along each axis
+	// DomainMin and DomainMax are the input values mapped to the first and last samples, usually 0 and 1.
+	DomainMin, DomainMax [3]float64
+	// Table holds Size×Size×Size output colors (0 to 1), with red changing fastest, then green, then blue.
+	Table [][3]float64
+}
+
+// LoadLUT loads a 3D LUT from a .cube file.
+//
+// filename: The path to the .cube file to load.
+//
+// Returns: A pointer to the parsed LUT, or an error if the file cannot be read or parsed.
+func LoadLUT(filename string) (*LUT, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadLUT(file)
+}
+
+// ReadLUT parses a 3D LUT in the .cube format.
+//
+// r: The reader to parse the LUT from.
+//
+// Returns: A pointer to the parsed LUT, or an error if it cannot be parsed.
+func ReadLUT(r io.Reader) (*LUT, error) {
+	lut := &LUT{DomainMax: [3]float64{1, 1, 1}}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "TITLE":
+			lut.Title = strings.Trim(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "TITLE")), `"`)
+		case "LUT_1D_SIZE":
+			return nil, fmt.Errorf("%w: line %d: 1D LUTs are not supported", ErrInvalidLUT, line)
+		case "LUT_3D_SIZE":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%w: line %d: malformed LUT_3D_SIZE", ErrInvalidLUT, line)
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil || size < 2 || size > 256 {
+				return nil, fmt.Errorf("%w: line %d: LUT_3D_SIZE must be between 2 and 256", ErrInvalidLUT, line)
+			}
+			lut.Size = size
+			lut.Table = make([][3]float64, 0, size*size*size)
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			v, err := parseTriplet(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidLUT, line, err)
+			}
+			if fields[0] == "DOMAIN_MIN" {
+				lut.DomainMin = v
+			} else {
+				lut.DomainMax = v
+			}
+		default:
+			if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+				// Unknown keywords are skipped, as other readers do.
+				continue
+			}
+			if lut.Size == 0 {
+				return nil, fmt.Errorf("%w: line %d: data before LUT_3D_SIZE", ErrInvalidLUT, line)
+			}
+			v, err := parseTriplet(fields)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidLUT, line, err)
+			}
+			if len(lut.Table) == cap(lut.Table) {
+				return nil, fmt.Errorf("%w: line %d: too many entries", ErrInvalidLUT, line)
+			}
+			lut.Table = append(lut.Table, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lut.Size == 0 || len(lut.Table) != lut.Size*lut.Size*lut.Size {
+		return nil, fmt.Errorf("%w: expected %d entries, got %d", ErrInvalidLUT, lut.Size*lut.Size*lut.Size, len(lut.Table))
+	}
+	return lut, nil
+}
+
+// parseTriplet parses three floating point numbers.
+func parseTriplet(fields []string) ([3]float64, error) {
+	var v [3]float64
+	if len(fields) != 3 {
+		return v, errors.New("expected three values")
+	}
+	for k, f := range fields {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return v, err
+		}
+		v[k] = n
+	}
+	return v, nil
+}
+
+// lookup returns the color of the LUT for an input color (0 to 1), with trilinear interpolation between samples.
+func (l *LUT) lookup(r, g, b float64) [3]float64 {
+	n := l.Size - 1
+	var base [3]int
+	var frac [3]float64
+	for k, v := range [3]float64{r, g, b} {
+		if span := l.DomainMax[k] - l.DomainMin[k]; span != 0 {
+			v = (v - l.DomainMin[k]) / span
+		}
+		v = math.Max(0, math.Min(1, v)) * float64(n)
+		base[k] = min(int(v), n-1)
+		frac[k] = v - float64(base[k])
+	}
+	at := func(dr, dg, db int) [3]float64 {
+		return l.Table[(base[0]+dr)+(base[1]+dg)*l.Size+(base[2]+db)*l.Size*l.Size]
+	}
+	var respond [3]float64
+	for ch := 0; ch < 3; ch++ {
+		c00 := at(0, 0, 0)[ch]*(1-frac[0]) + at(1, 0, 0)[ch]*frac[0]
+		c10 := at(0, 1, 0)[ch]*(1-frac[0]) + at(1, 1, 0)[ch]*frac[0]
+		c01 := at(0, 0, 1)[ch]*(1-frac[0]) + at(1, 0, 1)[ch]*frac[0]
+		c11 := at(0, 1, 1)[ch]*(1-frac[0]) + at(1, 1, 1)[ch]*frac[0]
+		c0 := c00*(1-frac[1]) + c10*frac[1]
+		c1 := c01*(1-frac[1]) + c11*frac[1]
+		respond[ch] = c0*(1-frac[2]) + c1*frac[2]
+	}
+	return respond
+}
+
+// ApplyLUT color grades the image with a 3D LUT, interpolating trilinearly between its samples.
+// The alpha channel of every pixel is kept intact.
+//
+// lut: The LUT to apply, as returned by LoadLUT. If it is nil, the image is left unchanged.
+func (i *Image) ApplyLUT(lut *LUT) {
+	if lut == nil || lut.Size < 2 || len(lut.Table) != lut.Size*lut.Size*lut.Size {
+		return
+	}
+	cache := make(map[[3]uint8][3]uint8)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			key := [3]uint8{pixel.R, pixel.G, pixel.B}
+			c, ok := cache[key]
+			if !ok {
+				v := lut.lookup(float64(pixel.R)/255, float64(pixel.G)/255, float64(pixel.B)/255)
+				c = [3]uint8{clampUint8(v[0] * 255), clampUint8(v[1] * 255), clampUint8(v[2] * 255)}
+				cache[key] = c
+			}
+			i.Pixel[x][y] = RGBA{c[0], c[1], c[2], pixel.A}
+		}
+	}
+}
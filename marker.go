@@ -0,0 +1,82 @@
+package picrocess
+
+import "math"
+
+type MarkerShape int
+
+const (
+	// MarkerCircle is a filled circle.
+	MarkerCircle MarkerShape = iota
+	// MarkerSquare is a filled square.
+	MarkerSquare
+	// MarkerDiamond is a filled square standing on a corner.
+	MarkerDiamond
+	// MarkerTriangle is a filled triangle pointing up.
+	MarkerTriangle
+	// MarkerPlus is an upright cross (+).
+	MarkerPlus
+	// MarkerCross is a diagonal cross (×).
+	MarkerCross
+	// MarkerPin is a map pin whose tip, rather than its center, is at the point.
+	MarkerPin
+)
+
+// DrawMarker draws a marker with anti-aliased edges, such as a point of a scatter plot or a pin on a map.
+//
+// p: The pixel the marker is centered on; for MarkerPin, the pixel its tip points at.
+// shape: The shape of the marker, such as MarkerCircle or MarkerPin.
+// size: The width and height of the marker, in pixels.
+// c: The color (RGBA) of the marker.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the marker, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawMarker(p Offset, shape MarkerShape, size uint, c RGBA, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawMarker(p, shape, size, c)
+	}) {
+		return
+	}
+	if size == 0 {
+		return
+	}
+	x, y := float64(p.W)+0.5, float64(p.H)+0.5
+	s := float64(size)
+	h := s / 2
+	path := NewPath()
+	rule := NonZero
+	switch shape {
+	case MarkerSquare:
+		path.addPolygon(point{x - h, y - h}, point{x + h, y - h}, point{x + h, y + h}, point{x - h, y + h})
+	case MarkerDiamond:
+		path.addPolygon(point{x, y - h}, point{x + h, y}, point{x, y + h}, point{x - h, y})
+	case MarkerTriangle:
+		// Center the triangle on its centroid, which sits a third of the way up from its base.
+		height := s * math.Sqrt(3) / 2
+		path.addPolygon(point{x, y - height*2/3}, point{x + h, y + height/3}, point{x - h, y + height/3})
+	case MarkerPlus, MarkerCross:
+		arm := math.Max(1, s/5) / 2
+		bars := [][4]point{
+			{{x - h, y - arm}, {x + h, y - arm}, {x + h, y + arm}, {x - h, y + arm}},
+			{{x - arm, y - h}, {x + arm, y - h}, {x + arm, y + h}, {x - arm, y + h}},
+		}
+		for _, bar := range bars {
+			if shape == MarkerCross {
+				// Rotate the bars by 45 degrees around the center.
+				for k, pt := range bar {
+					dx, dy := pt.x-x, pt.y-y
+					bar[k] = point{x + (dx-dy)/math.Sqrt2, y + (dx+dy)/math.Sqrt2}
+				}
+			}
+			path.addPolygon(bar[:]...)
+		}
+	case MarkerPin:
+		// A round head whose sides run straight down to the tip, with a hole in the middle of the head.
+		r := s * 0.35
+		cy := y - s + r
+		spread := math.Acos(r/(s-r)) * 180 / math.Pi
+		path.MoveTo(x, y).Arc(x, cy, r, 90+spread, 450-spread).Close()
+		path.Ellipse(x, cy, r*0.4, r*0.4)
+		rule = EvenOdd
+	default:
+		path.Ellipse(x, y, h, h)
+	}
+	i.FillPath(path, c, rule)
+}
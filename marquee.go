@@ -0,0 +1,39 @@
+package picrocess
+
+import "math"
+
+// Marquee builds a GIF of text scrolling from right to left through a box, like a news ticker: the text enters at the
+// right edge and leaves at the left edge, so the animation loops seamlessly. The text is centered vertically in the box.
+//
+// font: The Font object to use for the text.
+// c: The color (RGBA) of the text.
+// bg: The background color (RGBA) of the box.
+// w: The width of the box, in pixels.
+// h: The height of the box, in pixels.
+// size: The font size of the text.
+// text: The text to scroll.
+// speed: How fast the text scrolls, in pixels per second. The text moves at least one pixel per frame.
+// opts: Optional settings; WithDelay sets the delay of every frame, and the text options of Text, such as
+// WithTextOutline, style the text. The anchor is ignored.
+//
+// Returns: A new GIF containing the frames, or an error if there is an issue rendering the text or the frames.
+func Marquee(font *Font, c, bg RGBA, w, h uint, size float64, text string, speed float64, opts ...Option) (*GIF, error) {
+	options := NewOptions(opts...)
+	textW, _ := font.TextSize(size, text, opts...)
+	// Draw the text once on a strip with an empty box on either side, and crop every frame out of it.
+	strip := NewImage(w+textW+w, h, bg)
+	if err := strip.Text(font, c, NewOffset(w, h/2), size, text, append(opts, WithAnchor(AnchorLeft))...); err != nil {
+		return nil, err
+	}
+	step := max(1, math.Round(speed*float64(options.Delay)/100))
+	distance := float64(w + textW)
+	frames := max(1, int(math.Ceil(distance/step)))
+	gf := NewGIF()
+	for k := 0; k < frames; k++ {
+		x := uint(math.Round(float64(k) * distance / float64(frames)))
+		if err := gf.Append(strip.Crop(NewRect(x, 0, x+w, h)), options.Delay); err != nil {
+			return nil, err
+		}
+	}
+	return gf, nil
+}
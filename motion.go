@@ -0,0 +1,127 @@
+package picrocess
+
+import "math"
+
+// motionStep is the time between the keyframes sampled by the motion presets, in seconds.
+const motionStep = 1.0 / 50
+
+// sampleMotion adds linear keyframes for a property by sampling fn from start to start+duration.
+// fn receives the elapsed time since start, in seconds.
+func (l *Layer) sampleMotion(property LayerProperty, start, duration float64, fn func(elapsed float64) float64) {
+	steps := max(1, int(math.Ceil(duration/motionStep)))
+	for k := 0; k <= steps; k++ {
+		elapsed := duration * float64(k) / float64(steps)
+		l.Keyframe(property, start+elapsed, fn(elapsed), nil)
+	}
+}
+
+// Spring moves a layer property from one value to another like a damped spring,
+// overshooting the target and settling on it by the end of the duration. It works well
+// with PropertyScale for "pop in" effects.
+//
+// property: The property to animate.
+// start: When the motion starts, in seconds from the start of the timeline.
+// duration: How long the spring takes to settle, in seconds.
+// from: The value at the start of the motion.
+// to: The value the spring settles on.
+//
+// Returns: The layer, so calls can be chained.
+func (l *Layer) Spring(property LayerProperty, start, duration, from, to float64) *Layer {
+	if duration <= 0 {
+		return l.Keyframe(property, start, to, nil)
+	}
+	const damping = 0.3
+	omega := math.Log(100) / (damping * duration)
+	omegaD := omega * math.Sqrt(1-damping*damping)
+	l.sampleMotion(property, start, duration, func(elapsed float64) float64 {
+		if elapsed >= duration {
+			return to
+		}
+		decay := math.Exp(-damping * omega * elapsed)
+		return to + (from-to)*decay*(math.Cos(omegaD*elapsed)+damping*omega/omegaD*math.Sin(omegaD*elapsed))
+	})
+	return l
+}
+
+// GravityDrop drops a layer from one height onto a floor, where it bounces a few times before it comes to rest,
+// such as for making a logo fall into place.
+//
+// start: When the drop starts, in seconds from the start of the timeline.
+// duration: How long the drop and all bounces take, in seconds.
+// fromY: The vertical position of the layer's center when it is released.
+// floorY: The vertical position of the layer's center when it rests on the floor.
+//
+// Returns: The layer, so calls can be chained.
+func (l *Layer) GravityDrop(start, duration, fromY, floorY float64) *Layer {
+	if duration <= 0 {
+		return l.Keyframe(PropertyY, start, floorY, nil)
+	}
+	const restitution = 0.5
+	// With a restitution of 0.5, all bounces together take twice as long as the first fall.
+	fall := duration / 3
+	gravity := 2 * (floorY - fromY) / (fall * fall)
+	l.sampleMotion(PropertyY, start, duration, func(elapsed float64) float64 {
+		if elapsed < fall {
+			return fromY + gravity*elapsed*elapsed/2
+		}
+		elapsed -= fall
+		velocity := gravity * fall * restitution
+		for velocity/gravity > motionStep/4 {
+			airtime := 2 * velocity / gravity
+			if elapsed < airtime {
+				return floorY - velocity*elapsed + gravity*elapsed*elapsed/2
+			}
+			elapsed -= airtime
+			velocity *= restitution
+		}
+		return floorY
+	})
+	return l
+}
+
+// Shake shakes a layer from side to side with a motion that fades out over the duration,
+// such as for error or attention effects.
+//
+// start: When the shake starts, in seconds from the start of the timeline.
+// duration: How long the shake lasts, in seconds.
+// amplitude: How far the layer moves to either side at the start, in pixels.
+//
+// Returns: The layer, so calls can be chained.
+func (l *Layer) Shake(start, duration, amplitude float64) *Layer {
+	base := l.ValueAt(PropertyX, start)
+	if duration <= 0 {
+		return l
+	}
+	const frequency = 10
+	l.sampleMotion(PropertyX, start, duration, func(elapsed float64) float64 {
+		decay := 1 - elapsed/duration
+		return base + amplitude*decay*math.Sin(2*math.Pi*frequency*elapsed)
+	})
+	return l
+}
+
+// Orbit moves a layer in a circle around a center point.
+//
+// start: When the orbit starts, in seconds from the start of the timeline.
+// duration: How long the orbit lasts, in seconds.
+// cx: The horizontal position of the center of the orbit.
+// cy: The vertical position of the center of the orbit.
+// radius: The distance between the layer's center and the center of the orbit, in pixels.
+// turns: The number of full clockwise turns to make (negative values orbit counterclockwise).
+//
+// Returns: The layer, so calls can be chained.
+func (l *Layer) Orbit(start, duration, cx, cy, radius, turns float64) *Layer {
+	if duration <= 0 {
+		return l
+	}
+	angle := func(elapsed float64) float64 {
+		return 2 * math.Pi * turns * elapsed / duration
+	}
+	l.sampleMotion(PropertyX, start, duration, func(elapsed float64) float64 {
+		return cx + radius*math.Cos(angle(elapsed))
+	})
+	l.sampleMotion(PropertyY, start, duration, func(elapsed float64) float64 {
+		return cy + radius*math.Sin(angle(elapsed))
+	})
+	return l
+}
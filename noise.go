@@ -0,0 +1,108 @@
+package picrocess
+
+import (
+	"math"
+	"math/rand"
+)
+
+type NoiseKind int
+
+const (
+	// NoiseGaussian adds normally distributed noise to every channel of every pixel.
+	NoiseGaussian NoiseKind = iota
+	// NoiseUniform adds evenly distributed noise to every channel of every pixel.
+	NoiseUniform
+	// NoiseSaltAndPepper turns random pixels pure white or pure black.
+	NoiseSaltAndPepper
+	// NoiseMonochrome adds the same normally distributed noise to all channels of a pixel, without color speckles.
+	NoiseMonochrome
+)
+
+// AddNoise adds random noise to the image. The same seed always produces the same noise,
+// so test fixtures and "glitchy" images can be reproduced exactly.
+// The alpha channel of every pixel is kept intact.
+//
+// kind: The kind of noise to add.
+// amount: The strength of the noise, from 0 to 1. For gaussian and monochrome noise it is the standard deviation,
+// for uniform noise the maximum change, both relative to the full channel range; for salt-and-pepper noise it is
+// the fraction of pixels that are replaced.
+// seed: The seed of the random number generator.
+func (i *Image) AddNoise(kind NoiseKind, amount float64, seed int64) {
+	if amount <= 0 {
+		return
+	}
+	random := rand.New(rand.NewSource(seed))
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			switch kind {
+			case NoiseSaltAndPepper:
+				if random.Float64() >= amount {
+					continue
+				}
+				v := uint8(0)
+				if random.Intn(2) == 1 {
+					v = 255
+				}
+				i.Pixel[x][y] = RGBA{v, v, v, pixel.A}
+			case NoiseMonochrome:
+				n := random.NormFloat64() * amount * 255
+				i.Pixel[x][y] = RGBA{
+					R: clampUint8(float64(pixel.R) + n),
+					G: clampUint8(float64(pixel.G) + n),
+					B: clampUint8(float64(pixel.B) + n),
+					A: pixel.A,
+				}
+			default:
+				noise := func() float64 {
+					if kind == NoiseUniform {
+						return (random.Float64()*2 - 1) * amount * 255
+					}
+					return random.NormFloat64() * amount * 255
+				}
+				i.Pixel[x][y] = RGBA{
+					R: clampUint8(float64(pixel.R) + noise()),
+					G: clampUint8(float64(pixel.G) + noise()),
+					B: clampUint8(float64(pixel.B) + noise()),
+					A: pixel.A,
+				}
+			}
+		}
+	}
+}
+
+// FilmGrain adds analog film grain to the image. The grain is monochrome, slightly clumped,
+// and strongest in the midtones, like real film; pure blacks and whites are barely touched.
+// The grain pattern is fixed, so applying it to the same image always gives the same result.
+// The alpha channel of every pixel is kept intact.
+//
+// intensity: The strength of the grain, from 0 to 1.
+func (i *Image) FilmGrain(intensity float64) {
+	intensity = math.Max(0, math.Min(1, intensity))
+	if intensity == 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	random := rand.New(rand.NewSource(1))
+	grain := make([][]float64, i.Width)
+	for x := range grain {
+		grain[x] = make([]float64, i.Height)
+		for y := range grain[x] {
+			grain[x][y] = random.NormFloat64()
+		}
+	}
+	// A slight blur clumps the grain into specks larger than a single pixel.
+	grain = gaussianKernelBlur(grain, i.Width, i.Height, 0.6)
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			l := (0.299*float64(pixel.R) + 0.587*float64(pixel.G) + 0.114*float64(pixel.B)) / 255
+			n := grain[x][y] * 4 * l * (1 - l) * intensity * 96
+			i.Pixel[x][y] = RGBA{
+				R: clampUint8(float64(pixel.R) + n),
+				G: clampUint8(float64(pixel.G) + n),
+				B: clampUint8(float64(pixel.B) + n),
+				A: pixel.A,
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package picrocess
+
+import "image/jpeg"
+
+// Option configures an operation that accepts functional options, such as encoding, resizing, text, and QR codes.
+// Options that do not apply to an operation are ignored by it, so the same set of options can be shared.
+type Option func(o *Options)
+
+// GIFOption configures how a GIF is encoded by ToGIFBuffer, ToGIFByte, and SaveAsGIF.
+// It is the same type as Option and is kept for existing callers.
+type GIFOption = Option
+
+// Options holds the settings collected from functional options.
+// Custom encoders registered with RegisterEncoder receive it to read settings such as Quality.
+type Options struct {
+	// Quality is the encoding quality of lossy formats, from 1 to 100.
+	Quality int
+	// Delay is the delay of every frame of an animation, in 100ths of a second.
+	Delay int
+	// Anchor is the point of the drawn content that is placed at the given offset.
+	Anchor Anchor
+	// Filter is the resampling filter used to resize images.
+	Filter ResizeFilter
+	// Recovery is the error recovery level of QR codes.
+	Recovery QRRecovery
+	// LineHeight is the distance between the baselines of lines of text, as a multiple of the font size.
+	LineHeight float64
+
+	palette       *fixedPalette
+	colorSpace    *ColorSpace
+	backdrop      *backdrop
+	deterministic bool
+	roi           *roi
+	renderer      Renderer
+	shadow        *Shadow
+	blend         BlendMode
+	ellipsis      bool
+	textOutline   *textOutline
+	textFill      *textFill
+	optimize      bool
+	colors        int
+	dither        DitherMethod
+	disposal      Disposal
+	transparent   *RGBA
+	globalPalette bool
+}
+
+// NewOptions applies the options on top of the default settings and returns the result.
+//
+// opts: The options to apply, in order; later options override earlier ones.
+//
+// Returns: The collected settings.
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Quality:    jpeg.DefaultQuality,
+		Delay:      AnimateDelay,
+		Anchor:     AnchorTopLeft,
+		Filter:     FilterNearest,
+		Recovery:   QRHigh,
+		LineHeight: DefaultLineHeight,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithQuality sets the encoding quality of lossy formats such as JPEG.
+//
+// quality: The quality, from 1 (smallest file) to 100 (best quality). Values outside this range are clamped.
+func WithQuality(quality int) Option {
+	return func(o *Options) {
+		o.Quality = max(1, min(100, quality))
+	}
+}
+
+// WithDelay sets the delay of every frame of an animation.
+//
+// delay: The delay in 100ths of a second.
+func WithDelay(delay int) Option {
+	return func(o *Options) {
+		o.Delay = max(0, delay)
+	}
+}
+
+// WithAnchor sets which point of the drawn content is placed at the given offset.
+//
+// a: The anchor point, such as AnchorCenter.
+func WithAnchor(a Anchor) Option {
+	return func(o *Options) {
+		o.Anchor = a
+	}
+}
+
+// WithFilter sets the resampling filter used to resize images.
+//
+// f: The filter, such as FilterBilinear.
+func WithFilter(f ResizeFilter) Option {
+	return func(o *Options) {
+		o.Filter = f
+	}
+}
+
+// WithRecovery sets the error recovery level of QR codes.
+//
+// level: The recovery level, such as QRMedium.
+func WithRecovery(level QRRecovery) Option {
+	return func(o *Options) {
+		o.Recovery = level
+	}
+}
+
+// DefaultLineHeight is the distance between the baselines of lines of text, as a multiple of the font size,
+// unless WithLineHeight sets another.
+const DefaultLineHeight = 1.2
+
+// WithLineHeight sets the distance between lines of text with line breaks.
+//
+// factor: The distance between the baselines of lines, as a multiple of the font size, such as 1.5.
+func WithLineHeight(factor float64) Option {
+	return func(o *Options) {
+		o.LineHeight = max(0, factor)
+	}
+}
+
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// fractions returns the horizontal and vertical position of the anchor within a box, from 0 to 1.
+func (a Anchor) fractions() (float64, float64) {
+	if a < AnchorTopLeft || a > AnchorBottomRight {
+		return 0, 0
+	}
+	return float64(a%3) / 2, float64(a/3) / 2
+}
+
+type ResizeFilter int
+
+const (
+	// FilterNearest picks the nearest source pixel, keeping hard edges (good for pixel art).
+	FilterNearest ResizeFilter = iota
+	// FilterBilinear interpolates between the four nearest source pixels, for smoother results.
+	FilterBilinear
+)
+
+type QRRecovery int
+
+const (
+	// QRLow recovers about 7% of the data.
+	QRLow QRRecovery = iota
+	// QRMedium recovers about 15% of the data.
+	QRMedium
+	// QRHigh recovers about 25% of the data.
+	QRHigh
+	// QRHighest recovers about 30% of the data.
+	QRHighest
+)
@@ -0,0 +1,155 @@
+package picrocess
+
+import "math"
+
+type OutlineStyle int
+
+const (
+	// OutlineOutside draws the stroke around the silhouette, outside of the opaque pixels.
+	OutlineOutside OutlineStyle = iota
+	// OutlineInside draws the stroke along the edge, on top of the opaque pixels.
+	OutlineInside
+	// OutlineCenter splits the stroke evenly between the outside and the inside of the edge.
+	OutlineCenter
+)
+
+// alphaMask returns a 2D slice (X / Y) marking the pixels whose alpha is at least the given threshold.
+func (i *Image) alphaMask(threshold uint8) [][]bool {
+	mask := make([][]bool, i.Width)
+	for x := range i.Pixel {
+		mask[x] = make([]bool, i.Height)
+		for y := range i.Pixel[x] {
+			mask[x][y] = i.Pixel[x][y].A >= threshold
+		}
+	}
+	return mask
+}
+
+// distanceTransform calculates, for every pixel, the Euclidean distance to the nearest pixel set in the mask.
+// Pixels set in the mask have a distance of 0. If the mask is empty, every distance is +Inf.
+func distanceTransform(mask [][]bool, w, h uint) [][]float64 {
+	inf := math.Inf(1)
+	transform := func(f []float64) []float64 {
+		n := len(f)
+		d := make([]float64, n)
+		v := make([]int, n)
+		z := make([]float64, n+1)
+		k := -1
+		for q := 0; q < n; q++ {
+			if math.IsInf(f[q], 1) {
+				continue
+			}
+			if k < 0 {
+				k = 0
+				v[0] = q
+				z[0] = -inf
+				z[1] = inf
+				continue
+			}
+			s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+			for s <= z[k] {
+				k--
+				s = ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+			}
+			k++
+			v[k] = q
+			z[k] = s
+			z[k+1] = inf
+		}
+		if k < 0 {
+			for q := range d {
+				d[q] = inf
+			}
+			return d
+		}
+		k = 0
+		for q := 0; q < n; q++ {
+			for z[k+1] < float64(q) {
+				k++
+			}
+			dq := float64(q - v[k])
+			d[q] = dq*dq + f[v[k]]
+		}
+		return d
+	}
+	dist := make([][]float64, w)
+	for x := 0; x < int(w); x++ {
+		column := make([]float64, h)
+		for y := 0; y < int(h); y++ {
+			if mask[x][y] {
+				column[y] = 0
+			} else {
+				column[y] = inf
+			}
+		}
+		dist[x] = transform(column)
+	}
+	row := make([]float64, w)
+	for y := 0; y < int(h); y++ {
+		for x := 0; x < int(w); x++ {
+			row[x] = dist[x][y]
+		}
+		out := transform(row)
+		for x := 0; x < int(w); x++ {
+			dist[x][y] = math.Sqrt(out[x])
+		}
+	}
+	return dist
+}
+
+// Outline draws a stroke around the alpha silhouette of the image, such as the white border of a sticker.
+// Pixels with an alpha of at least 128 are treated as part of the silhouette, and the stroke edge is anti-aliased.
+// The image size does not change, so leave enough transparent padding for outside strokes.
+//
+// c: The color (RGBA) of the stroke.
+// thickness: The thickness of the stroke, in pixels.
+// style: Where the stroke is placed relative to the silhouette edge (outside, inside, or centered).
+func (i *Image) Outline(c RGBA, thickness uint, style OutlineStyle) {
+	if thickness == 0 || i.Width == 0 || i.Height == 0 {
+		return
+	}
+	outside, inside := float64(thickness), 0.0
+	switch style {
+	case OutlineInside:
+		outside, inside = 0, float64(thickness)
+	case OutlineCenter:
+		outside, inside = float64(thickness)/2, float64(thickness)/2
+	}
+	mask := i.alphaMask(128)
+	toInside := distanceTransform(mask, i.Width, i.Height)
+	inverse := make([][]bool, i.Width)
+	for x := range mask {
+		inverse[x] = make([]bool, i.Height)
+		for y := range mask[x] {
+			inverse[x][y] = !mask[x][y]
+		}
+	}
+	toOutside := distanceTransform(inverse, i.Width, i.Height)
+	coverage := func(d, width float64) float64 {
+		return math.Max(0, math.Min(1, width+1-d))
+	}
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			pixel := i.Pixel[x][y]
+			if mask[x][y] {
+				cov := coverage(toOutside[x][y], inside)
+				if inside == 0 || cov == 0 {
+					continue
+				}
+				stroke := c
+				stroke.A = uint8(float64(c.A) * cov)
+				blended := stroke.over(pixel)
+				blended.A = pixel.A
+				i.Pixel[x][y] = blended
+				continue
+			}
+			cov := coverage(toInside[x][y], outside)
+			if outside == 0 || cov == 0 {
+				continue
+			}
+			stroke := c
+			stroke.A = uint8(float64(c.A) * cov)
+			i.Pixel[x][y] = pixel.over(stroke)
+		}
+	}
+}
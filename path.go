@@ -0,0 +1,296 @@
+package picrocess
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+type FillRule int
+
+const (
+	// NonZero fills every area that the path winds around at least once.
+	NonZero FillRule = iota
+	// EvenOdd fills areas that are enclosed an odd number of times, leaving holes in overlapping regions.
+	EvenOdd
+)
+
+type point struct {
+	x, y float64
+}
+
+type subpath struct {
+	points []point
+	closed bool
+}
+
+// Path is a vector outline built from straight lines and cubic Bézier curves.
+// Curves are flattened into short line segments as they are added.
+type Path struct {
+	subpaths []subpath
+}
+
+// NewPath creates a new, empty Path.
+func NewPath() *Path {
+	return &Path{
+		subpaths: make([]subpath, 0),
+	}
+}
+
+// current returns the sub-path that is currently being drawn, starting a new one at (0, 0) if there is none.
+func (p *Path) current() *subpath {
+	if len(p.subpaths) == 0 || p.subpaths[len(p.subpaths)-1].closed {
+		start := point{}
+		if len(p.subpaths) > 0 {
+			start = p.subpaths[len(p.subpaths)-1].points[0]
+		}
+		p.subpaths = append(p.subpaths, subpath{points: []point{start}})
+	}
+	return &p.subpaths[len(p.subpaths)-1]
+}
+
+// MoveTo starts a new sub-path at the point (x, y).
+func (p *Path) MoveTo(x, y float64) *Path {
+	if len(p.subpaths) > 0 {
+		last := &p.subpaths[len(p.subpaths)-1]
+		if !last.closed && len(last.points) == 1 {
+			last.points[0] = point{x, y}
+			return p
+		}
+	}
+	p.subpaths = append(p.subpaths, subpath{points: []point{{x, y}}})
+	return p
+}
+
+// LineTo adds a straight line from the current point to the point (x, y).
+func (p *Path) LineTo(x, y float64) *Path {
+	s := p.current()
+	s.points = append(s.points, point{x, y})
+	return p
+}
+
+// CurveTo adds a cubic Bézier curve from the current point to the point (x, y),
+// using (cx1, cy1) and (cx2, cy2) as control points.
+func (p *Path) CurveTo(cx1, cy1, cx2, cy2, x, y float64) *Path {
+	s := p.current()
+	start := s.points[len(s.points)-1]
+	length := math.Hypot(cx1-start.x, cy1-start.y) + math.Hypot(cx2-cx1, cy2-cy1) + math.Hypot(x-cx2, y-cy2)
+	steps := max(1, int(math.Ceil(length/2)))
+	for n := 1; n <= steps; n++ {
+		t := float64(n) / float64(steps)
+		mt := 1 - t
+		s.points = append(s.points, point{
+			x: mt*mt*mt*start.x + 3*mt*mt*t*cx1 + 3*mt*t*t*cx2 + t*t*t*x,
+			y: mt*mt*mt*start.y + 3*mt*mt*t*cy1 + 3*mt*t*t*cy2 + t*t*t*y,
+		})
+	}
+	return p
+}
+
+// Ellipse adds a closed ellipse with the center (cx, cy) and the radii rx and ry as a new sub-path.
+func (p *Path) Ellipse(cx, cy, rx, ry float64) *Path {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	n := max(16, int(math.Ceil(2*math.Pi*math.Max(rx, ry)/1.5)))
+	p.MoveTo(cx+rx, cy)
+	for k := 1; k < n; k++ {
+		a := 2 * math.Pi * float64(k) / float64(n)
+		p.LineTo(cx+math.Cos(a)*rx, cy+math.Sin(a)*ry)
+	}
+	return p.Close()
+}
+
+// Arc adds a circular arc with the center (cx, cy) and the given radius, from startDeg to endDeg.
+// Angles are in degrees, clockwise from the positive x axis (3 o'clock). The arc is connected
+// with a straight line to the current point, or starts a new sub-path if there is no open one.
+func (p *Path) Arc(cx, cy, radius, startDeg, endDeg float64) *Path {
+	start, end := startDeg*math.Pi/180, endDeg*math.Pi/180
+	n := max(1, int(math.Ceil(math.Abs(end-start)*radius/1.5)))
+	for k := 0; k <= n; k++ {
+		a := start + (end-start)*float64(k)/float64(n)
+		x, y := cx+math.Cos(a)*radius, cy+math.Sin(a)*radius
+		if k == 0 && (len(p.subpaths) == 0 || p.subpaths[len(p.subpaths)-1].closed) {
+			p.MoveTo(x, y)
+		} else {
+			p.LineTo(x, y)
+		}
+	}
+	return p
+}
+
+// RoundedRect adds a closed rectangle with rounded corners as a new sub-path.
+// The radius is limited to half of the shorter side.
+func (p *Path) RoundedRect(x, y, w, h, radius float64) *Path {
+	radius = math.Max(0, math.Min(radius, math.Min(w, h)/2))
+	if radius == 0 {
+		return p.MoveTo(x, y).LineTo(x+w, y).LineTo(x+w, y+h).LineTo(x, y+h).Close()
+	}
+	// Control point distance that approximates a quarter circle with a cubic curve.
+	k := radius * 0.5522847
+	p.MoveTo(x+radius, y).LineTo(x+w-radius, y)
+	p.CurveTo(x+w-radius+k, y, x+w, y+radius-k, x+w, y+radius).LineTo(x+w, y+h-radius)
+	p.CurveTo(x+w, y+h-radius+k, x+w-radius+k, y+h, x+w-radius, y+h).LineTo(x+radius, y+h)
+	p.CurveTo(x+radius-k, y+h, x, y+h-radius+k, x, y+h-radius).LineTo(x, y+radius)
+	p.CurveTo(x, y+radius-k, x+radius-k, y, x+radius, y)
+	return p.Close()
+}
+
+// Close closes the current sub-path with a straight line back to its starting point.
+func (p *Path) Close() *Path {
+	if len(p.subpaths) == 0 {
+		return p
+	}
+	p.subpaths[len(p.subpaths)-1].closed = true
+	return p
+}
+
+// transformed returns a copy of the path with every point mapped through the transformation.
+func (p *Path) transformed(t Transform) *Path {
+	respond := &Path{subpaths: make([]subpath, len(p.subpaths))}
+	for k, s := range p.subpaths {
+		points := make([]point, len(s.points))
+		for n, pt := range s.points {
+			x, y := t.Apply(pt.x, pt.y)
+			points[n] = point{x, y}
+		}
+		respond.subpaths[k] = subpath{points: points, closed: s.closed}
+	}
+	return respond
+}
+
+// coverageMap is an anti-aliased coverage map (X / Y) of the pixels inside bounds, relative to bounds.Min.
+// Each value is between 0 (outside) and 1 (fully inside); pixels outside of bounds are not covered.
+// A map without values (cov is nil) is a rectangle that covers every pixel inside bounds fully.
+type coverageMap struct {
+	bounds image.Rectangle
+	cov    [][]float64
+}
+
+// newCoverageMap returns an empty coverage map for the pixels inside bounds.
+func newCoverageMap(bounds image.Rectangle) coverageMap {
+	cov := make([][]float64, bounds.Dx())
+	for x := range cov {
+		cov[x] = make([]float64, bounds.Dy())
+	}
+	return coverageMap{bounds: bounds, cov: cov}
+}
+
+// at returns the coverage of the pixel at (x, y), or 0 if it is outside of the map.
+func (m coverageMap) at(x, y int) float64 {
+	if !(image.Point{x, y}).In(m.bounds) {
+		return 0
+	}
+	if m.cov == nil {
+		return 1
+	}
+	return min(m.cov[x-m.bounds.Min.X][y-m.bounds.Min.Y], 1)
+}
+
+// addSpan adds horizontal coverage from x1 to x2 on row y, weighting partially covered pixels at both ends.
+func (m coverageMap) addSpan(x1, x2 float64, y int, weight float64) {
+	x1 = math.Max(x1, float64(m.bounds.Min.X))
+	x2 = math.Min(x2, float64(m.bounds.Max.X))
+	if x2 <= x1 {
+		return
+	}
+	start, end := int(x1), int(x2)
+	row := y - m.bounds.Min.Y
+	if start == end {
+		m.cov[start-m.bounds.Min.X][row] += (x2 - x1) * weight
+		return
+	}
+	m.cov[start-m.bounds.Min.X][row] += (float64(start+1) - x1) * weight
+	for x := start + 1; x < end; x++ {
+		m.cov[x-m.bounds.Min.X][row] += weight
+	}
+	if end < m.bounds.Max.X {
+		m.cov[end-m.bounds.Min.X][row] += (x2 - float64(end)) * weight
+	}
+}
+
+// coverage rasterizes the filled area of the path into an anti-aliased coverage map for an image of the given size.
+// Only the bounding box of the path, clamped to the image, is allocated and scanned.
+// Every sub-path is treated as closed.
+func (p *Path) coverage(w, h uint, rule FillRule) coverageMap {
+	const samples = 4
+	type edge struct {
+		x1, y1, x2, y2 float64
+		dir            int
+	}
+	edges := make([]edge, 0)
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, s := range p.subpaths {
+		n := len(s.points)
+		for k := 0; k < n; k++ {
+			a, b := s.points[k], s.points[(k+1)%n]
+			if a.y == b.y {
+				continue
+			}
+			if a.y < b.y {
+				edges = append(edges, edge{a.x, a.y, b.x, b.y, 1})
+			} else {
+				edges = append(edges, edge{b.x, b.y, a.x, a.y, -1})
+			}
+			e := edges[len(edges)-1]
+			minX, maxX = math.Min(minX, math.Min(e.x1, e.x2)), math.Max(maxX, math.Max(e.x1, e.x2))
+			minY, maxY = math.Min(minY, e.y1), math.Max(maxY, e.y2)
+		}
+	}
+	// Clamp before converting, so points far outside of the image (or not numbers at all) cannot overflow the bounds.
+	clamp := func(v float64, limit uint) int {
+		if !(v > 0) {
+			return 0
+		}
+		return int(math.Min(v, float64(limit)))
+	}
+	bounds := image.Rect(clamp(math.Floor(minX), w), clamp(math.Floor(minY), h), clamp(math.Ceil(maxX), w), clamp(math.Ceil(maxY), h))
+	m := newCoverageMap(bounds)
+	if bounds.Empty() {
+		return m
+	}
+	type crossing struct {
+		x   float64
+		dir int
+	}
+	sort.Slice(edges, func(a, b int) bool {
+		return edges[a].y1 < edges[b].y1
+	})
+	crossings := make([]crossing, 0)
+	active := make([]edge, 0)
+	next := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for sub := 0; sub < samples; sub++ {
+			sy := float64(y) + (float64(sub)+0.5)/samples
+			for next < len(edges) && edges[next].y1 <= sy {
+				active = append(active, edges[next])
+				next++
+			}
+			crossings = crossings[:0]
+			kept := active[:0]
+			for _, e := range active {
+				if sy >= e.y2 {
+					continue
+				}
+				kept = append(kept, e)
+				t := (sy - e.y1) / (e.y2 - e.y1)
+				crossings = append(crossings, crossing{e.x1 + t*(e.x2-e.x1), e.dir})
+			}
+			active = kept
+			sort.Slice(crossings, func(a, b int) bool {
+				return crossings[a].x < crossings[b].x
+			})
+			winding := 0
+			for k := 0; k+1 < len(crossings); k++ {
+				winding += crossings[k].dir
+				inside := winding != 0
+				if rule == EvenOdd {
+					inside = (k+1)%2 == 1
+				}
+				if !inside {
+					continue
+				}
+				m.addSpan(crossings[k].x, crossings[k+1].x, y, 1.0/samples)
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,319 @@
+package picrocess
+
+import "math"
+
+// sampleWrap returns the bilinearly interpolated color at the floating-point position (x, y),
+// wrapping coordinates around the image edges so the image repeats infinitely.
+func (i *Image) sampleWrap(x, y float64) RGBA {
+	if i.Width == 0 || i.Height == 0 {
+		return RGBA{}
+	}
+	x -= 0.5
+	y -= 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	wrap := func(v float64, size uint) uint {
+		m := math.Mod(v, float64(size))
+		if m < 0 {
+			m += float64(size)
+		}
+		return uint(m) % size
+	}
+	ax, bx := wrap(x0, i.Width), wrap(x0+1, i.Width)
+	ay, by := wrap(y0, i.Height), wrap(y0+1, i.Height)
+	top := lerpRGBA(i.Pixel[ax][ay], i.Pixel[bx][ay], fx)
+	bottom := lerpRGBA(i.Pixel[ax][by], i.Pixel[bx][by], fx)
+	return lerpRGBA(top, bottom, fy)
+}
+
+// FillPattern fills the rectangular region (r) with a repeating pattern image.
+// The pattern is tiled from the origin of the image and can be scaled, rotated, or moved with the transform.
+//
+// r: The rectangle defining the region to fill.
+// pattern: The image to repeat across the region.
+// t: The transformation applied to the pattern (use NewTransform() to tile it as-is).
+// opts: Optional settings; WithBlendMode sets how the pattern is blended with the image.
+func (i *Image) FillPattern(r Rect, pattern *Image, t Transform, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillPattern(r, pattern, t)
+	}) {
+		return
+	}
+	if pattern == nil || pattern.Width == 0 || pattern.Height == 0 {
+		return
+	}
+	inverse := t.Invert()
+	for x := r.W1; x < r.W2 && x < i.Width; x++ {
+		for y := r.H1; y < r.H2 && y < i.Height; y++ {
+			px, py := inverse.Apply(float64(x)+0.5, float64(y)+0.5)
+			i.Set(x, y, pattern.sampleWrap(px, py).over(i.Pixel[x][y]))
+		}
+	}
+}
+
+// FillPatternMask fills the image with a repeating pattern image, clipped to the silhouette of a mask.
+// The alpha channel of the mask decides how much of the pattern shows through at every pixel, so a mask
+// rendered from text or shapes produces a pattern-filled glyph or shape.
+//
+// mask: The image whose alpha channel defines the fill area. It is aligned with the top left corner of the image.
+// pattern: The image to repeat across the fill area.
+// t: The transformation applied to the pattern (use NewTransform() to tile it as-is).
+// opts: Optional settings; WithBlendMode sets how the pattern is blended with the image.
+func (i *Image) FillPatternMask(mask *Image, pattern *Image, t Transform, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillPatternMask(mask, pattern, t)
+	}) {
+		return
+	}
+	if mask == nil || pattern == nil || pattern.Width == 0 || pattern.Height == 0 {
+		return
+	}
+	inverse := t.Invert()
+	for x := uint(0); x < i.Width && x < mask.Width; x++ {
+		for y := uint(0); y < i.Height && y < mask.Height; y++ {
+			coverage := mask.Pixel[x][y].A
+			if coverage == 0 {
+				continue
+			}
+			px, py := inverse.Apply(float64(x)+0.5, float64(y)+0.5)
+			c := pattern.sampleWrap(px, py)
+			c.A = uint8(uint(c.A) * uint(coverage) / 255)
+			i.Set(x, y, c.over(i.Pixel[x][y]))
+		}
+	}
+}
+
+// Pattern is a fill that can vary across the image, such as a hatch, stripes, or a texture,
+// so shapes can be told apart without relying on color alone. RGBA is a Pattern of a single color.
+type Pattern interface {
+	// ColorAt returns the color of the pattern at the point (x, y), in image coordinates.
+	ColorAt(x, y float64) RGBA
+}
+
+// ColorAt returns the color itself, so a flat color can be used wherever a Pattern is expected.
+func (c RGBA) ColorAt(x, y float64) RGBA {
+	return c
+}
+
+type HatchKind int
+
+const (
+	// HatchHorizontal draws horizontal lines.
+	HatchHorizontal HatchKind = iota
+	// HatchVertical draws vertical lines.
+	HatchVertical
+	// HatchDiagonal draws lines rising from left to right (/).
+	HatchDiagonal
+	// HatchBackDiagonal draws lines falling from left to right (\).
+	HatchBackDiagonal
+	// HatchCross draws horizontal and vertical lines.
+	HatchCross
+	// HatchDiagonalCross draws lines in both diagonal directions.
+	HatchDiagonalCross
+	// HatchDots draws a grid of round dots.
+	HatchDots
+)
+
+type hatchPattern struct {
+	kind               HatchKind
+	fg, bg             RGBA
+	spacing, thickness float64
+}
+
+// lineCoverage returns how much of a pixel at the position v across a set of lines, repeating every spacing, is covered.
+func (h hatchPattern) lineCoverage(v float64) float64 {
+	d := math.Abs(v - h.spacing*math.Round(v/h.spacing))
+	return math.Max(0, math.Min(1, h.thickness/2-d+0.5))
+}
+
+func (h hatchPattern) ColorAt(x, y float64) RGBA {
+	var coverage float64
+	switch h.kind {
+	case HatchHorizontal:
+		coverage = h.lineCoverage(y)
+	case HatchVertical:
+		coverage = h.lineCoverage(x)
+	case HatchDiagonal:
+		coverage = h.lineCoverage((x + y) / math.Sqrt2)
+	case HatchBackDiagonal:
+		coverage = h.lineCoverage((x - y) / math.Sqrt2)
+	case HatchCross:
+		coverage = math.Max(h.lineCoverage(x), h.lineCoverage(y))
+	case HatchDiagonalCross:
+		coverage = math.Max(h.lineCoverage((x+y)/math.Sqrt2), h.lineCoverage((x-y)/math.Sqrt2))
+	case HatchDots:
+		dx := x - h.spacing*math.Round(x/h.spacing)
+		dy := y - h.spacing*math.Round(y/h.spacing)
+		coverage = math.Max(0, math.Min(1, h.thickness/2-math.Hypot(dx, dy)+0.5))
+	}
+	return lerpRGBA(h.bg, h.fg, coverage)
+}
+
+// NewHatch creates a hatch pattern of anti-aliased lines or dots, such as for telling chart series apart in print.
+//
+// kind: The kind of hatch, such as HatchDiagonal or HatchCross.
+// fg: The color (RGBA) of the lines.
+// bg: The color (RGBA) between the lines. Use a transparent color to hatch over what is already drawn.
+// spacing: The distance between lines, in pixels.
+// thickness: The thickness of the lines, or the diameter of the dots, in pixels.
+//
+// Returns: The hatch pattern.
+func NewHatch(kind HatchKind, fg, bg RGBA, spacing, thickness float64) Pattern {
+	return hatchPattern{kind: kind, fg: fg, bg: bg, spacing: math.Max(spacing, 1), thickness: thickness}
+}
+
+type stripePattern struct {
+	a, b     RGBA
+	width    float64
+	cos, sin float64
+}
+
+func (s stripePattern) ColorAt(x, y float64) RGBA {
+	// Measure across the stripes and blend over the last pixel before every border.
+	v := (x*s.cos + y*s.sin) / s.width
+	band := math.Floor(v)
+	t := math.Min(1, (v-band)*s.width)
+	first, second := s.a, s.b
+	if int64(band)%2 != 0 {
+		first, second = second, first
+	}
+	return lerpRGBA(second, first, t)
+}
+
+// NewStripes creates a pattern of alternating stripes of two colors, with anti-aliased borders.
+//
+// a: The color (RGBA) of the first stripe.
+// b: The color (RGBA) of the second stripe.
+// width: The width of every stripe, in pixels.
+// angleDeg: The angle of the stripes in degrees, clockwise from vertical; 0 gives vertical stripes and 90 horizontal ones.
+//
+// Returns: The stripe pattern.
+func NewStripes(a, b RGBA, width, angleDeg float64) Pattern {
+	rad := angleDeg * math.Pi / 180
+	return stripePattern{a: a, b: b, width: math.Max(width, 1), cos: math.Cos(rad), sin: math.Sin(rad)}
+}
+
+type checkerPattern struct {
+	a, b RGBA
+	size float64
+}
+
+func (c checkerPattern) ColorAt(x, y float64) RGBA {
+	if (int64(math.Floor(x/c.size))+int64(math.Floor(y/c.size)))%2 == 0 {
+		return c.a
+	}
+	return c.b
+}
+
+// NewCheckerboard creates a checkerboard pattern of two colors, such as for showing transparency.
+//
+// a: The color (RGBA) of the square at the top left corner.
+// b: The color (RGBA) of the other squares.
+// size: The width and height of every square, in pixels.
+//
+// Returns: The checkerboard pattern.
+func NewCheckerboard(a, b RGBA, size float64) Pattern {
+	return checkerPattern{a: a, b: b, size: math.Max(size, 1)}
+}
+
+type linearGradient struct {
+	stops    []GradientStop
+	x, y     float64
+	dx, dy   float64
+	lengthSq float64
+}
+
+func (g linearGradient) ColorAt(x, y float64) RGBA {
+	if g.lengthSq == 0 {
+		return gradientAt(g.stops, 0)
+	}
+	return gradientAt(g.stops, ((x-g.x)*g.dx+(y-g.y)*g.dy)/g.lengthSq)
+}
+
+// NewLinearGradient creates a pattern that blends between colors along the line from one point to another,
+// such as for gold or chrome text. Beyond the ends of the line, the colors of the first and last stops continue.
+//
+// from: The point where the gradient starts, in image coordinates.
+// to: The point where the gradient ends, in image coordinates.
+// stops: The colors of the gradient, sorted by position.
+//
+// Returns: The gradient pattern.
+func NewLinearGradient(from, to OffsetF, stops ...GradientStop) Pattern {
+	dx, dy := to.W-from.W, to.H-from.H
+	return linearGradient{stops: stops, x: from.W, y: from.H, dx: dx, dy: dy, lengthSq: dx*dx + dy*dy}
+}
+
+type imagePattern struct {
+	img     *Image
+	inverse Transform
+}
+
+func (p imagePattern) ColorAt(x, y float64) RGBA {
+	px, py := p.inverse.Apply(x, y)
+	return p.img.sampleWrap(px, py)
+}
+
+// NewImagePattern creates a pattern that repeats an image as a texture, like FillPattern.
+//
+// img: The image to repeat. It is tiled from the origin of the image being drawn on.
+// t: The transformation applied to the texture (use NewTransform() to tile it as-is).
+//
+// Returns: The texture pattern.
+func NewImagePattern(img *Image, t Transform) Pattern {
+	if img == nil || img.Width == 0 || img.Height == 0 {
+		return RGBA{}
+	}
+	return imagePattern{img: img, inverse: t.Invert()}
+}
+
+// fillCoveragePattern blends the pattern over the pixels of the coverage map, weighted by their coverage (0 to 1).
+func (i *Image) fillCoveragePattern(m coverageMap, p Pattern) {
+	for x := m.bounds.Min.X; x < m.bounds.Max.X; x++ {
+		for y := m.bounds.Min.Y; y < m.bounds.Max.Y; y++ {
+			v := m.at(x, y)
+			if v <= 0 {
+				continue
+			}
+			src := p.ColorAt(float64(x)+0.5, float64(y)+0.5)
+			src.A = clampUint8(float64(src.A) * v)
+			i.Set(uint(x), uint(y), src.over(i.Pixel[x][y]))
+		}
+	}
+}
+
+// FillPathPattern fills the area enclosed by the path with a pattern, with anti-aliased edges.
+// Any shape can be filled this way by building it with Path, such as Path.RoundedRect or Path.Ellipse.
+//
+// path: The Path to fill, in image coordinates.
+// p: The Pattern to fill with, such as NewHatch(HatchDiagonal, ...) or a plain RGBA.
+// rule: The fill rule (NonZero or EvenOdd) deciding which overlapping areas are inside.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillPathPattern(path *Path, p Pattern, rule FillRule, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillPathPattern(path, p, rule)
+	}) {
+		return
+	}
+	if t, ok := i.activeTransform(); ok {
+		path = path.transformed(t)
+	}
+	i.fillCoveragePattern(path.coverage(i.Width, i.Height, rule), p)
+}
+
+// FillRectPattern fills the rectangular region (r) with a pattern, such as a bar of a bar chart.
+//
+// r: The rectangle to fill. Its edges are corners between pixels.
+// p: The Pattern to fill with.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillRectPattern(r Rect, p Pattern, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillRectPattern(r, p)
+	}) {
+		return
+	}
+	for x := r.W1; x < r.W2 && x < i.Width; x++ {
+		for y := r.H1; y < r.H2 && y < i.Height; y++ {
+			i.Set(x, y, p.ColorAt(float64(x)+0.5, float64(y)+0.5).over(i.Pixel[x][y]))
+		}
+	}
+}
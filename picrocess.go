@@ -2,21 +2,21 @@ package picrocess
 
 import (
 	"bytes"
+	"context"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/gif"
-	"image/jpeg"
-	"image/png"
+	"io/fs"
 	"math"
-	"net/http"
 	"os"
+	"strings"
 
 	_ "golang.org/x/image/webp"
 
-	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
 	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -66,6 +66,29 @@ func (c RGBA) Brightness() int {
 	return brightness
 }
 
+// over composites the color on top of the destination color (dst) using source-over alpha blending.
+//
+// dst: The color underneath.
+//
+// Returns: The resulting blended color.
+func (c RGBA) over(dst RGBA) RGBA {
+	if c.A == 255 || dst.A == 0 {
+		return c
+	}
+	if c.A == 0 {
+		return dst
+	}
+	sa := float64(c.A) / 255
+	da := float64(dst.A) / 255 * (1 - sa)
+	outA := sa + da
+	return RGBA{
+		R: clampUint8((float64(c.R)*sa + float64(dst.R)*da) / outA),
+		G: clampUint8((float64(c.G)*sa + float64(dst.G)*da) / outA),
+		B: clampUint8((float64(c.B)*sa + float64(dst.B)*da) / outA),
+		A: clampUint8(outA * 255),
+	}
+}
+
 type Rect struct {
 	W1, H1, W2, H2 uint
 }
@@ -91,16 +114,22 @@ func NewRect(w1, h1, w2, h2 uint) Rect {
 // Dx returns the horizontal distance (width) between the two points of the Rect.
 // It calculates the difference between the second width (W2) and the first width (W1).
 //
-// Returns: The horizontal distance between W2 and W1.
+// Returns: The horizontal distance between W2 and W1, or 0 if W2 is smaller than W1.
 func (r *Rect) Dx() uint {
+	if r.W2 < r.W1 {
+		return 0
+	}
 	return r.W2 - r.W1
 }
 
 // Dy returns the vertical distance (height) between the two points of the Rect.
 // It calculates the difference between the second height (H2) and the first height (H1).
 //
-// Returns: The vertical distance between H2 and H1.
+// Returns: The vertical distance between H2 and H1, or 0 if H2 is smaller than H1.
 func (r *Rect) Dy() uint {
+	if r.H2 < r.H1 {
+		return 0
+	}
 	return r.H2 - r.H1
 }
 
@@ -125,6 +154,14 @@ func NewOffset(w, h uint) Offset {
 
 type Font struct {
 	face *truetype.Font
+	// otf is the font parsed by the OpenType parser, for fonts that freetype cannot parse; face is nil then.
+	otf *sfnt.Font
+	// emoji holds the images drawn for emoji instead of glyphs, if any.
+	emoji *EmojiSet
+	// fallbacks are the fonts that draw the characters the font has no glyphs for, in order of preference.
+	fallbacks []*Font
+	// cache keeps the faces of the font by size; copies of the font made with WithEmoji or WithFallback share it.
+	cache *faceCache
 }
 
 // LoadFont loads a font from the specified file and returns a pointer to a Font struct.
@@ -138,37 +175,63 @@ func LoadFont(filename string) (*Font, error) {
 	if err != nil {
 		return nil, err
 	}
-	fontFace, err := freetype.ParseFont(fontBytes)
+	return LoadFontBytes(fontBytes)
+}
+
+// LoadFontBytes parses a font from the contents of a TrueType (.ttf) or OpenType (.otf) font file, such as one
+// embedded with go:embed or fetched over the network, without writing it to a temporary file.
+// Variable fonts are drawn in their default instance, since selecting other instances of their axes is not supported.
+//
+// data: The contents of the font file.
+//
+// Returns: A pointer to a Font struct containing the parsed font, or an error if the data is not a valid font.
+func LoadFontBytes(data []byte) (*Font, error) {
+	return parseFont(data)
+}
+
+// LoadFontFS loads a font from a file system, such as an embed.FS holding fonts embedded with go:embed.
+//
+// fsys: The file system to read the font from.
+// name: The path of the font file within the file system, such as "fonts/Inter.ttf".
+//
+// Returns: A pointer to a Font struct containing the parsed font, or an error if any issue occurs.
+func LoadFontFS(fsys fs.FS, name string) (*Font, error) {
+	fontBytes, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, err
 	}
-	return &Font{face: fontFace}, nil
+	return LoadFontBytes(fontBytes)
 }
 
 // TextSize calculates the width and height of the given text when rendered with the specified font size.
-// It returns the width and height of the text in pixels.
+// It returns the width and height of the text in pixels: the advance width including kerning, and the height
+// from the top of the text down to the bottom of its descenders. Text with line breaks ("\n") is measured
+// as a block of lines: the width of the widest line, and the height of all lines. MeasureText returns
+// the metrics in more detail.
 //
 // size: The font size to use for rendering the text.
 // text: The text to measure.
+// opts: Optional settings; WithLineHeight sets the distance between lines, as for Text.
 //
 // Returns: The width and height of the text in pixels.
-func (f *Font) TextSize(size float64, text string) (uint, uint) {
-	var width uint
-	var height uint
-	fontFace := truetype.NewFace(f.face, &truetype.Options{Size: size})
-	for _, c := range text {
-		bounds, advance, _ := fontFace.GlyphBounds(c)
-		width += uint(advance.Ceil())
-		if bounds.Max.Y > fixed.Int26_6(height) {
-			height = uint(bounds.Max.Y.Ceil())
-		}
-	}
-	return width, height
+func (f *Font) TextSize(size float64, text string, opts ...Option) (uint, uint) {
+	m := f.MeasureText(size, text, opts...)
+	return uint(math.Ceil(m.Width)), uint(math.Ceil(m.Height))
+}
+
+// textLines splits text into its lines, accepting both "\n" and "\r\n" line breaks.
+func textLines(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
 }
 
 type Image struct {
 	Width, Height uint
 	Pixel         [][]RGBA // X / Y
+	ColorSpace    ColorSpace
+	clips         []coverageMap
+	transform     *Transform
+	transforms    []*Transform
+	scaleFactor   float64
 }
 
 // NewImage creates a new Image struct with the specified width (w), height (h), and initial color (color).
@@ -194,6 +257,21 @@ func NewImage(w, h uint, color RGBA) *Image {
 	return &respond
 }
 
+// clone returns a deep copy of the image pixels, without any clip regions or transformations.
+func (i *Image) clone() *Image {
+	respond := &Image{
+		Width:       i.Width,
+		Height:      i.Height,
+		Pixel:       make([][]RGBA, len(i.Pixel)),
+		ColorSpace:  i.ColorSpace,
+		scaleFactor: i.scaleFactor,
+	}
+	for x := range i.Pixel {
+		respond.Pixel[x] = append([]RGBA(nil), i.Pixel[x]...)
+	}
+	return respond
+}
+
 // LoadImage loads an image from a file, decodes it, and returns an Image struct.
 // It returns an error if the file cannot be opened or the image cannot be decoded.
 //
@@ -201,19 +279,7 @@ func NewImage(w, h uint, color RGBA) *Image {
 //
 // Returns: A pointer to an Image struct containing the decoded image, or an error if any issue occurs.
 func LoadImage(filename string) (*Image, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
-	return Render(rgba), nil
+	return LoadImageContext(context.Background(), filename)
 }
 
 // ImageURL loads an image from a URL, decodes it, and returns an Image struct.
@@ -223,19 +289,7 @@ func LoadImage(filename string) (*Image, error) {
 //
 // Returns: A pointer to an Image struct containing the decoded image, or an error if any issue occurs.
 func ImageURL(url string) (*Image, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	img, _, err := image.Decode(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
-	return Render(rgba), nil
+	return ImageURLContext(context.Background(), url)
 }
 
 // At returns the color of the pixel at the specified coordinates (x, y) in the image.
@@ -246,22 +300,32 @@ func ImageURL(url string) (*Image, error) {
 //
 // Returns: The color of the pixel at the given coordinates.
 func (i *Image) At(x, y uint) RGBA {
-	if x >= i.Width || y >= i.Height {
+	if x >= i.Width || y >= i.Height || x >= uint(len(i.Pixel)) || y >= uint(len(i.Pixel[x])) {
 		return RGBA{0, 0, 0, 0}
 	}
 	return i.Pixel[x][y]
 }
 
 // Set sets the color of the pixel at the specified coordinates (x, y) in the image.
-// If the coordinates are out of bounds, it does nothing.
+// If the coordinates are out of bounds or clipped by PushClip, it does nothing.
+// On the anti-aliased edge of a clip region, the color is blended with the existing pixel.
 //
 // x: The x-coordinate of the pixel.
 // y: The y-coordinate of the pixel.
 // c: The color to set at the given coordinates.
 func (i *Image) Set(x, y uint, c RGBA) {
-	if x >= i.Width || y >= i.Height {
+	if x >= i.Width || y >= i.Height || x >= uint(len(i.Pixel)) || y >= uint(len(i.Pixel[x])) {
 		return
 	}
+	if len(i.clips) > 0 {
+		cov := i.clipAt(x, y)
+		if cov <= 0 {
+			return
+		}
+		if cov < 1 {
+			c = lerpRGBA(i.Pixel[x][y], c, cov)
+		}
+	}
 	i.Pixel[x][y] = c
 }
 
@@ -288,10 +352,20 @@ func (i *Image) To(b, a RGBA) {
 //
 // i2: The image to overlay on top of the current image (i).
 // o: The offset to position the second image on top of the first image (i).
+// opts: Optional settings; WithShadow casts a drop shadow underneath the overlaid image.
 //
 // The function blends the pixels based on the alpha values. It uses the formula for alpha blending
 // when both pixels are partially transparent, while fully opaque pixels are copied directly.
-func (i *Image) Overlay(i2 *Image, o Offset) {
+func (i *Image) Overlay(i2 *Image, o Offset, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.Overlay(i2, o)
+	}) {
+		return
+	}
+	if t, ok := i.activeTransform(); ok {
+		i.drawTransformed(i2, t.Translate(float64(o.W), float64(o.H)))
+		return
+	}
 	for x := range i2.Pixel {
 		for y := range i2.Pixel[x] {
 			pixel := i2.At(uint(x), uint(y))
@@ -327,7 +401,13 @@ func (i *Image) Overlay(i2 *Image, o Offset) {
 //
 // w: The new width of the image.
 // h: The new height of the image.
-func (i *Image) Resize(w, h uint) {
+// opts: Optional settings; WithFilter(FilterBilinear) interpolates between pixels instead.
+func (i *Image) Resize(w, h uint, opts ...Option) {
+	if NewOptions(opts...).Filter == FilterBilinear {
+		resized := i.resampled(w, h)
+		i.Pixel, i.Width, i.Height = resized.Pixel, w, h
+		return
+	}
 	newPixel := make([][]RGBA, w)
 	for x := range newPixel {
 		newPixel[x] = make([]RGBA, h)
@@ -351,9 +431,10 @@ func (i *Image) Resize(w, h uint) {
 // Returns: A new Image struct containing the cropped region.
 func (i *Image) Crop(r Rect) *Image {
 	cropped := &Image{
-		Width:  r.Dx(),
-		Height: r.Dy(),
-		Pixel:  make([][]RGBA, r.Dx()),
+		Width:      r.Dx(),
+		Height:     r.Dy(),
+		Pixel:      make([][]RGBA, r.Dx()),
+		ColorSpace: i.ColorSpace,
 	}
 	for x := range cropped.Pixel {
 		cropped.Pixel[x] = make([]RGBA, r.Dy())
@@ -373,9 +454,10 @@ func (i *Image) Crop(r Rect) *Image {
 //
 // This function modifies the image by setting the pixels outside the rounded area to transparent.
 func (i *Image) Round(px uint) {
+	px = min(px, i.Width/2, i.Height/2)
 	for x := range i.Pixel {
 		for y := range i.Pixel[x] {
-			if uint(x) >= px && uint(x) <= i.Width-px || uint(y) >= px && uint(y) <= i.Width-px {
+			if uint(x) >= px && uint(x) <= i.Width-px || uint(y) >= px && uint(y) <= i.Height-px {
 				continue
 			}
 			var dx float64
@@ -383,7 +465,7 @@ func (i *Image) Round(px uint) {
 			if uint(x) <= px && uint(y) <= px {
 				dx = float64(px)
 				dy = float64(px)
-			} else if uint(x) <= px && uint(y) > i.Width-px {
+			} else if uint(x) <= px && uint(y) > i.Height-px {
 				dx = float64(px)
 				dy = float64(i.Height - px)
 			} else if uint(x) >= i.Width-px && uint(y) <= px {
@@ -412,7 +494,7 @@ func (i *Image) Rotate90() {
 	for x := range i.Pixel {
 		for y := range i.Pixel[x] {
 			pixel := i.At(uint(x), uint(y))
-			newPixel[y][i.Height-1-uint(x)] = pixel
+			newPixel[y][i.Width-1-uint(x)] = pixel
 		}
 	}
 	i.Pixel = newPixel
@@ -471,31 +553,122 @@ func (i *Image) FlipVertical() {
 // o: The offset specifying where to draw the text on the image.
 // size: The font size to use for rendering the text.
 // text: The string of text to be drawn on the image.
+// opts: Optional settings; WithAnchor places the given point of the text at the offset instead of its top-left corner,
+// WithLineHeight sets the distance between lines, WithTextOutline outlines the glyphs,
+// WithTextFill or WithTextGradient fills them with a pattern or gradient instead of the color,
+// WithBackdrop or WithScrim draws a backdrop behind the text, WithHighlight a box behind every line,
+// WithBlendMode sets how the text is blended with the image, and WithShadow casts a drop shadow underneath it.
+//
+// Text with line breaks ("\n") is drawn as a block of lines. The anchor places the block, and also aligns the lines
+// within it: AnchorTop and AnchorCenter center every line, and AnchorRight right-aligns them.
 //
 // Returns: An error if there is an issue rendering the text.
-func (i *Image) Text(font *Font, c RGBA, o Offset, size float64, text string) error {
-	img := i.Render()
-	pt := freetype.Pt(int(o.W), int(o.H)+int(size))
-	ctx := freetype.NewContext()
-	ctx.SetDPI(72)
-	ctx.SetFont(font.face)
-	ctx.SetFontSize(size)
-	ctx.SetClip(img.Bounds())
-	ctx.SetDst(img)
-	ctx.SetSrc(&image.Uniform{C: color.RGBA{c.R, c.G, c.B, c.A}})
-	_, err := ctx.DrawString(text, pt)
-	if err != nil {
+func (i *Image) Text(font *Font, c RGBA, o Offset, size float64, text string, opts ...Option) error {
+	var layerErr error
+	if i.layered(opts, func(layer *Image) {
+		if err := layer.Text(font, c, o, size, text, unlayered(opts)...); err != nil {
+			layerErr = err
+		}
+	}) || layerErr != nil {
+		return layerErr
+	}
+	options := NewOptions(opts...)
+	x, y := font.textOrigin(o, size, text, options, opts)
+	lines := textLines(text)
+	step := size * options.LineHeight
+	fx, _ := options.Anchor.fractions()
+	if options.backdrop != nil {
+		i.drawBackdrop(options.backdrop, font, size, text, step, x, y, fx)
+	}
+	if options.textOutline == nil && options.textFill == nil {
+		return i.drawText(font, c, x, y, size, lines, step, fx)
+	}
+	// Fill and outline the glyphs on a layer of their own, so the outline goes around the text and not the backdrop.
+	layer := i.newLayer()
+	if err := layer.drawText(font, c, x, y, size, lines, step, fx); err != nil {
 		return err
 	}
-	for x := range i.Pixel {
-		for y := range i.Pixel[x] {
-			r, g, b, a := img.RGBAAt(int(x), int(y)).RGBA()
-			i.Pixel[x][y] = RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}
+	if options.textFill != nil {
+		options.textFill.apply(layer)
+	}
+	if options.textOutline != nil {
+		width := options.textOutline.width
+		if t, ok := i.activeTransform(); ok {
+			width *= math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
 		}
+		layer.Outline(options.textOutline.color, uint(math.Round(width)), OutlineOutside)
 	}
+	i.compositeLayer(layer, BlendNormal)
 	return nil
 }
 
+// drawText draws lines of text whose block has its top-left corner at (x, y) in the current transformation,
+// as drawTextLines does without one.
+func (i *Image) drawText(font *Font, c RGBA, x, y, size float64, lines []string, step, align float64) error {
+	if t, ok := i.activeTransform(); ok {
+		// Render the glyphs at the scaled size so they stay sharp, then map the layer into place.
+		scale := math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
+		if scale == 0 {
+			return nil
+		}
+		width, _ := font.TextSize(size*scale, strings.Join(lines, "\n"))
+		layer := NewImage(width+uint(size*scale), uint(size*scale*1.5+step*scale*float64(len(lines)-1)), RGBA{})
+		if err := layer.drawTextLines(font, c, 0, 0, size*scale, lines, step*scale, align); err != nil {
+			return err
+		}
+		i.drawTransformed(layer, t.Translate(x, y).Scale(1/scale, 1/scale))
+		return nil
+	}
+	return i.drawTextLines(font, c, x, y, size, lines, step, align)
+}
+
+// drawTextLines draws lines of text whose block has its top-left corner at (x, y), without any transformation.
+// Every line is step pixels below the previous one and is aligned within the block by align, from 0 (left) to 1 (right).
+func (i *Image) drawTextLines(font *Font, c RGBA, x, y, size float64, lines []string, step, align float64) error {
+	src := image.NewUniform(color.RGBA{c.R, c.G, c.B, c.A})
+	width, _ := font.TextSize(size, strings.Join(lines, "\n"))
+	// Only render the part of the image around the text, with a margin of a font size for descenders and
+	// for glyphs that reach beyond their advance, such as italics.
+	margin := int(math.Ceil(size))
+	bottom := int(math.Ceil(y+size+float64(len(lines)-1)*step)) + margin
+	img := i.renderRect(image.Rect(int(x)-margin, int(y)-margin, int(x)+int(width)+margin, bottom))
+	if img.Rect.Empty() {
+		return nil
+	}
+	for k, line := range lines {
+		lineWidth, _ := font.TextSize(size, line)
+		lx := x + math.Round(float64(width-lineWidth)*align)
+		if err := font.drawLine(img, src, size, fixed.P(int(lx), int(y+float64(k)*step)+int(size)), line); err != nil {
+			return err
+		}
+	}
+	i.setRGBA(img)
+	return nil
+}
+
+// renderRect converts the part of the image within the rectangle to an image.RGBA object, as Render does
+// for the whole image. The rectangle is clipped to the image, and the image.RGBA keeps its coordinates.
+func (i *Image) renderRect(r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(r.Intersect(image.Rect(0, 0, int(i.Width), int(i.Height))))
+	for x := img.Rect.Min.X; x < img.Rect.Max.X && x < len(i.Pixel); x++ {
+		for y := img.Rect.Min.Y; y < img.Rect.Max.Y && y < len(i.Pixel[x]); y++ {
+			pixel := i.Pixel[x][y]
+			img.SetRGBA(x, y, color.RGBA{pixel.R, pixel.G, pixel.B, pixel.A})
+		}
+	}
+	return img
+}
+
+// setRGBA copies the pixels of an image.RGBA made with renderRect back into the image, at their coordinates.
+func (i *Image) setRGBA(img *image.RGBA) {
+	for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+		for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+			c := img.RGBAAt(x, y)
+			i.Set(uint(x), uint(y), RGBA{R: c.R, G: c.G, B: c.B, A: c.A})
+		}
+	}
+}
+
 func pointToLineDistance(x1, y1, x2, y2, px, py float64) float64 {
 	vx, vy := x2-x1, y2-y1
 	lenSq := vx*vx + vy*vy
@@ -517,39 +690,81 @@ func pointToLineDistance(x1, y1, x2, y2, px, py float64) float64 {
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
-// applyAntialiasing applies antialiasing to the line drawing process by blending colors
-// for pixels near the line edges based on their distance from the line.
+// applyAntialiasing applies antialiasing to the line drawing process by blending the line color
+// into pixels on the line edges, based on how much of the pixel the line covers.
 func (i *Image) applyAntialiasing(x, y uint, c RGBA, distance, thickness float64) {
-	if distance <= thickness/2 {
-		alpha := uint8(255 * (1 - distance/(thickness/2)))
-		original := i.At(x, y)
-		blended := RGBA{
-			R: uint8((float64(original.R)*(255-float64(alpha)) + float64(c.R)*float64(alpha)) / 255),
-			G: uint8((float64(original.G)*(255-float64(alpha)) + float64(c.G)*float64(alpha)) / 255),
-			B: uint8((float64(original.B)*(255-float64(alpha)) + float64(c.B)*float64(alpha)) / 255),
-			A: uint8(math.Max(float64(original.A), float64(alpha))),
-		}
-		i.Set(x, y, blended)
+	coverage := math.Max(0, math.Min(1, thickness/2-distance+0.5))
+	if coverage <= 0 {
+		return
 	}
+	alpha := uint8(math.Round(255 * coverage))
+	original := i.At(x, y)
+	blended := RGBA{
+		R: uint8((float64(original.R)*(255-float64(alpha)) + float64(c.R)*float64(alpha)) / 255),
+		G: uint8((float64(original.G)*(255-float64(alpha)) + float64(c.G)*float64(alpha)) / 255),
+		B: uint8((float64(original.B)*(255-float64(alpha)) + float64(c.B)*float64(alpha)) / 255),
+		A: uint8(math.Max(float64(original.A), float64(alpha))),
+	}
+	i.Set(x, y, blended)
 }
 
 // Line draws a line on the image from point (r.W1, r.H1) to point (r.W2, r.H2) with the specified color (c)
-// and thickness. It iterates over the pixels of the image and sets the pixel color to the specified color
-// if the pixel is within the thickness of the line.
+// and thickness. Only the pixels near the line are visited, so the cost grows with the length of the line
+// rather than the size of the image. With antialiasing, the edges of the line are blended by coverage.
 //
 // r: The rectangle defining the start and end points of the line (W1, H1) to (W2, H2).
 // c: The color (RGBA) to use for the line.
 // thickness: The thickness of the line.
-func (i *Image) Line(r Rect, c RGBA, thickness float64, antialiasing bool) {
-	for x := range i.Pixel {
-		for y := range i.Pixel[x] {
-			distance := pointToLineDistance(float64(r.W1), float64(r.H1), float64(r.W2), float64(r.H2), float64(x), float64(y))
-			if distance <= thickness/2 {
-				if antialiasing {
-					i.applyAntialiasing(uint(x), uint(y), c, distance, thickness)
-				} else {
-					i.Set(uint(x), uint(y), c)
-				}
+// antialiasing: Whether to smooth the edges of the line.
+// opts: Optional settings; WithBlendMode sets how the line is blended with the image, and WithShadow casts a drop shadow underneath it.
+func (i *Image) Line(r Rect, c RGBA, thickness float64, antialiasing bool, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.Line(r, c, thickness, antialiasing)
+	}) {
+		return
+	}
+	x1, y1 := float64(r.W1), float64(r.H1)
+	x2, y2 := float64(r.W2), float64(r.H2)
+	if t, ok := i.activeTransform(); ok {
+		x1, y1 = t.Apply(x1, y1)
+		x2, y2 = t.Apply(x2, y2)
+		thickness *= math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
+	}
+	reach := thickness / 2
+	if antialiasing {
+		reach += 0.5
+	}
+	// Walk along the major axis and only test the pixels across the line at every step.
+	steep := math.Abs(y2-y1) > math.Abs(x2-x1)
+	if steep {
+		x1, y1, x2, y2 = y1, x1, y2, x2
+	}
+	if x1 > x2 {
+		x1, y1, x2, y2 = x2, y2, x1, y1
+	}
+	slope := 0.0
+	if x2 != x1 {
+		slope = (y2 - y1) / (x2 - x1)
+	}
+	across := reach*math.Sqrt(1+slope*slope) + 1
+	major, minor := float64(i.Width), float64(i.Height)
+	if steep {
+		major, minor = minor, major
+	}
+	for u := math.Max(0, math.Floor(x1-reach)); u <= math.Min(major-1, math.Ceil(x2+reach)); u++ {
+		center := y1 + (math.Max(x1, math.Min(x2, u))-x1)*slope
+		for v := math.Max(0, math.Floor(center-across)); v <= math.Min(minor-1, math.Ceil(center+across)); v++ {
+			x, y := u, v
+			px1, py1, px2, py2 := x1, y1, x2, y2
+			if steep {
+				x, y = v, u
+				px1, py1, px2, py2 = y1, x1, y2, x2
+			}
+			distance := pointToLineDistance(px1, py1, px2, py2, x, y)
+			if antialiasing {
+				i.applyAntialiasing(uint(x), uint(y), c, distance, thickness)
+			} else if distance <= thickness/2 {
+				i.Set(uint(x), uint(y), c)
 			}
 		}
 	}
@@ -650,9 +865,8 @@ func (i *Image) ToJPGByte(quality int) ([]byte, error) {
 
 // ToPNGBuffer converts the Image to a PNG format and returns a bytes.Buffer.
 func (i *Image) ToPNGBuffer() (*bytes.Buffer, error) {
-	img := i.Render()
 	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
+	if err := i.encodePNG(&buf, currentRenderer()); err != nil {
 		return nil, err
 	}
 	return &buf, nil
@@ -660,10 +874,8 @@ func (i *Image) ToPNGBuffer() (*bytes.Buffer, error) {
 
 // ToJPGBuffer converts the Image to a JPG format with the specified quality and returns a bytes.Buffer.
 func (i *Image) ToJPGBuffer(quality int) (*bytes.Buffer, error) {
-	img := i.Render()
 	var buf bytes.Buffer
-	opt := &jpeg.Options{Quality: quality}
-	if err := jpeg.Encode(&buf, img, opt); err != nil {
+	if err := i.encodeJPEG(&buf, quality, currentRenderer()); err != nil {
 		return nil, err
 	}
 	return &buf, nil
@@ -671,34 +883,21 @@ func (i *Image) ToJPGBuffer(quality int) (*bytes.Buffer, error) {
 
 // SaveAsPNG saves the Image as a PNG file to the specified path.
 func (i *Image) SaveAsPNG(filename string) error {
-	img := i.Render()
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	err = png.Encode(file, img)
-	if err != nil {
-		return err
-	}
-	return nil
+	return i.SaveAsPNGContext(context.Background(), filename)
 }
 
 // SaveAsJPG saves the Image as a JPG file to the specified path with the specified quality.
 func (i *Image) SaveAsJPG(filename string, quality int) error {
-	img := i.Render()
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	opt := &jpeg.Options{Quality: quality}
-	return jpeg.Encode(file, img, opt)
+	return i.SaveAsJPGContext(context.Background(), filename, quality)
 }
 
 type GIF struct {
 	Delay []int
 	Image []*image.RGBA
+	// Disposal is what happens to every frame before the next one is drawn; missing entries are DisposalAuto.
+	Disposal []Disposal
+	// LoopCount is how many times the animation repeats: 0 loops forever, -1 plays it once.
+	LoopCount int
 }
 
 // NewGIF creates and returns a new GIF object.
@@ -710,14 +909,39 @@ func NewGIF() *GIF {
 }
 
 // Append adds a new frame (image) to the GIF with a specified delay.
-func (gf *GIF) Append(image *Image, delay int) {
+// The frame is rendered with the Renderer set with SetRenderer, or the one given with WithRenderer.
+// WithDisposal sets what happens to the frame before the next one is drawn, and WithTransparentColor
+// makes the pixels of a color transparent, so the frames before it show through.
+//
+// Returns: An error if the Renderer fails to render the frame, in which case no frame is added.
+func (gf *GIF) Append(image *Image, delay int, opts ...Option) error {
+	options := NewOptions(opts...)
+	frame, err := image.renderRGBA(options.activeRenderer())
+	if err != nil {
+		return err
+	}
+	if options.transparent != nil {
+		clearColor(frame, *options.transparent)
+	}
+	for len(gf.Disposal) < len(gf.Image) {
+		gf.Disposal = append(gf.Disposal, DisposalAuto)
+	}
 	gf.Delay = append(gf.Delay, delay)
-	gf.Image = append(gf.Image, image.Render())
+	gf.Image = append(gf.Image, frame)
+	gf.Disposal = append(gf.Disposal, options.disposal)
+	return nil
+}
+
+// SetLoopCount sets how many times the animation repeats after it is first played.
+//
+// n: The number of repeats; 0 loops forever and -1 plays the animation only once.
+func (gf *GIF) SetLoopCount(n int) {
+	gf.LoopCount = max(n, -1)
 }
 
 // ToGIFByte converts the GIF object to a byte slice in GIF format.
-func (i *GIF) ToGIFByte() ([]byte, error) {
-	buffer, err := i.ToGIFBuffer()
+func (i *GIF) ToGIFByte(opts ...GIFOption) ([]byte, error) {
+	buffer, err := i.ToGIFBuffer(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -725,20 +949,85 @@ func (i *GIF) ToGIFByte() ([]byte, error) {
 }
 
 // ToGIFBuffer converts the GIF object into a bytes buffer containing the GIF data.
-func (gf *GIF) ToGIFBuffer() (*bytes.Buffer, error) {
+func (gf *GIF) ToGIFBuffer(opts ...GIFOption) (*bytes.Buffer, error) {
+	return gf.encode(NewOptions(opts...))
+}
+
+// encode encodes the GIF with the collected options.
+func (gf *GIF) encode(options Options) (*bytes.Buffer, error) {
+	return gf.encodeContext(context.Background(), options)
+}
+
+// encodeContext encodes the GIF with the collected options, stopping between frames once the context is done.
+func (gf *GIF) encodeContext(ctx context.Context, options Options) (*bytes.Buffer, error) {
 	var buf bytes.Buffer
-	gifImages := make([]*image.Paletted, len(gf.Image))
-	disposal := make([]byte, len(gf.Image))
+	gifImages := make([]*image.Paletted, 0, len(gf.Image))
+	delays := make([]int, 0, len(gf.Image))
+	disposal := make([]byte, 0, len(gf.Image))
+	optimize := options.optimize && framesOpaque(gf.Image)
+	colors := options.colors
+	if colors == 0 {
+		colors = 256
+	}
+	palette := options.palette
+	if palette == nil && options.globalPalette {
+		palette = newFixedPalette(quantizeFrames(gf.Image, colors, optimize))
+	}
 	for i, img := range gf.Image {
-		gifImages[i] = image.NewPaletted(img.Bounds(), Palette(img, 256*256*256))
-		draw.Draw(gifImages[i], img.Bounds(), img, image.Point{}, draw.Src)
-		disposal[i] = gif.DisposalBackground
-	}
-	err := gif.EncodeAll(&buf, &gif.GIF{
-		Image:    gifImages,
-		Delay:    gf.Delay,
-		Disposal: disposal,
-	})
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		delay := 0
+		if i < len(gf.Delay) {
+			delay = gf.Delay[i]
+		}
+		if optimize && i > 0 && keepsFrame(gf.disposal(i-1)) {
+			frame, ok := diffFrame(gf.Image[i-1], img, palette == nil || palette.transparent())
+			if !ok {
+				// The frame does not change anything, so the previous frame is shown for longer instead.
+				delays[len(delays)-1] += delay
+				continue
+			}
+			img = frame
+		}
+		var paletted *image.Paletted
+		switch {
+		case palette != nil && options.dither != DitherNone:
+			paletted = ditherPaletted(img, palette.palette, options.dither)
+		case palette != nil:
+			paletted = palette.paletted(img)
+		default:
+			local := Palette(img, colors)
+			if options.dither != DitherNone {
+				paletted = ditherPaletted(img, local, options.dither)
+			} else {
+				paletted = newFixedPalette(local).paletted(img)
+			}
+		}
+		gifImages = append(gifImages, paletted)
+		delays = append(delays, delay)
+		switch d := gf.disposal(i); {
+		case d != DisposalAuto:
+			disposal = append(disposal, byte(d))
+		case optimize:
+			// Later frames only draw what changed on top of this one.
+			disposal = append(disposal, gif.DisposalNone)
+		default:
+			disposal = append(disposal, gif.DisposalBackground)
+		}
+	}
+	encoded := &gif.GIF{
+		Image:     gifImages,
+		Delay:     delays,
+		Disposal:  disposal,
+		LoopCount: gf.LoopCount,
+	}
+	if palette != nil && len(gifImages) > 0 {
+		// Frames that share a palette use it as the global color table instead of repeating it.
+		bounds := gf.bounds()
+		encoded.Config = image.Config{ColorModel: palette.palette, Width: bounds.Max.X, Height: bounds.Max.Y}
+	}
+	err := gif.EncodeAll(&buf, encoded)
 	if err != nil {
 		return nil, err
 	}
@@ -746,47 +1035,28 @@ func (gf *GIF) ToGIFBuffer() (*bytes.Buffer, error) {
 }
 
 // SaveAsGIF saves the GIF data to a file.
-func (i *GIF) SaveAsGIF(filename string) error {
-	data, err := i.ToGIFByte()
-	if err != nil {
-		return err
-	}
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	_, err = file.Write(data)
-	if err != nil {
-		return err
-	}
-	return nil
+func (i *GIF) SaveAsGIF(filename string, opts ...GIFOption) error {
+	return i.SaveAsGIFContext(context.Background(), filename, opts...)
 }
 
 // Palette generates a color palette for the given RGBA frame, with a customizable limit on the number of colors.
-// It extracts unique colors from the image and returns a color.Palette.
-// If the number of colors exceeds the limit, the palette is truncated to the specified limit.
+// Frames with no more colors than the limit get exactly their own colors. Frames with more colors are quantized
+// with the median-cut algorithm, so the palette follows the colors that cover the most pixels instead of
+// dropping colors arbitrarily. Fully transparent pixels keep a transparent color of their own.
+//
+// frame: The frame to generate the palette for.
+// limit: The largest number of colors in the palette, such as 256 for GIFs.
+//
+// Returns: The palette of the frame.
 func Palette(frame *image.RGBA, limit int) color.Palette {
-	colorSet := make(map[color.RGBA]struct{})
-	for y := 0; y < frame.Bounds().Dy(); y++ {
-		for x := 0; x < frame.Bounds().Dx(); x++ {
-			colorSet[frame.RGBAAt(x, y)] = struct{}{}
-		}
-	}
-	var colors []color.Color
-	for c := range colorSet {
-		colors = append(colors, c)
-	}
-	if len(colors) > limit {
-		colors = colors[:limit]
-	}
-	return colors
+	return quantize(frame, limit)
 }
 
 // NewQRCode generates a new QR code image from the given content, with customizable foreground and background colors.
 // It creates a QR code of the specified size and color options, and returns the generated image.
-func NewQRCode(bgColor, fgColor RGBA, size int, content string) (*Image, error) {
-	qr, err := qrcode.New(content, qrcode.High)
+// The error recovery level defaults to QRHigh and can be changed with WithRecovery.
+func NewQRCode(bgColor, fgColor RGBA, size int, content string, opts ...Option) (*Image, error) {
+	qr, err := qrcode.New(content, qrcode.RecoveryLevel(NewOptions(opts...).Recovery))
 	if err != nil {
 		return nil, err
 	}
@@ -865,24 +1135,20 @@ func (g *LineGrape) Render() *Image {
 	base.Line(NewRect(30, 30, 670, 30), NewRGBA(120, 120, 120), 2, false)
 	base.Line(NewRect(30, 470, 670, 470), NewRGBA(120, 120, 120), 2, false)
 	base.Line(NewRect(670, 30, 670, 470), NewRGBA(120, 120, 120), 2, false)
-	lastX := uint(30)
-	lastY := uint(0)
 	for i := uint(0); i < 6; i++ {
 		base.Line(NewRect(30, 440/6*(i+1)+30, 670, 440/6*(i+1)+30), NewRGBA(120, 120, 120), 1, true)
 	}
 	step := float64(640) / float64(len(g.Value))
+	points := make([]Offset, 0, len(g.Value))
 	for i := range g.Value {
 		x := uint(step*float64(i)) + 30
 		y := 500 - (uint((g.Value[i]-min)/(max-min)*440) + 30)
-		if i == 0 {
-			lastY = y
-		}
-		base.Line(NewRect(lastX, lastY, x, y), NewRGBA(255, 0, 0), 3, true)
+		points = append(points, NewOffset(x, y))
 		if i != len(g.Value)-1 {
 			base.Line(NewRect(x, 30, x, 470), NewRGBA(120, 120, 120), 1, false)
 		}
-		lastX = x
-		lastY = y
 	}
+	// Draw the line last and as one stroke, so the grid stays behind it and the corners are joined smoothly.
+	base.DrawPolyline(points, NewRGBA(255, 0, 0), 3, CapRound, JoinRound)
 	return base
 }
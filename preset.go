@@ -0,0 +1,269 @@
+package picrocess
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownPreset is returned (wrapped with the name) when no preset is registered under a name.
+	ErrUnknownPreset = errors.New("picrocess: unknown preset")
+	// ErrUnknownOperation is returned (wrapped with the name) when an operation of a preset is not registered.
+	ErrUnknownOperation = errors.New("picrocess: unknown operation")
+	// ErrInvalidParam is returned (wrapped with the operation and the parameter) when a parameter of an operation
+	// is out of range, such as a negative or infinite size.
+	ErrInvalidParam = errors.New("picrocess: invalid operation parameter")
+)
+
+// Operation is a single serializable processing step of a preset, such as
+// {"op": "resize", "params": {"width": 256, "height": 256}}.
+type Operation struct {
+	Name   string             `json:"op"`
+	Params map[string]float64 `json:"params,omitempty"`
+}
+
+// NewOperation creates an Operation with the given name and parameters.
+//
+// name: The name of a registered operation, such as "resize" or "sepia".
+// params: The parameters of the operation, by name. Missing parameters use their defaults.
+//
+// Returns: A new Operation.
+func NewOperation(name string, params map[string]float64) Operation {
+	return Operation{Name: name, Params: params}
+}
+
+// param returns the named parameter, or def if it is not set.
+func (o Operation) param(name string, def float64) float64 {
+	if v, ok := o.Params[name]; ok {
+		return v
+	}
+	return def
+}
+
+// size returns the named parameter as a size or coordinate in pixels, or def if it is not set.
+// It returns ErrInvalidParam if the parameter is not a number from 0 to math.MaxUint32.
+func (o Operation) size(name string, def uint) (uint, error) {
+	v := o.param(name, float64(def))
+	if math.IsNaN(v) || v < 0 || v > math.MaxUint32 {
+		return 0, fmt.Errorf("%w: %s %s = %v", ErrInvalidParam, o.Name, name, v)
+	}
+	return uint(v), nil
+}
+
+// Preset is a named list of operations that can be shared as JSON.
+type Preset struct {
+	Name       string      `json:"name"`
+	Operations []Operation `json:"operations"`
+}
+
+// OperationFunc applies an operation with its parameters to an image in place.
+type OperationFunc func(i *Image, op Operation) error
+
+var (
+	presetMu   sync.RWMutex
+	presets    = make(map[string]Preset)
+	operations = map[string]OperationFunc{
+		"resize": func(i *Image, op Operation) error {
+			w, err := op.size("width", i.Width)
+			if err != nil {
+				return err
+			}
+			h, err := op.size("height", i.Height)
+			if err != nil {
+				return err
+			}
+			if err := checkPixels(int(w), int(h)); err != nil {
+				return err
+			}
+			i.Resize(w, h, WithFilter(ResizeFilter(op.param("filter", float64(FilterNearest)))))
+			return nil
+		},
+		"crop": func(i *Image, op Operation) error {
+			var err error
+			size := func(name string, def uint) uint {
+				v, sizeErr := op.size(name, def)
+				if err == nil {
+					err = sizeErr
+				}
+				return v
+			}
+			r := NewRect(size("x1", 0), size("y1", 0), size("x2", i.Width), size("y2", i.Height))
+			if err != nil {
+				return err
+			}
+			if err := checkPixels(int(r.Dx()), int(r.Dy())); err != nil {
+				return err
+			}
+			cropped := i.Crop(r)
+			i.Pixel, i.Width, i.Height = cropped.Pixel, cropped.Width, cropped.Height
+			return nil
+		},
+		"round": func(i *Image, op Operation) error {
+			i.Round(uint(op.param("radius", 0)))
+			return nil
+		},
+		"rotate90":        func(i *Image, op Operation) error { i.Rotate90(); return nil },
+		"rotate_minus90":  func(i *Image, op Operation) error { i.RotateMinus90(); return nil },
+		"flip_horizontal": func(i *Image, op Operation) error { i.FlipHorizontal(); return nil },
+		"flip_vertical":   func(i *Image, op Operation) error { i.FlipVertical(); return nil },
+		"grayscale": func(i *Image, op Operation) error {
+			i.Grayscale(GrayMode(op.param("mode", float64(GrayLuminosity))))
+			return nil
+		},
+		"sepia": func(i *Image, op Operation) error {
+			i.Sepia(op.param("intensity", 1))
+			return nil
+		},
+		"adjust_hsl": func(i *Image, op Operation) error {
+			i.AdjustHSL(op.param("hue", 0), op.param("saturation", 1), op.param("lightness", 1))
+			return nil
+		},
+		"temperature": func(i *Image, op Operation) error {
+			i.Temperature(op.param("kelvin", 0))
+			return nil
+		},
+		"auto_white_balance": func(i *Image, op Operation) error { i.AutoWhiteBalance(); return nil },
+		"posterize": func(i *Image, op Operation) error {
+			i.Posterize(uint8(op.param("levels", 4)))
+			return nil
+		},
+		"threshold": func(i *Image, op Operation) error {
+			i.Threshold(uint8(op.param("level", 128)))
+			return nil
+		},
+		"pixelate": func(i *Image, op Operation) error {
+			i.Pixelate(uint(op.param("size", 8)))
+			return nil
+		},
+		"vignette": func(i *Image, op Operation) error {
+			i.Vignette(op.param("strength", 0.5), RGBA{0, 0, 0, 255})
+			return nil
+		},
+		"bilateral": func(i *Image, op Operation) error {
+			i.BilateralFilter(op.param("sigma_space", 3), op.param("sigma_color", 30))
+			return nil
+		},
+		"oil_paint": func(i *Image, op Operation) error {
+			i.OilPaint(uint(op.param("radius", 3)), uint(op.param("levels", 20)))
+			return nil
+		},
+		"noise": func(i *Image, op Operation) error {
+			i.AddNoise(NoiseKind(op.param("kind", float64(NoiseGaussian))), op.param("amount", 0.05), int64(op.param("seed", 1)))
+			return nil
+		},
+		"film_grain": func(i *Image, op Operation) error {
+			i.FilmGrain(op.param("intensity", 0.3))
+			return nil
+		},
+		"scanlines": func(i *Image, op Operation) error {
+			i.Scanlines(uint(op.param("spacing", 2)), op.param("darkness", 0.3))
+			return nil
+		},
+	}
+)
+
+// RegisterOperation registers an operation that presets can refer to by name, replacing any operation with that name.
+//
+// name: The name of the operation.
+// fn: The function that applies the operation.
+func RegisterOperation(name string, fn OperationFunc) {
+	presetMu.Lock()
+	defer presetMu.Unlock()
+	operations[name] = fn
+}
+
+// RegisterPreset registers a named preset, replacing any preset with that name.
+//
+// name: The name of the preset, such as "thumbnail".
+// ops: The operations of the preset, applied in order.
+func RegisterPreset(name string, ops ...Operation) {
+	presetMu.Lock()
+	defer presetMu.Unlock()
+	presets[name] = Preset{Name: name, Operations: append([]Operation(nil), ops...)}
+}
+
+// Apply applies the operations to the image in order.
+//
+// ops: The operations to apply.
+//
+// Returns: An error if an operation is not registered or fails; the operations before it stay applied.
+func (i *Image) Apply(ops ...Operation) error {
+	for _, op := range ops {
+		presetMu.RLock()
+		fn, ok := operations[op.Name]
+		presetMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownOperation, op.Name)
+		}
+		if err := fn(i, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyPreset applies a registered preset to the image.
+//
+// img: The image to process in place.
+// name: The name of the preset.
+//
+// Returns: An error if the preset or one of its operations is not registered, or an operation fails.
+func ApplyPreset(img *Image, name string) error {
+	presetMu.RLock()
+	preset, ok := presets[name]
+	presetMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownPreset, name)
+	}
+	return img.Apply(preset.Operations...)
+}
+
+// ExportPresets writes all registered presets as a JSON array, sorted by name.
+//
+// w: The writer to write the JSON to.
+//
+// Returns: An error if writing fails.
+func ExportPresets(w io.Writer) error {
+	presetMu.RLock()
+	list := make([]Preset, 0, len(presets))
+	for _, p := range presets {
+		list = append(list, p)
+	}
+	presetMu.RUnlock()
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].Name < list[b].Name
+	})
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+// ImportPresets reads a JSON array of presets, as written by ExportPresets, and registers them.
+//
+// r: The reader to read the JSON from.
+//
+// Returns: An error if the JSON cannot be parsed or refers to an unregistered operation; no preset is registered then.
+func ImportPresets(r io.Reader) error {
+	var list []Preset
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return err
+	}
+	presetMu.Lock()
+	defer presetMu.Unlock()
+	for _, p := range list {
+		for _, op := range p.Operations {
+			if _, ok := operations[op.Name]; !ok {
+				return fmt.Errorf("%w: %q in preset %q", ErrUnknownOperation, op.Name, p.Name)
+			}
+		}
+	}
+	for _, p := range list {
+		presets[p.Name] = p
+	}
+	return nil
+}
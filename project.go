@@ -0,0 +1,328 @@
+package picrocess
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+)
+
+// ProjectVersion is the version of the project format written by WriteProject.
+// Projects of older versions are migrated when they are read.
+const ProjectVersion = 1
+
+var (
+	// ErrUnsupportedProject is returned (wrapped with the details) when a project file is malformed or was written by a newer version.
+	ErrUnsupportedProject = errors.New("picrocess: unsupported project")
+	// ErrNotSerializable is returned (wrapped with the details) when a timeline uses a custom easing or layer effect that cannot be saved.
+	ErrNotSerializable = errors.New("picrocess: not serializable")
+)
+
+var (
+	// MaxProjectBytes is the largest size, in bytes, of a project archive read by ReadProject and LoadProject.
+	// Set it to 0 to disable the limit.
+	MaxProjectBytes = 256 * 1024 * 1024
+	// MaxProjectEntryBytes is the largest uncompressed size, in bytes, of a file inside a project archive,
+	// so small archives that decompress to huge files cannot exhaust memory. Set it to 0 to disable the limit.
+	MaxProjectEntryBytes = 64 * 1024 * 1024
+)
+
+// projectFile is the JSON document stored as project.json inside a project archive.
+type projectFile struct {
+	Version    int            `json:"version"`
+	Width      uint           `json:"width"`
+	Height     uint           `json:"height"`
+	Background RGBA           `json:"background"`
+	Layers     []projectLayer `json:"layers"`
+}
+
+type projectLayer struct {
+	// Image is the path of the layer's PNG inside the archive, or empty if the layer has no image.
+	Image     string                              `json:"image,omitempty"`
+	X         float64                             `json:"x"`
+	Y         float64                             `json:"y"`
+	Opacity   float64                             `json:"opacity"`
+	Rotation  float64                             `json:"rotation"`
+	Scale     float64                             `json:"scale"`
+	Hue       float64                             `json:"hue"`
+	Effects   []projectEffect                     `json:"effects,omitempty"`
+	Keyframes map[LayerProperty][]projectKeyframe `json:"keyframes,omitempty"`
+}
+
+type projectKeyframe struct {
+	Time  float64 `json:"time"`
+	Value float64 `json:"value"`
+	Ease  string  `json:"ease,omitempty"`
+}
+
+type projectEffect struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// projectEasings names the built-in easings, so keyframes can refer to them in a project file.
+var projectEasings = map[string]Easing{
+	"linear":      Linear,
+	"ease_in":     EaseIn,
+	"ease_out":    EaseOut,
+	"ease_in_out": EaseInOut,
+	"bounce":      Bounce,
+	"elastic":     Elastic,
+}
+
+// projectMigrations upgrade a project of the version they are stored under to the next version.
+var projectMigrations = map[int]func(p *projectFile) error{}
+
+// easingName returns the project name of a built-in easing.
+func easingName(ease Easing) (string, error) {
+	if ease == nil {
+		return "", nil
+	}
+	pointer := reflect.ValueOf(ease).Pointer()
+	for name, e := range projectEasings {
+		if reflect.ValueOf(e).Pointer() == pointer {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: custom easing", ErrNotSerializable)
+}
+
+// encodeEffect returns the project form of a built-in layer effect.
+func encodeEffect(effect LayerEffect) (projectEffect, error) {
+	var name string
+	switch effect.(type) {
+	case ColorOverlay:
+		name = "color_overlay"
+	case GradientOverlay:
+		name = "gradient_overlay"
+	case InnerShadow:
+		name = "inner_shadow"
+	case InnerGlow:
+		name = "inner_glow"
+	case BevelEmboss:
+		name = "bevel_emboss"
+	default:
+		return projectEffect{}, fmt.Errorf("%w: layer effect %T", ErrNotSerializable, effect)
+	}
+	params, err := json.Marshal(effect)
+	return projectEffect{Type: name, Params: params}, err
+}
+
+// decodeEffect returns the layer effect stored in a project.
+func decodeEffect(e projectEffect) (LayerEffect, error) {
+	var err error
+	switch e.Type {
+	case "color_overlay":
+		var effect ColorOverlay
+		err = json.Unmarshal(e.Params, &effect)
+		return effect, err
+	case "gradient_overlay":
+		var effect GradientOverlay
+		err = json.Unmarshal(e.Params, &effect)
+		return effect, err
+	case "inner_shadow":
+		var effect InnerShadow
+		err = json.Unmarshal(e.Params, &effect)
+		return effect, err
+	case "inner_glow":
+		var effect InnerGlow
+		err = json.Unmarshal(e.Params, &effect)
+		return effect, err
+	case "bevel_emboss":
+		var effect BevelEmboss
+		err = json.Unmarshal(e.Params, &effect)
+		return effect, err
+	}
+	return nil, fmt.Errorf("%w: unknown layer effect %q", ErrUnsupportedProject, e.Type)
+}
+
+// WriteProject writes a timeline as a project: a zip archive holding a versioned project.json with the layers,
+// their properties, keyframes, and effects, and a PNG of every layer image. The project can be read back
+// with ReadProject to re-render the design later, such as with different layer images.
+//
+// w: The writer to write the archive to.
+// tl: The timeline to save.
+//
+// Returns: An error if writing fails, or one wrapping ErrNotSerializable if a layer uses a custom easing or effect.
+func WriteProject(w io.Writer, tl *Timeline) error {
+	doc := projectFile{
+		Version:    ProjectVersion,
+		Width:      tl.Width,
+		Height:     tl.Height,
+		Background: tl.Background,
+		Layers:     make([]projectLayer, len(tl.Layers)),
+	}
+	images := make(map[string]*Image)
+	for k, l := range tl.Layers {
+		layer := projectLayer{X: l.X, Y: l.Y, Opacity: l.Opacity, Rotation: l.Rotation, Scale: l.Scale, Hue: l.Hue}
+		if l.Image != nil {
+			layer.Image = fmt.Sprintf("layers/%d.png", k)
+			images[layer.Image] = l.Image
+		}
+		for _, effect := range l.Effects {
+			e, err := encodeEffect(effect)
+			if err != nil {
+				return err
+			}
+			layer.Effects = append(layer.Effects, e)
+		}
+		for property, frames := range l.keyframes {
+			if layer.Keyframes == nil {
+				layer.Keyframes = make(map[LayerProperty][]projectKeyframe)
+			}
+			for _, f := range frames {
+				name, err := easingName(f.Ease)
+				if err != nil {
+					return err
+				}
+				layer.Keyframes[property] = append(layer.Keyframes[property], projectKeyframe{Time: f.Time, Value: f.Value, Ease: name})
+			}
+		}
+		doc.Layers[k] = layer
+	}
+	archive := zip.NewWriter(w)
+	manifest, err := archive.Create("project.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	for k := range tl.Layers {
+		name := doc.Layers[k].Image
+		if name == "" {
+			continue
+		}
+		// The PNG data is already compressed, so store it as-is.
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := images[name].encodePNG(file, StdRenderer); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// ReadProject reads a project written by WriteProject, migrating it from older versions of the format.
+//
+// r: The reader to read the archive from.
+//
+// Archives larger than MaxProjectBytes, and files inside them larger than MaxProjectEntryBytes, are rejected with a LimitError.
+//
+// Returns: A new Timeline with the layers of the project, or an error wrapping ErrUnsupportedProject if it cannot be read.
+func ReadProject(r io.Reader) (*Timeline, error) {
+	data, err := readLimited(r, MaxProjectBytes)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedProject, err)
+	}
+	open := func(name string) ([]byte, error) {
+		file, err := archive.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedProject, err)
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedProject, err)
+		}
+		// Check the size in the header first, and still stop reading at the limit in case the header is wrong.
+		if MaxProjectEntryBytes > 0 && info.Size() > int64(MaxProjectEntryBytes) {
+			return nil, &LimitError{Err: ErrFileTooLarge, Value: int(min(info.Size(), math.MaxInt)), Limit: MaxProjectEntryBytes}
+		}
+		return readLimited(file, MaxProjectEntryBytes)
+	}
+	manifest, err := open("project.json")
+	if err != nil {
+		return nil, err
+	}
+	var doc projectFile
+	if err := json.Unmarshal(manifest, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedProject, err)
+	}
+	if doc.Version < 1 || doc.Version > ProjectVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedProject, doc.Version)
+	}
+	for doc.Version < ProjectVersion {
+		migrate, ok := projectMigrations[doc.Version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no migration from version %d", ErrUnsupportedProject, doc.Version)
+		}
+		if err := migrate(&doc); err != nil {
+			return nil, err
+		}
+		doc.Version++
+	}
+	tl := NewTimeline(doc.Width, doc.Height, doc.Background)
+	for _, layer := range doc.Layers {
+		var img *Image
+		if layer.Image != "" {
+			data, err := open(layer.Image)
+			if err != nil {
+				return nil, err
+			}
+			if img, _, err = DecodeImage(bytes.NewReader(data)); err != nil {
+				return nil, err
+			}
+		}
+		l := NewLayer(img)
+		l.X, l.Y, l.Opacity, l.Rotation, l.Scale, l.Hue = layer.X, layer.Y, layer.Opacity, layer.Rotation, layer.Scale, layer.Hue
+		for _, e := range layer.Effects {
+			effect, err := decodeEffect(e)
+			if err != nil {
+				return nil, err
+			}
+			l.Effects = append(l.Effects, effect)
+		}
+		for property, frames := range layer.Keyframes {
+			for _, f := range frames {
+				ease, ok := projectEasings[f.Ease]
+				if !ok && f.Ease != "" {
+					return nil, fmt.Errorf("%w: unknown easing %q", ErrUnsupportedProject, f.Ease)
+				}
+				l.Keyframe(property, f.Time, f.Value, ease)
+			}
+		}
+		tl.Add(l)
+	}
+	return tl, nil
+}
+
+// SaveProject saves the timeline as a project file, as written by WriteProject.
+//
+// filename: The path of the file to write, such as "banner.picproj".
+//
+// Returns: An error if the file cannot be written or the timeline cannot be saved.
+func (tl *Timeline) SaveProject(filename string) error {
+	var buf bytes.Buffer
+	if err := WriteProject(&buf, tl); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// LoadProject loads a project file saved by SaveProject or WriteProject.
+//
+// filename: The path of the project file.
+//
+// Returns: A new Timeline with the layers of the project, or an error if it cannot be read.
+func LoadProject(filename string) (*Timeline, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadProject(file)
+}
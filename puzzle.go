@@ -0,0 +1,137 @@
+package picrocess
+
+import (
+	"math"
+	"math/rand"
+)
+
+type PuzzleStyle int
+
+const (
+	// PuzzleClassic cuts pieces with round knobs on a narrow neck, like a jigsaw puzzle.
+	PuzzleClassic PuzzleStyle = iota
+	// PuzzleSquare cuts pieces with rectangular tabs.
+	PuzzleSquare
+	// PuzzleStraight cuts plain rectangular pieces without knobs.
+	PuzzleStraight
+)
+
+// SplitPuzzle cuts the image into interlocking puzzle pieces with transparent backgrounds, such as for puzzle games in chat bots.
+// The cells are cols × rows equal parts of the image, and every piece image extends the same margin beyond its cell on all sides
+// to hold the knobs, so a piece belongs at the top left corner of its cell minus the margin.
+// The knobs are laid out the same way every time for the same image size.
+//
+// rows: The number of rows of pieces.
+// cols: The number of columns of pieces.
+// knobStyle: The shape of the knobs (PuzzleClassic, PuzzleSquare, or PuzzleStraight).
+//
+// Returns: The pieces, row by row from the top left, or nil if rows or cols is 0 or larger than the image.
+func (i *Image) SplitPuzzle(rows, cols uint, knobStyle PuzzleStyle) []*Image {
+	if rows == 0 || cols == 0 || rows > i.Height || cols > i.Width {
+		return nil
+	}
+	xs := make([]float64, cols+1)
+	for c := range xs {
+		xs[c] = float64(uint(c) * i.Width / cols)
+	}
+	ys := make([]float64, rows+1)
+	for r := range ys {
+		ys[r] = float64(uint(r) * i.Height / rows)
+	}
+	knob := math.Min(float64(i.Width/cols), float64(i.Height/rows)) * 0.2
+	reach := 0.0
+	switch knobStyle {
+	case PuzzleClassic:
+		reach = knob * 1.5
+	case PuzzleSquare:
+		reach = knob
+	}
+	margin := uint(math.Ceil(reach))
+	// Every inner edge has a knob going one way or the other: down or right for +1, up or left for -1.
+	random := rand.New(rand.NewSource(1))
+	side := func() float64 {
+		if random.Intn(2) == 0 {
+			return -1
+		}
+		return 1
+	}
+	horizontal := make([][]float64, rows+1)
+	for r := range horizontal {
+		horizontal[r] = make([]float64, cols)
+		for c := range horizontal[r] {
+			if r > 0 && r < int(rows) {
+				horizontal[r][c] = side()
+			}
+		}
+	}
+	vertical := make([][]float64, rows)
+	for r := range vertical {
+		vertical[r] = make([]float64, cols+1)
+		for c := range vertical[r] {
+			if c > 0 && c < int(cols) {
+				vertical[r][c] = side()
+			}
+		}
+	}
+	pieces := make([]*Image, 0, rows*cols)
+	for r := uint(0); r < rows; r++ {
+		for c := uint(0); c < cols; c++ {
+			x0, y0, x1, y1 := xs[c], ys[r], xs[c+1], ys[r+1]
+			// Trace the outline clockwise; the sign of every edge tells whether its knob points out of the piece or into it.
+			path := NewPath().MoveTo(x0, y0)
+			puzzleEdge(path, x0, y0, x1, y0, -horizontal[r][c], knob, knobStyle)
+			puzzleEdge(path, x1, y0, x1, y1, vertical[r][c+1], knob, knobStyle)
+			puzzleEdge(path, x1, y1, x0, y1, horizontal[r+1][c], knob, knobStyle)
+			puzzleEdge(path, x0, y1, x0, y0, -vertical[r][c], knob, knobStyle)
+			path.Close()
+			left, top := x0-float64(margin), y0-float64(margin)
+			w, h := uint(x1-x0)+2*margin, uint(y1-y0)+2*margin
+			cov := path.transformed(NewTransform().Translate(-left, -top)).coverage(w, h, NonZero)
+			piece := NewImage(w, h, RGBA{0, 0, 0, 0})
+			for px := cov.bounds.Min.X; px < cov.bounds.Max.X; px++ {
+				for py := cov.bounds.Min.Y; py < cov.bounds.Max.Y; py++ {
+					v := cov.at(px, py)
+					sx, sy := int(left)+px, int(top)+py
+					if v <= 0 || sx < 0 || sy < 0 || sx >= int(i.Width) || sy >= int(i.Height) {
+						continue
+					}
+					p := i.Pixel[sx][sy]
+					p.A = clampUint8(float64(p.A) * v)
+					piece.Pixel[px][py] = p
+				}
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+	return pieces
+}
+
+// puzzleEdge adds one edge of a puzzle piece from (x0, y0) to (x1, y1) to the path. The knob points to the left
+// of the direction of travel (out of a piece traced clockwise) if bulge is positive, to the right if it is negative,
+// and the edge is straight if it is 0.
+func puzzleEdge(path *Path, x0, y0, x1, y1, bulge, knob float64, style PuzzleStyle) {
+	length := math.Hypot(x1-x0, y1-y0)
+	if bulge == 0 || style == PuzzleStraight || length == 0 {
+		path.LineTo(x1, y1)
+		return
+	}
+	// Build the knob in a local frame, with u along the edge and v out of it, then map it onto the edge.
+	local := NewPath().MoveTo(0, 0)
+	mid := length / 2
+	switch style {
+	case PuzzleSquare:
+		local.LineTo(mid-knob/2, 0).LineTo(mid-knob/2, knob).LineTo(mid+knob/2, knob).LineTo(mid+knob/2, 0)
+	default:
+		// A round head on a narrow neck; the arc runs from the left of the neck around the far side of the head to its right.
+		neck, radius, center := knob*0.4, knob*0.6, knob*0.9
+		angle := math.Atan2(-math.Sqrt(radius*radius-neck*neck), -neck) * 180 / math.Pi
+		local.LineTo(mid-neck, 0).Arc(mid, center, radius, angle, -540-angle).LineTo(mid+neck, 0)
+	}
+	local.LineTo(length, 0)
+	ux, uy := (x1-x0)/length, (y1-y0)/length
+	// In image coordinates (y pointing down), the left of the direction of travel is (uy, -ux).
+	vx, vy := uy*bulge, -ux*bulge
+	for _, pt := range local.subpaths[0].points[1:] {
+		path.LineTo(x0+pt.x*ux+pt.y*vx, y0+pt.x*uy+pt.y*vy)
+	}
+}
@@ -0,0 +1,181 @@
+package picrocess
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// WithColors sets how many colors the palettes of GIF frames have. Fewer colors make smaller files.
+//
+// n: The number of colors, from 2 to 256.
+func WithColors(n int) Option {
+	return func(o *Options) {
+		o.colors = max(2, min(n, 256))
+	}
+}
+
+// colorCount is a color of an image and how many pixels have it.
+type colorCount struct {
+	c     color.RGBA
+	count int
+}
+
+// channel returns one channel of the color: 0 for red, 1 for green, 2 for blue, and 3 for alpha.
+func (c colorCount) channel(k int) uint8 {
+	return [4]uint8{c.c.R, c.c.G, c.c.B, c.c.A}[k]
+}
+
+// colorBox is a box of the color space holding some of the colors of an image, for median-cut quantization.
+type colorBox struct {
+	colors []colorCount
+	count  int
+	// channel is the channel the colors spread over the most, and spread how far they spread over it.
+	channel, spread int
+}
+
+func newColorBox(colors []colorCount) colorBox {
+	b := colorBox{colors: colors}
+	lo, hi := [4]int{255, 255, 255, 255}, [4]int{}
+	for _, c := range colors {
+		b.count += c.count
+		for k := 0; k < 4; k++ {
+			v := int(c.channel(k))
+			lo[k], hi[k] = min(lo[k], v), max(hi[k], v)
+		}
+	}
+	for k := 0; k < 4; k++ {
+		if hi[k]-lo[k] > b.spread {
+			b.channel, b.spread = k, hi[k]-lo[k]
+		}
+	}
+	return b
+}
+
+// split splits the box at the median pixel along the channel its colors spread over the most.
+func (b colorBox) split() (colorBox, colorBox) {
+	sort.SliceStable(b.colors, func(i, j int) bool {
+		return b.colors[i].channel(b.channel) < b.colors[j].channel(b.channel)
+	})
+	half, seen, at := b.count/2, 0, 1
+	for k, c := range b.colors[:len(b.colors)-1] {
+		seen += c.count
+		at = k + 1
+		if seen >= half {
+			break
+		}
+	}
+	return newColorBox(b.colors[:at]), newColorBox(b.colors[at:])
+}
+
+// average returns the average color of the pixels in the box.
+func (b colorBox) average() color.RGBA {
+	var sum [4]int
+	for _, c := range b.colors {
+		for k := 0; k < 4; k++ {
+			sum[k] += int(c.channel(k)) * c.count
+		}
+	}
+	avg := func(k int) uint8 {
+		return uint8((sum[k] + b.count/2) / b.count)
+	}
+	return color.RGBA{avg(0), avg(1), avg(2), avg(3)}
+}
+
+// medianCut reduces the colors to at most n colors with the median-cut algorithm: the box of colors with
+// the most pixels spread over the widest range is split at its median until there are n boxes, and every box
+// becomes the average of its colors.
+func medianCut(colors []colorCount, n int) color.Palette {
+	if n <= 0 || len(colors) == 0 {
+		return color.Palette{}
+	}
+	boxes := []colorBox{newColorBox(colors)}
+	for len(boxes) < n {
+		best := -1
+		for k, b := range boxes {
+			if len(b.colors) > 1 && (best < 0 || b.spread*b.count > boxes[best].spread*boxes[best].count) {
+				best = k
+			}
+		}
+		if best < 0 {
+			break
+		}
+		a, b := boxes[best].split()
+		boxes[best] = a
+		boxes = append(boxes, b)
+	}
+	palette := make(color.Palette, len(boxes))
+	for k, b := range boxes {
+		palette[k] = b.average()
+	}
+	return palette
+}
+
+// WithGlobalPalette encodes GIFs with one palette shared by all frames instead of a palette per frame,
+// for smaller files and colors that do not shift between frames. The palette is quantized from the pixels
+// of every frame, to the number of colors set with WithColors.
+func WithGlobalPalette() Option {
+	return func(o *Options) {
+		o.globalPalette = true
+	}
+}
+
+// quantize returns a palette of at most limit colors for the frame: its own colors if it has few enough,
+// or else colors chosen with median-cut quantization. Fully transparent pixels keep a transparent color
+// of their own, so they stay transparent in GIFs.
+func quantize(frame *image.RGBA, limit int) color.Palette {
+	return quantizeFrames([]*image.RGBA{frame}, limit, false)
+}
+
+// quantizeFrames returns one palette of at most limit colors for all the frames, as quantize does for one frame.
+// If transparent is true, the palette has a transparent color even if no pixel is transparent.
+func quantizeFrames(frames []*image.RGBA, limit int, transparent bool) color.Palette {
+	counts := make(map[color.RGBA]int)
+	for _, frame := range frames {
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := frame.RGBAAt(x, y)
+				if c.A == 0 {
+					transparent = true
+					continue
+				}
+				counts[c]++
+			}
+		}
+	}
+	colors := make([]colorCount, 0, len(counts))
+	for c, count := range counts {
+		colors = append(colors, colorCount{c: c, count: count})
+	}
+	// Sort the colors so the same frame always gets the same palette.
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i].c, colors[j].c
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		if a.B != b.B {
+			return a.B < b.B
+		}
+		return a.A < b.A
+	})
+	if transparent {
+		limit--
+	}
+	var palette color.Palette
+	if len(colors) <= limit {
+		palette = make(color.Palette, len(colors))
+		for k, c := range colors {
+			palette[k] = c.c
+		}
+	} else {
+		palette = medianCut(colors, limit)
+	}
+	if transparent {
+		palette = append(palette, color.RGBA{})
+	}
+	return palette
+}
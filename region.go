@@ -0,0 +1,143 @@
+package picrocess
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrRegionTooLarge is returned when a requested region does not fit inside the image.
+var ErrRegionTooLarge = errors.New("picrocess: region does not fit in the image")
+
+// regionStats holds summed-area tables of the luminance, squared luminance, and edge strength of an image,
+// so the statistics of any rectangle can be read in constant time.
+type regionStats struct {
+	w, h                 uint
+	sum, sumSq, gradient [][]float64
+}
+
+// newRegionStats builds the summed-area tables of the image.
+func (i *Image) newRegionStats() *regionStats {
+	w, h := i.Width, i.Height
+	lum := i.luminance()
+	gx, gy := sobel(lum, w, h)
+	s := &regionStats{w: w, h: h}
+	s.sum, s.sumSq, s.gradient = make([][]float64, w+1), make([][]float64, w+1), make([][]float64, w+1)
+	for x := range s.sum {
+		s.sum[x], s.sumSq[x], s.gradient[x] = make([]float64, h+1), make([]float64, h+1), make([]float64, h+1)
+	}
+	for x := uint(1); x <= w; x++ {
+		for y := uint(1); y <= h; y++ {
+			l := lum[x-1][y-1]
+			// The Sobel kernels sum eight weighted neighbors, so divide by 4 to keep edges in the 0..255 range.
+			g := math.Hypot(gx[x-1][y-1], gy[x-1][y-1]) / 4
+			s.sum[x][y] = l + s.sum[x-1][y] + s.sum[x][y-1] - s.sum[x-1][y-1]
+			s.sumSq[x][y] = l*l + s.sumSq[x-1][y] + s.sumSq[x][y-1] - s.sumSq[x-1][y-1]
+			s.gradient[x][y] = g + s.gradient[x-1][y] + s.gradient[x][y-1] - s.gradient[x-1][y-1]
+		}
+	}
+	return s
+}
+
+// rectSum returns the sum of a table over a rectangle, which must lie inside the image.
+func rectSum(table [][]float64, r Rect) float64 {
+	return table[r.W2][r.H2] - table[r.W1][r.H2] - table[r.W2][r.H1] + table[r.W1][r.H1]
+}
+
+// at returns the mean luminance, the standard deviation of the luminance, and the mean edge strength
+// of a rectangle, all from 0 to 255.
+func (s *regionStats) at(r Rect) (mean, stddev, edges float64) {
+	r = NewRect(min(r.W1, s.w), min(r.H1, s.h), min(r.W2, s.w), min(r.H2, s.h))
+	n := float64(r.Dx() * r.Dy())
+	if n == 0 {
+		return 0, 0, 0
+	}
+	mean = rectSum(s.sum, r) / n
+	variance := rectSum(s.sumSq, r)/n - mean*mean
+	return mean, math.Sqrt(math.Max(0, variance)), rectSum(s.gradient, r) / n
+}
+
+// busyness returns how busy a rectangle of the image is, combining edge density and luminance variation.
+func (s *regionStats) busyness(r Rect) float64 {
+	_, stddev, edges := s.at(r)
+	return edges + stddev
+}
+
+// FindQuietRegion finds the least busy area of the image, with the fewest edges and the least variation
+// in brightness, so watermarks and captions can be placed where they do not cover the subject.
+//
+// minSize: The size of the area to find.
+//
+// Returns: The rectangle of the quietest area, or ErrRegionTooLarge if the size does not fit in the image.
+func (i *Image) FindQuietRegion(minSize Offset) (Rect, error) {
+	if minSize.W == 0 || minSize.H == 0 || minSize.W > i.Width || minSize.H > i.Height {
+		return Rect{}, ErrRegionTooLarge
+	}
+	stats := i.newRegionStats()
+	// Test positions on a grid of about an eighth of the region size, and always include the edges of the image.
+	stepX, stepY := max(1, minSize.W/8), max(1, minSize.H/8)
+	positions := func(span, step uint) []uint {
+		respond := make([]uint, 0)
+		for p := uint(0); p < span; p += step {
+			respond = append(respond, p)
+		}
+		return append(respond, span)
+	}
+	best, bestScore := Rect{}, math.Inf(1)
+	for _, x := range positions(i.Width-minSize.W, stepX) {
+		for _, y := range positions(i.Height-minSize.H, stepY) {
+			r := NewRect(x, y, x+minSize.W, y+minSize.H)
+			if score := stats.busyness(r); score < bestScore {
+				best, bestScore = r, score
+			}
+		}
+	}
+	return best, nil
+}
+
+// relativeLuminance returns the WCAG relative luminance of a color, from 0 (black) to 1 (white).
+func relativeLuminance(r, g, b float64) float64 {
+	return 0.2126*srgbToLinear(r/255) + 0.7152*srgbToLinear(g/255) + 0.0722*srgbToLinear(b/255)
+}
+
+// contrastRatio returns the WCAG contrast ratio between two relative luminances, from 1 to 21.
+func contrastRatio(a, b float64) float64 {
+	return (math.Max(a, b) + 0.05) / (math.Min(a, b) + 0.05)
+}
+
+// ReadabilityScore estimates how readable text of the given color and size would be on top of a region of the image.
+// It combines the contrast between the text and the background (including its darkest and lightest parts)
+// with how busy the background is; larger text tolerates busier backgrounds.
+// Scores of about 0.5 and above are comfortably readable; lower scores call for a scrim or backdrop.
+//
+// r: The rectangle the text would cover.
+// textColor: The color of the text.
+// size: The font size of the text.
+//
+// Returns: The readability score, from 0 (unreadable) to 1 (excellent).
+func (i *Image) ReadabilityScore(r Rect, textColor RGBA, size float64) float64 {
+	region := i.Crop(NewRect(min(r.W1, i.Width), min(r.H1, i.Height), min(r.W2, i.Width), min(r.H2, i.Height)))
+	if region.Width == 0 || region.Height == 0 {
+		return 0
+	}
+	// Average the relative luminance of the background, with transparent pixels counting as black.
+	sum, sumSq, n := 0.0, 0.0, 0.0
+	for x := range region.Pixel {
+		for y := range region.Pixel[x] {
+			p := region.Pixel[x][y]
+			a := float64(p.A) / 255
+			l := relativeLuminance(float64(p.R)*a, float64(p.G)*a, float64(p.B)*a)
+			sum += l
+			sumSq += l * l
+			n++
+		}
+	}
+	mean := sum / n
+	spread := math.Sqrt(math.Max(0, sumSq/n-mean*mean))
+	text := relativeLuminance(float64(textColor.R), float64(textColor.G), float64(textColor.B))
+	// The text must stand out from the background parts closest to its own brightness.
+	ratio := math.Min(contrastRatio(text, math.Max(0, mean-spread)), contrastRatio(text, math.Min(1, mean+spread)))
+	contrast := math.Max(0, math.Min(1, (ratio-1)/6))
+	clutter := math.Min(1, region.newRegionStats().busyness(NewRect(0, 0, region.Width, region.Height))/64)
+	large := math.Max(0, math.Min(1, size/48))
+	return contrast * (1 - clutter*(0.7-0.4*large))
+}
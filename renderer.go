@@ -0,0 +1,83 @@
+package picrocess
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+)
+
+// Renderer converts an Image into a standard image for encoding, so the backend that produces the final pixels
+// can be swapped (such as one that post-processes on a GPU or targets a different pixel format) without changing
+// the code that builds the image. Every encoding path, including PNG, JPEG, and GIF frames, goes through a Renderer.
+type Renderer interface {
+	Render(img *Image) (image.Image, error)
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(img *Image) (image.Image, error)
+
+// Render calls f(img).
+func (f RendererFunc) Render(img *Image) (image.Image, error) {
+	return f(img)
+}
+
+// StdRenderer renders images with the standard library, as an *image.RGBA. It is the default Renderer.
+var StdRenderer Renderer = RendererFunc(func(img *Image) (image.Image, error) {
+	return img.Render(), nil
+})
+
+var (
+	rendererMu      sync.RWMutex
+	defaultRenderer = StdRenderer
+)
+
+// SetRenderer sets the Renderer used by every encoding function that is not given one with WithRenderer.
+//
+// r: The Renderer to use. If it is nil, StdRenderer is restored.
+func SetRenderer(r Renderer) {
+	if r == nil {
+		r = StdRenderer
+	}
+	rendererMu.Lock()
+	defer rendererMu.Unlock()
+	defaultRenderer = r
+}
+
+// currentRenderer returns the Renderer set with SetRenderer.
+func currentRenderer() Renderer {
+	rendererMu.RLock()
+	defer rendererMu.RUnlock()
+	return defaultRenderer
+}
+
+// WithRenderer sets the Renderer used to encode, overriding the one set with SetRenderer for a single call.
+//
+// r: The Renderer to use. If it is nil, the one set with SetRenderer is used.
+func WithRenderer(r Renderer) Option {
+	return func(o *Options) {
+		o.renderer = r
+	}
+}
+
+// activeRenderer returns the Renderer of the options, or the one set with SetRenderer if there is none.
+func (o Options) activeRenderer() Renderer {
+	if o.renderer != nil {
+		return o.renderer
+	}
+	return currentRenderer()
+}
+
+// renderRGBA renders the image with the Renderer and converts the result to an *image.RGBA if it is not one already.
+func (i *Image) renderRGBA(r Renderer) (*image.RGBA, error) {
+	rendered, err := r.Render(i)
+	if err != nil {
+		return nil, err
+	}
+	if rgba, ok := rendered.(*image.RGBA); ok {
+		return rgba, nil
+	}
+	bounds := rendered.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), rendered, bounds.Min, draw.Src)
+	return rgba, nil
+}
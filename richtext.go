@@ -0,0 +1,334 @@
+package picrocess
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Span is a piece of rich text drawn in one style. Fields left at their zero value take the defaults of the RichText.
+type Span struct {
+	// Text is the text of the span. Line breaks ("\n") start a new line.
+	Text string
+	// Font is the font of the span, such as a bold or italic font of the family; nil uses the font of the RichText.
+	Font *Font
+	// Size is the font size of the span; 0 uses the size of the RichText.
+	Size float64
+	// Color is the color of the span; nil uses the color of the RichText.
+	Color *RGBA
+	// Underline draws a line under the span.
+	Underline bool
+	// Strikethrough draws a line through the span.
+	Strikethrough bool
+}
+
+// RichText is a paragraph of text made of spans with their own font, size, color, and style, laid out and wrapped
+// as one block, such as "player **Name** reached level **42**". Draw it with Image.RichText.
+type RichText struct {
+	Font  *Font
+	Size  float64
+	Color RGBA
+	Spans []Span
+}
+
+// NewRichText creates an empty RichText with the default style of its spans.
+//
+// font: The Font object to use for spans without a font of their own.
+// size: The font size to use for spans without a size of their own.
+// c: The color (RGBA) to use for spans without a color of their own.
+//
+// Returns: A pointer to a new RichText with no spans.
+func NewRichText(font *Font, size float64, c RGBA) *RichText {
+	return &RichText{Font: font, Size: size, Color: c}
+}
+
+// Add appends text in the default style of the rich text.
+//
+// text: The text to append.
+//
+// Returns: The RichText, so calls can be chained.
+func (r *RichText) Add(text string) *RichText {
+	return r.AddSpan(Span{Text: text})
+}
+
+// AddColor appends text in a color of its own, such as to highlight a name or a number.
+//
+// text: The text to append.
+// c: The color (RGBA) of the text.
+//
+// Returns: The RichText, so calls can be chained.
+func (r *RichText) AddColor(text string, c RGBA) *RichText {
+	return r.AddSpan(Span{Text: text, Color: &c})
+}
+
+// AddSpan appends a span of text in a style of its own.
+//
+// s: The span to append.
+//
+// Returns: The RichText, so calls can be chained.
+func (r *RichText) AddSpan(s Span) *RichText {
+	r.Spans = append(r.Spans, s)
+	return r
+}
+
+// style returns the span with the defaults of the rich text filled in and its size multiplied by scale.
+func (r *RichText) style(s Span, scale float64) Span {
+	if s.Font == nil {
+		s.Font = r.Font
+	}
+	if s.Size <= 0 {
+		s.Size = r.Size
+	}
+	if s.Color == nil {
+		s.Color = &r.Color
+	}
+	s.Size *= scale
+	return s
+}
+
+// richFragment is text of one span that is drawn in one go.
+type richFragment struct {
+	span  int
+	text  string
+	width float64
+}
+
+// richLine is a line of laid-out rich text.
+type richLine struct {
+	fragments []richFragment
+	width     float64
+	// size is the largest font size on the line, and descent the deepest descent of its fonts.
+	size, descent float64
+	// baseline is the distance of the baseline of the line below the top of the block.
+	baseline float64
+}
+
+// richLayout is rich text broken into lines, with the styles of its spans.
+type richLayout struct {
+	spans  []Span
+	lines  []richLine
+	width  float64
+	height float64
+}
+
+// layout breaks the rich text into lines no wider than width, breaking between words where it can and inside
+// words that are wider than a whole line, with font sizes multiplied by scale. A width of 0 only breaks lines at
+// line breaks. Lines are lineHeight times their largest font size apart.
+func (r *RichText) layout(width, lineHeight, scale float64) richLayout {
+	l := richLayout{spans: make([]Span, len(r.Spans))}
+	for k, s := range r.Spans {
+		l.spans[k] = r.style(s, scale)
+	}
+	measure := func(span int, text string) float64 {
+		s := l.spans[span]
+		if s.Font == nil || text == "" {
+			return 0
+		}
+		return s.Font.MeasureText(s.Size, text).Width
+	}
+	// appendTo appends text of a span to a line of fragments, joining it with the last fragment if it has the same span.
+	appendTo := func(fragments []richFragment, span int, text string) []richFragment {
+		if n := len(fragments); n > 0 && fragments[n-1].span == span {
+			fragments[n-1].text += text
+			fragments[n-1].width = measure(span, fragments[n-1].text)
+			return fragments
+		}
+		return append(fragments, richFragment{span: span, text: text, width: measure(span, text)})
+	}
+	widthOf := func(fragments []richFragment) float64 {
+		total := 0.0
+		for _, f := range fragments {
+			total += f.width
+		}
+		return total
+	}
+
+	var line, spaces, word []richFragment
+	lastSpan := 0
+	finishLine := func() {
+		l.lines = append(l.lines, richLine{fragments: line})
+		if len(line) == 0 {
+			// An empty line takes the size of the span that broke it.
+			l.lines[len(l.lines)-1].fragments = []richFragment{{span: lastSpan}}
+		}
+		line, spaces = nil, nil
+	}
+	placeWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		if len(line) > 0 && (width <= 0 || widthOf(line)+widthOf(spaces)+widthOf(word) <= width) {
+			for _, f := range append(spaces, word...) {
+				line = appendTo(line, f.span, f.text)
+			}
+			spaces, word = nil, nil
+			return
+		}
+		if len(line) > 0 {
+			finishLine()
+		}
+		spaces = nil
+		// Break words that do not fit on a line of their own.
+		for _, f := range word {
+			for _, c := range f.text {
+				if width > 0 && len(line) > 0 && widthOf(appendTo(append([]richFragment(nil), line...), f.span, string(c))) > width {
+					finishLine()
+				}
+				line = appendTo(line, f.span, string(c))
+			}
+		}
+		word = nil
+	}
+	for k, s := range r.Spans {
+		if l.spans[k].Font == nil {
+			continue
+		}
+		for _, c := range strings.ReplaceAll(s.Text, "\r\n", "\n") {
+			lastSpan = k
+			switch {
+			case c == '\n':
+				placeWord()
+				finishLine()
+			case unicode.IsSpace(c):
+				placeWord()
+				if len(line) > 0 {
+					spaces = appendTo(spaces, k, string(c))
+				}
+			default:
+				word = appendTo(word, k, string(c))
+			}
+		}
+	}
+	placeWord()
+	if len(line) > 0 {
+		finishLine()
+	}
+
+	baseline := 0.0
+	for k := range l.lines {
+		line := &l.lines[k]
+		for _, f := range line.fragments {
+			s := l.spans[f.span]
+			if s.Font == nil {
+				continue
+			}
+			line.size = math.Max(line.size, s.Size)
+			line.descent = math.Max(line.descent, s.Font.MeasureText(s.Size, "").Descent)
+		}
+		line.width = widthOf(line.fragments)
+		// The first baseline is one font size below the top, as for Text.
+		if k == 0 {
+			baseline = line.size
+		} else {
+			baseline += line.size * lineHeight
+		}
+		line.baseline = baseline
+		l.width = math.Max(l.width, line.width)
+		l.height = baseline + line.descent
+	}
+	return l
+}
+
+// Measure returns the width and height of the rich text when drawn with Image.RichText.
+//
+// width: The width to wrap the text at, in pixels; 0 only breaks lines at line breaks.
+// opts: Optional settings; WithLineHeight sets the distance between lines, as a multiple of their largest font size.
+//
+// Returns: The width and height of the text in pixels.
+func (r *RichText) Measure(width uint, opts ...Option) (uint, uint) {
+	l := r.layout(float64(width), NewOptions(opts...).LineHeight, 1)
+	return uint(math.Ceil(l.width)), uint(math.Ceil(l.height))
+}
+
+// draw draws laid-out rich text onto dst with the top-left corner of the block at (x, y).
+// Every line is aligned within the block by align, from 0 (left) to 1 (right).
+func (l richLayout) draw(dst draw.Image, x, y, align float64) error {
+	for _, line := range l.lines {
+		lx := x + math.Round((l.width-line.width)*align)
+		baseline := y + math.Round(line.baseline)
+		for _, f := range line.fragments {
+			s := l.spans[f.span]
+			src := image.NewUniform(color.RGBA{s.Color.R, s.Color.G, s.Color.B, s.Color.A})
+			dot := fixed.Point26_6{X: fixed.Int26_6(math.Round(lx * 64)), Y: fixed.I(int(baseline))}
+			if f.text != "" {
+				if err := s.Font.drawLine(dst, src, s.Size, dot, f.text); err != nil {
+					return err
+				}
+			}
+			thickness := math.Max(1, math.Round(s.Size/14))
+			if s.Underline {
+				top := int(baseline + math.Round(s.Size/10))
+				draw.Draw(dst, image.Rect(int(lx), top, int(math.Ceil(lx+f.width)), top+int(thickness)), src, image.Point{}, draw.Over)
+			}
+			if s.Strikethrough {
+				top := int(baseline - math.Round(s.Size*0.3))
+				draw.Draw(dst, image.Rect(int(lx), top, int(math.Ceil(lx+f.width)), top+int(thickness)), src, image.Point{}, draw.Over)
+			}
+			lx += f.width
+		}
+	}
+	return nil
+}
+
+// RichText draws rich text on the image, wrapping its spans as one paragraph.
+//
+// rt: The rich text to draw.
+// o: The offset to draw the text at; by default, the top left corner of the text.
+// width: The width to wrap the text at, in pixels; 0 only breaks lines at line breaks.
+// opts: Optional settings; WithAnchor places the text relative to the offset and aligns its lines, WithLineHeight sets
+// the distance between lines as a multiple of their largest font size, and WithShadow and WithBlendMode apply as they do for Text.
+//
+// Returns: An error if there is an issue rendering the text.
+func (i *Image) RichText(rt *RichText, o Offset, width uint, opts ...Option) error {
+	var layerErr error
+	if i.layered(opts, func(layer *Image) {
+		if err := layer.RichText(rt, o, width, unlayered(opts)...); err != nil {
+			layerErr = err
+		}
+	}) || layerErr != nil {
+		return layerErr
+	}
+	options := NewOptions(opts...)
+	l := rt.layout(float64(width), options.LineHeight, 1)
+	fx, fy := options.Anchor.fractions()
+	x := float64(o.W) - math.Round(math.Ceil(l.width)*fx)
+	y := float64(o.H) - math.Round(math.Ceil(l.height)*fy)
+	if t, ok := i.activeTransform(); ok {
+		// Render the glyphs at the scaled size so they stay sharp, then map the layer into place.
+		scale := math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
+		if scale == 0 {
+			return nil
+		}
+		scaled := rt.layout(float64(width)*scale, options.LineHeight, scale)
+		layer := NewImage(uint(math.Ceil(scaled.width))+1, uint(math.Ceil(scaled.height))+1, RGBA{})
+		if err := layer.drawRichText(scaled, 0, 0, fx); err != nil {
+			return err
+		}
+		i.drawTransformed(layer, t.Translate(x, y).Scale(1/scale, 1/scale))
+		return nil
+	}
+	return i.drawRichText(l, x, y, fx)
+}
+
+// drawRichText draws laid-out rich text with the top-left corner of the block at (x, y), without any transformation.
+func (i *Image) drawRichText(l richLayout, x, y, align float64) error {
+	// Only render the part of the image around the text, with a margin of the largest font size.
+	margin := 0.0
+	for _, line := range l.lines {
+		margin = math.Max(margin, line.size)
+	}
+	img := i.renderRect(image.Rect(int(x-margin), int(y-margin), int(math.Ceil(x+l.width+margin)), int(math.Ceil(y+l.height+margin))))
+	if img.Rect.Empty() {
+		return nil
+	}
+	if err := l.draw(img, x, y, align); err != nil {
+		return err
+	}
+	i.setRGBA(img)
+	return nil
+}
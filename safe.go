@@ -0,0 +1,31 @@
+package picrocess
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned by Safe when the function it runs panics.
+type PanicError struct {
+	Value any    // The value the function panicked with
+	Stack []byte // The stack trace of the panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("picrocess: recovered panic: %v", e.Value)
+}
+
+// Safe runs fn and turns any panic inside it into a PanicError, so a malformed input
+// cannot crash a worker that processes many images.
+//
+// fn: The function to run, usually a chain of image operations.
+//
+// Returns: The error returned by fn, or a *PanicError if fn panicked.
+func Safe(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
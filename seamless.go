@@ -0,0 +1,79 @@
+package picrocess
+
+import "math"
+
+// MakeSeamless converts the image into a texture that tiles without visible seams.
+// It offsets a copy of the image by half its size (wrapping around the edges) and
+// blends that copy into the original near the borders, so opposite edges line up
+// when the image is repeated.
+//
+// blendWidth: The width of the border band, in pixels, that is blended with the offset copy.
+// It is clamped to half of the smallest image dimension.
+func (i *Image) MakeSeamless(blendWidth uint) {
+	if i.Width < 2 || i.Height < 2 || blendWidth == 0 {
+		return
+	}
+	if blendWidth > i.Width/2 {
+		blendWidth = i.Width / 2
+	}
+	if blendWidth > i.Height/2 {
+		blendWidth = i.Height / 2
+	}
+	edgeWeight := func(p, size uint) float64 {
+		d := p
+		if size-1-p < d {
+			d = size - 1 - p
+		}
+		if d >= blendWidth {
+			return 0
+		}
+		return 1 - float64(d)/float64(blendWidth)
+	}
+	newPixel := make([][]RGBA, i.Width)
+	for x := uint(0); x < i.Width; x++ {
+		newPixel[x] = make([]RGBA, i.Height)
+		for y := uint(0); y < i.Height; y++ {
+			original := i.Pixel[x][y]
+			shifted := i.Pixel[(x+i.Width/2)%i.Width][(y+i.Height/2)%i.Height]
+			t := math.Max(edgeWeight(x, i.Width), edgeWeight(y, i.Height))
+			newPixel[x][y] = RGBA{
+				R: uint8((1-t)*float64(original.R) + t*float64(shifted.R)),
+				G: uint8((1-t)*float64(original.G) + t*float64(shifted.G)),
+				B: uint8((1-t)*float64(original.B) + t*float64(shifted.B)),
+				A: uint8((1-t)*float64(original.A) + t*float64(shifted.A)),
+			}
+		}
+	}
+	i.Pixel = newPixel
+}
+
+// IsTileable reports whether the image can be repeated without visible seams.
+// It compares the left column against the right column and the top row against the bottom row,
+// and checks that the average per-channel difference stays within the tolerance.
+//
+// tolerance: The maximum average channel difference (0-255) allowed across opposite edges.
+//
+// Returns: true if both edge pairs are within the tolerance, false otherwise.
+func (i *Image) IsTileable(tolerance float64) bool {
+	if i.Width == 0 || i.Height == 0 {
+		return false
+	}
+	diff := func(a, b RGBA) float64 {
+		return (math.Abs(float64(a.R)-float64(b.R)) +
+			math.Abs(float64(a.G)-float64(b.G)) +
+			math.Abs(float64(a.B)-float64(b.B)) +
+			math.Abs(float64(a.A)-float64(b.A))) / 4
+	}
+	var total float64
+	for y := uint(0); y < i.Height; y++ {
+		total += diff(i.Pixel[0][y], i.Pixel[i.Width-1][y])
+	}
+	if total/float64(i.Height) > tolerance {
+		return false
+	}
+	total = 0
+	for x := uint(0); x < i.Width; x++ {
+		total += diff(i.Pixel[x][0], i.Pixel[x][i.Height-1])
+	}
+	return total/float64(i.Width) <= tolerance
+}
@@ -0,0 +1,46 @@
+package picrocess
+
+// Shadow is a soft drop shadow cast by a drawn shape or an overlaid image.
+type Shadow struct {
+	// OffsetX and OffsetY move the shadow relative to what casts it, in pixels; positive values move it right and down.
+	OffsetX, OffsetY int
+	// Blur is the radius of the shadow's soft edge, in pixels; 0 gives a hard shadow.
+	Blur float64
+	// Color is the color of the shadow; its alpha sets how dark the shadow is.
+	Color RGBA
+}
+
+// WithShadow makes shape drawing functions and Overlay cast a drop shadow underneath what they draw,
+// such as for cards and stickers.
+//
+// s: The shadow to cast.
+func WithShadow(s Shadow) Option {
+	return func(o *Options) {
+		o.shadow = &s
+	}
+}
+
+// castShadow draws the shadow of whatever draw paints: draw runs on an empty layer of the same size and transformation
+// as the image, and the silhouette of the layer is offset, blurred, and composited onto the image.
+// The shadow scales with the scale factor of the image like other draw operations.
+func (i *Image) castShadow(s Shadow, draw func(layer *Image)) {
+	layer := i.newLayer()
+	draw(layer)
+	scale := i.ScaleFactor()
+	dx, dy := int(float64(s.OffsetX)*scale), int(float64(s.OffsetY)*scale)
+	silhouette := NewImage(i.Width, i.Height, RGBA{})
+	for x := range layer.Pixel {
+		for y := range layer.Pixel[x] {
+			a := layer.Pixel[x][y].A
+			sx, sy := x+dx, y+dy
+			if a == 0 || sx < 0 || sy < 0 || sx >= int(i.Width) || sy >= int(i.Height) {
+				continue
+			}
+			silhouette.Pixel[sx][sy] = RGBA{s.Color.R, s.Color.G, s.Color.B, uint8(uint(s.Color.A) * uint(a) / 255)}
+		}
+	}
+	if s.Blur > 0 {
+		silhouette = silhouette.gaussianBlur(s.Blur * scale)
+	}
+	i.compositeLayer(silhouette, BlendNormal)
+}
@@ -0,0 +1,189 @@
+package picrocess
+
+import "math"
+
+// FillEllipse draws a filled ellipse with anti-aliased edges.
+//
+// center: The pixel at the center of the ellipse.
+// rx: The horizontal radius, in pixels.
+// ry: The vertical radius, in pixels.
+// c: The color (RGBA) to fill with.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillEllipse(center Offset, rx, ry uint, c RGBA, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillEllipse(center, rx, ry, c)
+	}) {
+		return
+	}
+	if rx == 0 || ry == 0 {
+		return
+	}
+	cx, cy := float64(center.W)+0.5, float64(center.H)+0.5
+	i.FillPath(NewPath().Ellipse(cx, cy, float64(rx), float64(ry)), c, NonZero)
+}
+
+// DrawEllipse draws the outline of an ellipse with anti-aliased edges.
+// The outline is centered on the radius, so half of the thickness lies inside the ellipse and half outside.
+//
+// center: The pixel at the center of the ellipse.
+// rx: The horizontal radius, in pixels.
+// ry: The vertical radius, in pixels.
+// c: The color (RGBA) of the outline.
+// thickness: The thickness of the outline, in pixels.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawEllipse(center Offset, rx, ry uint, c RGBA, thickness float64, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawEllipse(center, rx, ry, c, thickness)
+	}) {
+		return
+	}
+	if thickness <= 0 {
+		return
+	}
+	cx, cy := float64(center.W)+0.5, float64(center.H)+0.5
+	hw := thickness / 2
+	path := NewPath().Ellipse(cx, cy, float64(rx)+hw, float64(ry)+hw)
+	if inner := min(float64(rx), float64(ry)) - hw; inner > 0 {
+		path.Ellipse(cx, cy, float64(rx)-hw, float64(ry)-hw)
+	}
+	i.FillPath(path, c, EvenOdd)
+}
+
+// FillCircle draws a filled circle with anti-aliased edges, such as a status dot.
+//
+// center: The pixel at the center of the circle.
+// radius: The radius, in pixels.
+// c: The color (RGBA) to fill with.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillCircle(center Offset, radius uint, c RGBA, opts ...Option) {
+	i.FillEllipse(center, radius, radius, c, opts...)
+}
+
+// DrawCircle draws the outline of a circle with anti-aliased edges, such as a badge border.
+// The outline is centered on the radius, so half of the thickness lies inside the circle and half outside.
+//
+// center: The pixel at the center of the circle.
+// radius: The radius, in pixels.
+// c: The color (RGBA) of the outline.
+// thickness: The thickness of the outline, in pixels.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawCircle(center Offset, radius uint, c RGBA, thickness float64, opts ...Option) {
+	i.DrawEllipse(center, radius, radius, c, thickness, opts...)
+}
+
+// FillPolygon fills the polygon through the given points with anti-aliased edges, such as a star, an arrow, or a map region.
+// The points are corners between pixels, so the polygon (0,0) (10,0) (10,10) (0,10) covers exactly 10×10 pixels.
+//
+// points: The corners of the polygon, in order. The polygon is closed automatically.
+// c: The color (RGBA) to fill with.
+// rule: The fill rule (NonZero or EvenOdd) deciding which self-overlapping areas are inside.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillPolygon(points []Offset, c RGBA, rule FillRule, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillPolygon(points, c, rule)
+	}) {
+		return
+	}
+	if len(points) < 3 {
+		return
+	}
+	path := NewPath().MoveTo(float64(points[0].W), float64(points[0].H))
+	for _, p := range points[1:] {
+		path.LineTo(float64(p.W), float64(p.H))
+	}
+	i.FillPath(path.Close(), c, rule)
+}
+
+// DrawArc draws a circular arc with anti-aliased edges, such as a circular progress indicator.
+// Angles are in degrees, clockwise from 3 o'clock; an arc from -90 to 270 is a full circle starting at the top.
+// The arc is centered on the radius, so half of the thickness lies inside and half outside.
+//
+// center: The pixel at the center of the arc.
+// radius: The radius, in pixels.
+// startDeg: The angle the arc starts at.
+// endDeg: The angle the arc ends at.
+// c: The color (RGBA) of the arc.
+// thickness: The thickness of the arc, in pixels.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawArc(center Offset, radius uint, startDeg, endDeg float64, c RGBA, thickness float64, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawArc(center, radius, startDeg, endDeg, c, thickness)
+	}) {
+		return
+	}
+	if thickness <= 0 || startDeg == endDeg {
+		return
+	}
+	cx, cy := float64(center.W)+0.5, float64(center.H)+0.5
+	hw := thickness / 2
+	path := NewPath().Arc(cx, cy, float64(radius)+hw, startDeg, endDeg)
+	path.Arc(cx, cy, math.Max(0, float64(radius)-hw), endDeg, startDeg)
+	i.FillPath(path.Close(), c, NonZero)
+}
+
+// FillPie draws a filled pie slice with anti-aliased edges, such as a segment of a pie chart.
+// Angles are in degrees, clockwise from 3 o'clock.
+//
+// center: The pixel at the center of the pie.
+// radius: The radius, in pixels.
+// startDeg: The angle the slice starts at.
+// endDeg: The angle the slice ends at.
+// c: The color (RGBA) to fill with.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillPie(center Offset, radius uint, startDeg, endDeg float64, c RGBA, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillPie(center, radius, startDeg, endDeg, c)
+	}) {
+		return
+	}
+	if radius == 0 || startDeg == endDeg {
+		return
+	}
+	cx, cy := float64(center.W)+0.5, float64(center.H)+0.5
+	path := NewPath().MoveTo(cx, cy).Arc(cx, cy, float64(radius), startDeg, endDeg)
+	i.FillPath(path.Close(), c, NonZero)
+}
+
+// FillRoundedRect fills a rectangle with rounded corners and anti-aliased edges, such as the background of a card or banner.
+//
+// r: The rectangle to fill. Its edges are corners between pixels, so (0,0)-(10,10) covers exactly 10×10 pixels.
+// radius: The radius of the corners, in pixels. It is limited to half of the shorter side.
+// c: The color (RGBA) to fill with.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillRoundedRect(r Rect, radius uint, c RGBA, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillRoundedRect(r, radius, c)
+	}) {
+		return
+	}
+	if r.Dx() == 0 || r.Dy() == 0 {
+		return
+	}
+	i.FillPath(NewPath().RoundedRect(float64(r.W1), float64(r.H1), float64(r.Dx()), float64(r.Dy()), float64(radius)), c, NonZero)
+}
+
+// DrawRoundedRect draws the outline of a rectangle with rounded corners and anti-aliased edges, such as the border of a card.
+// The outline lies entirely inside the rectangle, so it lines up with FillRoundedRect using the same rectangle and radius.
+//
+// r: The rectangle to outline. Its edges are corners between pixels.
+// radius: The radius of the outer corners, in pixels. It is limited to half of the shorter side.
+// c: The color (RGBA) of the outline.
+// thickness: The thickness of the outline, in pixels.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawRoundedRect(r Rect, radius uint, c RGBA, thickness float64, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.DrawRoundedRect(r, radius, c, thickness)
+	}) {
+		return
+	}
+	if thickness <= 0 || r.Dx() == 0 || r.Dy() == 0 {
+		return
+	}
+	x, y, w, h := float64(r.W1), float64(r.H1), float64(r.Dx()), float64(r.Dy())
+	outer := math.Min(float64(radius), math.Min(w, h)/2)
+	path := NewPath().RoundedRect(x, y, w, h, outer)
+	if w > 2*thickness && h > 2*thickness {
+		path.RoundedRect(x+thickness, y+thickness, w-2*thickness, h-2*thickness, math.Max(0, outer-thickness))
+	}
+	i.FillPath(path, c, EvenOdd)
+}
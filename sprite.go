@@ -0,0 +1,114 @@
+package picrocess
+
+import "math"
+
+// SliceTiles cuts the image into tiles of the given size, such as the frames of a spritesheet.
+// Partial tiles at the right and bottom edges are left out.
+//
+// tileW: The width of every tile, in pixels.
+// tileH: The height of every tile, in pixels.
+//
+// Returns: The tiles, row by row from the top left, or nil if a tile size is 0 or larger than the image.
+func (i *Image) SliceTiles(tileW, tileH uint) []*Image {
+	if tileW == 0 || tileH == 0 || tileW > i.Width || tileH > i.Height {
+		return nil
+	}
+	cols, rows := i.Width/tileW, i.Height/tileH
+	tiles := make([]*Image, 0, cols*rows)
+	for r := uint(0); r < rows; r++ {
+		for c := uint(0); c < cols; c++ {
+			tiles = append(tiles, i.Crop(NewRect(c*tileW, r*tileH, (c+1)*tileW, (r+1)*tileH)))
+		}
+	}
+	return tiles
+}
+
+// PreviewSpriteAnimation slices a spritesheet into its frames and assembles them into an animated GIF,
+// to check an animation without loading it into a game engine. The frames are read as GIFFromSpriteSheet reads them.
+//
+// sheet: The spritesheet to preview.
+// tileW: The width of every frame, in pixels.
+// tileH: The height of every frame, in pixels.
+// fps: The frame rate of the animation. If it is 0 or less, every frame gets a delay of AnimateDelay.
+//
+// Returns: A new GIF containing the frames of the sheet, or an error if the Renderer fails to render a frame.
+func PreviewSpriteAnimation(sheet *Image, tileW, tileH uint, fps int) (*GIF, error) {
+	delay := AnimateDelay
+	if fps > 0 {
+		delay = max(MinFrameDelay, int(math.Round(100/float64(fps))))
+	}
+	return GIFFromSpriteSheet(sheet, tileW, tileH, delay)
+}
+
+// transparent reports whether every pixel of the image is fully transparent.
+func (i *Image) transparent() bool {
+	for x := range i.Pixel {
+		for y := range i.Pixel[x] {
+			if i.Pixel[x][y].A != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GIFFromSpriteSheet slices a spritesheet into its frames and assembles them into a GIF, such as to export
+// the animations of a game. Frames are read row by row from the top left, and the fully transparent tiles
+// at the end of the sheet, the unused cells of its last row, are left out. Transparent tiles between other frames
+// are kept, so blank frames of an animation keep their timing.
+//
+// img: The spritesheet to convert.
+// frameW: The width of every frame, in pixels.
+// frameH: The height of every frame, in pixels.
+// delay: The delay of every frame in 100ths of a second.
+//
+// Returns: A new GIF containing the frames of the sheet, or an error if the Renderer fails to render a frame.
+func GIFFromSpriteSheet(img *Image, frameW, frameH uint, delay int) (*GIF, error) {
+	tiles := img.SliceTiles(frameW, frameH)
+	for len(tiles) > 0 && tiles[len(tiles)-1].transparent() {
+		tiles = tiles[:len(tiles)-1]
+	}
+	gf := NewGIF()
+	for _, tile := range tiles {
+		if err := gf.Append(tile, delay); err != nil {
+			return nil, err
+		}
+	}
+	return gf, nil
+}
+
+// ToSpriteSheet lays the frames of the GIF out on a spritesheet, row by row from the top left, such as to import
+// an animation into a game engine. Every cell is as large as the largest frame, and the frame delays are not kept.
+// The GIF is left unchanged.
+//
+// cols: The number of frames per row; 0 puts every frame on one row.
+//
+// Returns: A new Image containing the spritesheet, or an empty image if the GIF has no frames.
+func (gf *GIF) ToSpriteSheet(cols uint) *Image {
+	frames := gf.coalesced()
+	var cellW, cellH uint
+	for _, frame := range frames {
+		cellW = max(cellW, uint(frame.Bounds().Dx()))
+		cellH = max(cellH, uint(frame.Bounds().Dy()))
+	}
+	count := uint(len(frames))
+	if count == 0 {
+		return NewImage(0, 0, RGBA{})
+	}
+	if cols == 0 || cols > count {
+		cols = count
+	}
+	rows := (count + cols - 1) / cols
+	sheet := NewImage(cols*cellW, rows*cellH, RGBA{})
+	for k, frame := range frames {
+		left, top := uint(k)%cols*cellW, uint(k)/cols*cellH
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := frame.RGBAAt(x, y)
+				sheet.Pixel[left+uint(x-bounds.Min.X)][top+uint(y-bounds.Min.Y)] = RGBA{c.R, c.G, c.B, c.A}
+			}
+		}
+	}
+	return sheet
+}
@@ -0,0 +1,228 @@
+package picrocess
+
+import "math"
+
+type LineCap int
+
+const (
+	// CapButt ends the stroke exactly at the end point.
+	CapButt LineCap = iota
+	// CapRound ends the stroke with a half circle around the end point.
+	CapRound
+	// CapSquare ends the stroke with a half square extending past the end point.
+	CapSquare
+)
+
+type LineJoin int
+
+const (
+	// JoinMiter connects segments with a sharp corner, falling back to a bevel for very sharp angles.
+	JoinMiter LineJoin = iota
+	// JoinRound connects segments with a circular arc.
+	JoinRound
+	// JoinBevel connects segments by cutting the corner off with a straight line.
+	JoinBevel
+)
+
+// miterLimit is the maximum ratio between the miter length and the stroke width before a miter join becomes a bevel.
+const miterLimit = 4
+
+// addPolygon adds the points as a closed sub-path, reversing them if needed so every polygon winds the same way.
+// Polygons that share a winding direction can be filled together with NonZero to get their union.
+func (p *Path) addPolygon(points ...point) {
+	area := 0.0
+	for k := range points {
+		a, b := points[k], points[(k+1)%len(points)]
+		area += a.x*b.y - b.x*a.y
+	}
+	if area < 0 {
+		for l, r := 0, len(points)-1; l < r; l, r = l+1, r-1 {
+			points[l], points[r] = points[r], points[l]
+		}
+	}
+	p.subpaths = append(p.subpaths, subpath{points: points, closed: true})
+}
+
+// addCircle adds a circle with the given center and radius as a polygon.
+func (p *Path) addCircle(c point, radius float64) {
+	n := max(8, int(math.Ceil(2*math.Pi*radius/1.5)))
+	points := make([]point, n)
+	for k := range points {
+		a := 2 * math.Pi * float64(k) / float64(n)
+		points[k] = point{c.x + math.Cos(a)*radius, c.y + math.Sin(a)*radius}
+	}
+	p.addPolygon(points...)
+}
+
+// strokeOutline builds a Path covering the stroke of every sub-path, made of one polygon per segment, join, and cap.
+// The polygons all wind the same way, so the outline must be filled with NonZero.
+func (p *Path) strokeOutline(width float64, lineCap LineCap, join LineJoin) *Path {
+	outline := NewPath()
+	hw := width / 2
+	if hw <= 0 {
+		return outline
+	}
+	for _, s := range p.subpaths {
+		points := make([]point, 0, len(s.points))
+		for _, pt := range s.points {
+			if len(points) > 0 && points[len(points)-1] == pt {
+				continue
+			}
+			points = append(points, pt)
+		}
+		closed := s.closed
+		if closed && len(points) > 1 && points[0] == points[len(points)-1] {
+			points = points[:len(points)-1]
+		}
+		if len(points) == 1 {
+			switch lineCap {
+			case CapRound:
+				outline.addCircle(points[0], hw)
+			case CapSquare:
+				c := points[0]
+				outline.addPolygon(point{c.x - hw, c.y - hw}, point{c.x + hw, c.y - hw}, point{c.x + hw, c.y + hw}, point{c.x - hw, c.y + hw})
+			}
+			continue
+		}
+		n := len(points)
+		segments := n - 1
+		if closed {
+			segments = n
+		}
+		direction := func(k int) (float64, float64) {
+			a, b := points[k%n], points[(k+1)%n]
+			l := math.Hypot(b.x-a.x, b.y-a.y)
+			return (b.x - a.x) / l, (b.y - a.y) / l
+		}
+		for k := 0; k < segments; k++ {
+			a, b := points[k], points[(k+1)%n]
+			dx, dy := direction(k)
+			nx, ny := -dy*hw, dx*hw
+			if !closed && lineCap == CapSquare {
+				if k == 0 {
+					a = point{a.x - dx*hw, a.y - dy*hw}
+				}
+				if k == segments-1 {
+					b = point{b.x + dx*hw, b.y + dy*hw}
+				}
+			}
+			outline.addPolygon(point{a.x + nx, a.y + ny}, point{b.x + nx, b.y + ny}, point{b.x - nx, b.y - ny}, point{a.x - nx, a.y - ny})
+		}
+		if !closed && lineCap == CapRound {
+			outline.addCircle(points[0], hw)
+			outline.addCircle(points[n-1], hw)
+		}
+		for k := 0; k < n; k++ {
+			if !closed && (k == 0 || k == n-1) {
+				continue
+			}
+			v := points[k]
+			d1x, d1y := direction(k - 1 + n)
+			d2x, d2y := direction(k)
+			if d1x*d2y-d1y*d2x == 0 && d1x*d2x+d1y*d2y > 0 {
+				continue
+			}
+			if join == JoinRound {
+				outline.addCircle(v, hw)
+				continue
+			}
+			n1x, n1y := -d1y, d1x
+			n2x, n2y := -d2y, d2x
+			for _, side := range []float64{1, -1} {
+				p1 := point{v.x + side*n1x*hw, v.y + side*n1y*hw}
+				p2 := point{v.x + side*n2x*hw, v.y + side*n2y*hw}
+				mx, my := side*(n1x+n2x), side*(n1y+n2y)
+				ml := math.Hypot(mx, my)
+				outer := ml > 0 && mx*d1x+my*d1y > 0
+				if join == JoinMiter && outer {
+					mx, my = mx/ml, my/ml
+					cos := mx*side*n1x + my*side*n1y
+					if cos > 0 && 1/cos <= miterLimit {
+						m := point{v.x + mx*hw/cos, v.y + my*hw/cos}
+						outline.addPolygon(v, p1, m, p2)
+						continue
+					}
+				}
+				outline.addPolygon(v, p1, p2)
+			}
+		}
+	}
+	return outline
+}
+
+// fillCoverage blends the color c over the pixels of the coverage map, weighted by their coverage (0 to 1).
+func (i *Image) fillCoverage(m coverageMap, c RGBA) {
+	for x := m.bounds.Min.X; x < m.bounds.Max.X; x++ {
+		for y := m.bounds.Min.Y; y < m.bounds.Max.Y; y++ {
+			v := m.at(x, y)
+			if v <= 0 {
+				continue
+			}
+			src := c
+			src.A = clampUint8(float64(c.A) * v)
+			i.Set(uint(x), uint(y), src.over(i.Pixel[x][y]))
+		}
+	}
+}
+
+// FillPath fills the area enclosed by the path with the given color, with anti-aliased edges.
+// Open sub-paths are treated as closed.
+//
+// path: The Path to fill, in image coordinates.
+// c: The color (RGBA) to fill with.
+// rule: The fill rule (NonZero or EvenOdd) deciding which overlapping areas are inside.
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) FillPath(path *Path, c RGBA, rule FillRule, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.FillPath(path, c, rule)
+	}) {
+		return
+	}
+	if t, ok := i.activeTransform(); ok {
+		path = path.transformed(t)
+	}
+	i.fillCoverage(path.coverage(i.Width, i.Height, rule), c)
+}
+
+// StrokePath draws the outline of the path with the given color and width, with anti-aliased edges.
+//
+// path: The Path to stroke, in image coordinates.
+// c: The color (RGBA) of the stroke.
+// width: The width of the stroke, in pixels.
+// lineCap: How the ends of open sub-paths are drawn (CapButt, CapRound, or CapSquare).
+// join: How connected segments are joined (JoinMiter, JoinRound, or JoinBevel).
+// opts: Optional settings; WithShadow casts a drop shadow underneath the shape, and WithBlendMode sets how it is blended with the image.
+func (i *Image) StrokePath(path *Path, c RGBA, width float64, lineCap LineCap, join LineJoin, opts ...Option) {
+	if i.layered(opts, func(layer *Image) {
+		layer.StrokePath(path, c, width, lineCap, join)
+	}) {
+		return
+	}
+	outline := path.strokeOutline(width, lineCap, join)
+	if t, ok := i.activeTransform(); ok {
+		outline = outline.transformed(t)
+	}
+	i.fillCoverage(outline.coverage(i.Width, i.Height, NonZero), c)
+}
+
+// DrawPolyline draws connected line segments through the points with the given color and thickness, with anti-aliased
+// edges. Unlike drawing every segment with Line, the segments are stroked as one path, so the corners are joined
+// without notches or overlapping blends, such as for the lines of a chart.
+// Like Line, the points refer to the centers of pixels.
+//
+// points: The points to connect, in order. At least two are needed.
+// c: The color (RGBA) of the line.
+// thickness: The thickness of the line, in pixels.
+// lineCap: How the two ends of the line are drawn (CapButt, CapRound, or CapSquare).
+// join: How the segments are joined at every point (JoinMiter, JoinRound, or JoinBevel).
+// opts: Optional settings; WithShadow casts a drop shadow underneath the line, and WithBlendMode sets how it is blended with the image.
+func (i *Image) DrawPolyline(points []Offset, c RGBA, thickness float64, lineCap LineCap, join LineJoin, opts ...Option) {
+	if len(points) < 2 || thickness <= 0 {
+		return
+	}
+	path := NewPath().MoveTo(float64(points[0].W)+0.5, float64(points[0].H)+0.5)
+	for _, p := range points[1:] {
+		path.LineTo(float64(p.W)+0.5, float64(p.H)+0.5)
+	}
+	i.StrokePath(path, c, thickness, lineCap, join, opts...)
+}
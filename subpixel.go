@@ -0,0 +1,64 @@
+package picrocess
+
+type OffsetF struct {
+	W float64
+	H float64
+}
+
+// NewOffsetF creates a new OffsetF struct using the provided fractional width (w) and height (h) values.
+// Unlike Offset, it can address positions between pixels, which keeps slow movement in animations smooth.
+//
+// w: The width value
+// h: The height value
+//
+// Returns: An OffsetF struct initialized with the given width and height.
+func NewOffsetF(w, h float64) OffsetF {
+	return OffsetF{
+		W: w,
+		H: h,
+	}
+}
+
+// OverlayF overlays the second image (i2) onto the image at a fractional offset (o).
+// Positions between pixels are rendered by resampling i2 with bilinear interpolation.
+//
+// i2: The image to overlay on top of the current image (i).
+// o: The fractional offset to position the second image on top of the first image (i).
+// opts: Optional settings, as for Overlay.
+func (i *Image) OverlayF(i2 *Image, o OffsetF, opts ...Option) {
+	i.Push()
+	i.Translate(o.W, o.H)
+	i.Overlay(i2, NewOffset(0, 0), opts...)
+	i.Pop()
+}
+
+// LineF draws an anti-aliased line between two fractional points with the given color and thickness.
+// Like Line, whole-number coordinates refer to the center of a pixel.
+//
+// from: The start point of the line.
+// to: The end point of the line.
+// c: The color (RGBA) to use for the line.
+// thickness: The thickness of the line.
+// opts: Optional settings, as for Line.
+func (i *Image) LineF(from, to OffsetF, c RGBA, thickness float64, opts ...Option) {
+	path := NewPath().MoveTo(from.W+0.5, from.H+0.5).LineTo(to.W+0.5, to.H+0.5)
+	i.StrokePath(path, c, thickness, CapButt, JoinMiter, opts...)
+}
+
+// TextF draws the specified text on the image at a fractional offset (o).
+// It works like Text, but positions between pixels are rendered with sub-pixel accuracy.
+//
+// font: The Font object to use for rendering the text.
+// c: The color (RGBA) to use for the text.
+// o: The fractional offset specifying where to draw the text on the image.
+// size: The font size to use for rendering the text.
+// text: The string of text to be drawn on the image.
+// opts: Optional settings, as for Text.
+//
+// Returns: An error if there is an issue rendering the text.
+func (i *Image) TextF(font *Font, c RGBA, o OffsetF, size float64, text string, opts ...Option) error {
+	i.Push()
+	defer i.Pop()
+	i.Translate(o.W, o.H)
+	return i.Text(font, c, NewOffset(0, 0), size, text, opts...)
+}
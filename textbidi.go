@@ -0,0 +1,261 @@
+package picrocess
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// arabicJoining is how an Arabic letter joins its neighbors.
+type arabicJoining int
+
+const (
+	// joinRight letters only join the letter before them, such as alef and waw.
+	joinRight arabicJoining = iota + 1
+	// joinDual letters join the letters on both sides, such as beh and lam.
+	joinDual
+	// joinCausing characters make their neighbors join without changing form themselves, such as tatweel.
+	joinCausing
+)
+
+// arabicForm is the isolated presentation form of an Arabic letter and how it joins. The final, initial,
+// and medial forms follow the isolated form, in that order, as they do in the Arabic Presentation Forms blocks.
+type arabicForm struct {
+	isolated rune
+	joining  arabicJoining
+}
+
+// arabicForms holds the presentation forms of the Arabic letters, including the ones used for Persian and Urdu.
+var arabicForms = map[rune]arabicForm{
+	0x0621: {0xFE80, 0},
+	0x0622: {0xFE81, joinRight},
+	0x0623: {0xFE83, joinRight},
+	0x0624: {0xFE85, joinRight},
+	0x0625: {0xFE87, joinRight},
+	0x0626: {0xFE89, joinDual},
+	0x0627: {0xFE8D, joinRight},
+	0x0628: {0xFE8F, joinDual},
+	0x0629: {0xFE93, joinRight},
+	0x062A: {0xFE95, joinDual},
+	0x062B: {0xFE99, joinDual},
+	0x062C: {0xFE9D, joinDual},
+	0x062D: {0xFEA1, joinDual},
+	0x062E: {0xFEA5, joinDual},
+	0x062F: {0xFEA9, joinRight},
+	0x0630: {0xFEAB, joinRight},
+	0x0631: {0xFEAD, joinRight},
+	0x0632: {0xFEAF, joinRight},
+	0x0633: {0xFEB1, joinDual},
+	0x0634: {0xFEB5, joinDual},
+	0x0635: {0xFEB9, joinDual},
+	0x0636: {0xFEBD, joinDual},
+	0x0637: {0xFEC1, joinDual},
+	0x0638: {0xFEC5, joinDual},
+	0x0639: {0xFEC9, joinDual},
+	0x063A: {0xFECD, joinDual},
+	0x0640: {0x0640, joinCausing},
+	0x0641: {0xFED1, joinDual},
+	0x0642: {0xFED5, joinDual},
+	0x0643: {0xFED9, joinDual},
+	0x0644: {0xFEDD, joinDual},
+	0x0645: {0xFEE1, joinDual},
+	0x0646: {0xFEE5, joinDual},
+	0x0647: {0xFEE9, joinDual},
+	0x0648: {0xFEED, joinRight},
+	0x0649: {0xFEEF, joinRight},
+	0x064A: {0xFEF1, joinDual},
+	0x067E: {0xFB56, joinDual},
+	0x0686: {0xFB7A, joinDual},
+	0x0698: {0xFB8A, joinRight},
+	0x06A9: {0xFB8E, joinDual},
+	0x06AF: {0xFB92, joinDual},
+	0x06CC: {0xFBFC, joinDual},
+}
+
+// lamAlef holds the isolated forms of the ligatures of lam with the alef that follows it.
+// The final form follows the isolated form.
+var lamAlef = map[rune]rune{
+	0x0622: 0xFEF5,
+	0x0623: 0xFEF7,
+	0x0625: 0xFEF9,
+	0x0627: 0xFEFB,
+}
+
+// isMark reports whether the rune is a combining mark, such as Arabic harakat or Hebrew niqqud, which belongs
+// to the character before it and does not take part in joining.
+func isMark(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// shapeArabic replaces the Arabic letters of text, in logical order, with the presentation forms that join them
+// to their neighbors, and lam followed by alef with their ligature. Letters whose form the font has no glyph for
+// are left as they are.
+func (f *Font) shapeArabic(runes []rune) []rune {
+	// joiningAt returns how the nearest letter in the given direction from k joins, skipping combining marks.
+	joiningAt := func(k, step int) arabicJoining {
+		for k += step; k >= 0 && k < len(runes); k += step {
+			if !isMark(runes[k]) {
+				return arabicForms[runes[k]].joining
+			}
+		}
+		return 0
+	}
+	shaped := make([]rune, 0, len(runes))
+	for k := 0; k < len(runes); k++ {
+		r := runes[k]
+		form, ok := arabicForms[r]
+		if !ok || form.joining == 0 || form.joining == joinCausing {
+			shaped = append(shaped, r)
+			continue
+		}
+		before := joiningAt(k, -1)
+		joinsBefore := before == joinDual || before == joinCausing
+		if r == 0x0644 && k+1 < len(runes) {
+			if ligature, ok := lamAlef[runes[k+1]]; ok {
+				if joinsBefore {
+					ligature++
+				}
+				if f.fontFor(ligature).hasGlyph(ligature) {
+					shaped = append(shaped, ligature)
+					k++
+					continue
+				}
+			}
+		}
+		joinsAfter := form.joining == joinDual && joiningAt(k, 1) != 0
+		shape := form.isolated
+		switch {
+		case joinsBefore && joinsAfter:
+			shape += 3
+		case joinsAfter:
+			shape += 2
+		case joinsBefore:
+			shape++
+		}
+		if !f.fontFor(shape).hasGlyph(shape) {
+			shape = r
+		}
+		shaped = append(shaped, shape)
+	}
+	return shaped
+}
+
+// isRightToLeft reports whether the rune is written from right to left, such as Hebrew and Arabic letters.
+func isRightToLeft(r rune) bool {
+	props, _ := bidi.LookupRune(r)
+	class := props.Class()
+	return class == bidi.R || class == bidi.AL
+}
+
+// reverseRun reverses the runes of a right-to-left run into visual order. Combining marks stay after the character
+// they belong to, emoji sequences stay together, and brackets are mirrored so that they still enclose their text.
+func reverseRun(runes []rune) []rune {
+	// Split the run into clusters that are drawn as one.
+	var clusters [][]rune
+	for k := 0; k < len(runes); k++ {
+		r := runes[k]
+		joined := len(clusters) > 0 && (isMark(r) || r == 0x200D || r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF) ||
+			(k > 0 && runes[k-1] == 0x200D))
+		if joined {
+			clusters[len(clusters)-1] = append(clusters[len(clusters)-1], r)
+			continue
+		}
+		if props, _ := bidi.LookupRune(r); props.IsBracket() {
+			r = []rune(bidi.ReverseString(string(r)))[0]
+		}
+		clusters = append(clusters, []rune{r})
+	}
+	reversed := make([]rune, 0, len(runes))
+	for k := len(clusters) - 1; k >= 0; k-- {
+		reversed = append(reversed, clusters[k]...)
+	}
+	return reversed
+}
+
+// isNumber reports whether the runes are a number, with digits but no left-to-right letters.
+func isNumber(runes []rune) bool {
+	digits := false
+	for _, r := range runes {
+		switch props, _ := bidi.LookupRune(r); props.Class() {
+		case bidi.L:
+			return false
+		case bidi.EN, bidi.AN:
+			digits = true
+		}
+	}
+	return digits
+}
+
+// visualLine returns a line of text in the order its characters are drawn from left to right: Arabic letters
+// are shaped, and right-to-left runs, such as Hebrew and Arabic words, are reversed by the Unicode bidirectional
+// algorithm, so that mixed lines keep their left-to-right words and numbers in reading order. The direction of
+// the line is that of its first Hebrew, Arabic, or Latin letter. Lines without right-to-left text are returned as they are.
+func (f *Font) visualLine(line string) string {
+	runes := []rune(line)
+	rightToLeft := false
+	for _, r := range runes {
+		if isRightToLeft(r) {
+			rightToLeft = true
+			break
+		}
+	}
+	if !rightToLeft {
+		return line
+	}
+	shaped := string(f.shapeArabic(runes))
+	var paragraph bidi.Paragraph
+	if _, err := paragraph.SetString(shaped); err != nil {
+		return shaped
+	}
+	ordering, err := paragraph.Order()
+	if err != nil {
+		return shaped
+	}
+	// The paragraph takes the direction of its first strong character.
+	baseRightToLeft := false
+	for _, r := range runes {
+		props, _ := bidi.LookupRune(r)
+		if class := props.Class(); class == bidi.L || class == bidi.R || class == bidi.AL {
+			baseRightToLeft = class != bidi.L
+			break
+		}
+	}
+	// Give the runs their embedding levels: right-to-left runs are odd, and numbers inside right-to-left text
+	// are nested one level deeper, as are all left-to-right runs of a right-to-left line.
+	runs := make([][]rune, ordering.NumRuns())
+	levels := make([]int, len(runs))
+	for k := range runs {
+		run := ordering.Run(k)
+		runs[k] = []rune(run.String())
+		switch {
+		case run.Direction() == bidi.RightToLeft:
+			levels[k] = 1
+		case baseRightToLeft || (k > 0 && levels[k-1] == 1 && isNumber(runs[k])):
+			levels[k] = 2
+		}
+	}
+	// Reverse every sequence of runs at each level or deeper, from the deepest level up to level 1.
+	for level := 2; level >= 1; level-- {
+		for k := 0; k < len(runs); {
+			if levels[k] < level {
+				k++
+				continue
+			}
+			end := k
+			for end < len(runs) && levels[end] >= level {
+				runs[end] = reverseRun(runs[end])
+				end++
+			}
+			for a, b := k, end-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			k = end
+		}
+	}
+	visual := make([]rune, 0, len(runes))
+	for _, run := range runs {
+		visual = append(visual, run...)
+	}
+	return string(visual)
+}
@@ -0,0 +1,164 @@
+package picrocess
+
+import (
+	"math"
+	"strings"
+)
+
+// WithEllipsis makes TextBox end the last line that fits with an ellipsis ("…") when the text is too long for the box,
+// so readers can tell that it was cut.
+func WithEllipsis() Option {
+	return func(o *Options) {
+		o.ellipsis = true
+	}
+}
+
+// wrapText breaks text into lines no wider than width, breaking between words where it can and inside words
+// that are wider than a whole line. Line breaks in the text ("\n") are kept.
+func wrapText(font *Font, size float64, text string, width uint) []string {
+	fits := func(s string) bool {
+		w, _ := font.TextSize(size, s)
+		return w <= width
+	}
+	var lines []string
+	for _, paragraph := range textLines(text) {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if fits(candidate) {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Break words that do not fit on a line of their own.
+			line = ""
+			for _, r := range word {
+				if line != "" && !fits(line+string(r)) {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ellipsize shortens the line until it fits within width with an ellipsis appended, and appends the ellipsis.
+func ellipsize(font *Font, size float64, line string, width uint) string {
+	ellipsis := "…"
+	if !font.fontFor('…').hasGlyph('…') {
+		ellipsis = "..."
+	}
+	runes := []rune(strings.TrimRight(line, " "))
+	for len(runes) > 0 {
+		if w, _ := font.TextSize(size, string(runes)+ellipsis); w <= width {
+			break
+		}
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ") + ellipsis
+}
+
+// TextBox draws text inside a box, wrapping the words onto as many lines as fit the width of the box,
+// such as for user-generated strings of unpredictable length. Lines that do not fit the height of the box
+// are left out, and nothing is drawn outside of the box.
+//
+// font: The Font object to use for rendering the text.
+// c: The color (RGBA) to use for the text.
+// box: The rectangle to draw the text in.
+// size: The font size to use for rendering the text.
+// text: The text to draw. Line breaks ("\n") start a new line.
+// opts: Optional settings; WithAnchor places the text within the box (by default, at its top-left corner) and aligns
+// the lines, such as AnchorCenter to center them both ways, WithEllipsis ends the text with "…" when it is cut,
+// and the other options of Text, such as WithLineHeight or WithBackdrop, apply as they do for Text.
+//
+// Returns: An error if there is an issue rendering the text.
+func (i *Image) TextBox(font *Font, c RGBA, box Rect, size float64, text string, opts ...Option) error {
+	if box.Dx() == 0 || box.Dy() == 0 || size <= 0 {
+		return nil
+	}
+	options := NewOptions(opts...)
+	lines := wrapText(font, size, text, box.Dx())
+	// Keep the lines whose glyphs fit above the bottom of the box; the first line is always kept.
+	step := size * options.LineHeight
+	descent := font.MeasureText(size, "", opts...).Descent
+	visible := 1
+	for visible < len(lines) && size+float64(visible)*step+descent <= float64(box.Dy()) {
+		visible++
+	}
+	if visible < len(lines) {
+		lines = lines[:visible]
+		if options.ellipsis {
+			lines[visible-1] = ellipsize(font, size, lines[visible-1], box.Dx())
+		}
+	}
+	fx, fy := options.Anchor.fractions()
+	height := size + float64(len(lines)-1)*step + descent
+	x := float64(box.W1) + math.Round(float64(box.Dx())*fx)
+	y := float64(box.H1) + math.Max(0, math.Round((float64(box.Dy())-height)*fy))
+	i.PushClip(box)
+	defer i.PopClip()
+	// Place the block by its top edge, since its height is already known, and keep the horizontal anchor for aligning the lines.
+	return i.Text(font, c, NewOffset(uint(x), uint(y)), size, strings.Join(lines, "\n"), append(opts, WithAnchor(options.Anchor%3))...)
+}
+
+// FitSize finds the largest font size at which text fits within the given width and height, with a binary search
+// over the measured size of the text.
+//
+// w: The width the text has to fit in, in pixels.
+// h: The height the text has to fit in, in pixels.
+// text: The text to fit. Line breaks ("\n") start a new line.
+// opts: Optional settings; WithLineHeight sets the distance between lines, as for Text.
+//
+// Returns: The largest font size, in steps of a quarter pixel, at which the text fits, or 0 if it does not fit at any size.
+func (f *Font) FitSize(w, h uint, text string, opts ...Option) float64 {
+	fits := func(size float64) bool {
+		width, height := f.TextSize(size, text, opts...)
+		return width <= w && height <= h
+	}
+	// The text is at least one font size tall, so the height bounds the search. Sizes are searched in quarter pixels.
+	lo, hi := 0, int(h)*4
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if fits(float64(mid) / 4) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return float64(lo) / 4
+}
+
+// TextFit draws text inside a box at the largest font size at which it fits, such as for usernames and titles
+// of unpredictable length. The text is not wrapped; line breaks ("\n") start a new line.
+//
+// font: The Font object to use for rendering the text.
+// c: The color (RGBA) to use for the text.
+// box: The rectangle to fit the text in.
+// text: The text to draw.
+// opts: Optional settings; WithAnchor places the text within the box (by default, at its top-left corner), such as
+// AnchorCenter to center it, and the other options of Text apply as they do for Text.
+//
+// Returns: The font size the text was drawn at, or 0 if it does not fit at any size, and an error if there is
+// an issue rendering the text.
+func (i *Image) TextFit(font *Font, c RGBA, box Rect, text string, opts ...Option) (float64, error) {
+	size := font.FitSize(box.Dx(), box.Dy(), text, opts...)
+	if size <= 0 {
+		return 0, nil
+	}
+	options := NewOptions(opts...)
+	width, height := font.TextSize(size, text, opts...)
+	fx, fy := options.Anchor.fractions()
+	x := float64(box.W1) + math.Round(float64(box.Dx()-width)*fx)
+	y := float64(box.H1) + math.Round(float64(box.Dy()-height)*fy)
+	// Place the text by its top-left corner, and keep the horizontal anchor for aligning the lines.
+	x += math.Round(float64(width) * fx)
+	return size, i.Text(font, c, NewOffset(uint(x), uint(y)), size, text, append(opts, WithAnchor(options.Anchor%3))...)
+}
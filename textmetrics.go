@@ -0,0 +1,202 @@
+package picrocess
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextMetrics describes the size of text drawn with a font, in pixels.
+type TextMetrics struct {
+	// Width is the advance width of the widest line, including kerning.
+	Width float64
+	// Height is the distance from the top of the text, the offset given to Text, to the bottom of the descenders
+	// of its last line.
+	Height float64
+	// Ascent is how far the tallest glyphs of the font reach above the baseline.
+	Ascent float64
+	// Descent is how far the glyphs of the font reach below the baseline, such as the tails of g, y, and p.
+	Descent float64
+	// LineHeight is the distance between the baselines of lines.
+	LineHeight float64
+	// Lines is the number of lines of the text.
+	Lines int
+}
+
+// lineAdvance returns how far the pen moves across a line of text, including kerning.
+func lineAdvance(face font.Face, line string) fixed.Int26_6 {
+	var advance fixed.Int26_6
+	prev := rune(-1)
+	for _, r := range line {
+		if prev >= 0 {
+			advance += face.Kern(prev, r)
+		}
+		a, _ := face.GlyphAdvance(r)
+		advance += a
+		prev = r
+	}
+	return advance
+}
+
+// textRun is a part of a line of text that is drawn in one go: either text drawn with one font of a fallback chain,
+// or an emoji image.
+type textRun struct {
+	text    string
+	font    *Font
+	face    font.Face
+	emoji   *Image
+	advance fixed.Int26_6
+}
+
+// layoutLine splits a line of text into runs of text in the fonts that have their glyphs and runs of emoji images,
+// with how far the pen moves across each, in the order they are drawn from left to right. Emoji images are one font size wide.
+func (f *Font) layoutLine(face font.Face, size float64, line string) []textRun {
+	line = f.visualLine(line)
+	if f.emoji == nil && len(f.fallbacks) == 0 {
+		return []textRun{{text: line, font: f, face: face, advance: lineAdvance(face, line)}}
+	}
+	faces := map[*Font]font.Face{f: face}
+	runes := []rune(line)
+	var runs []textRun
+	var current *Font
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			text := string(runes[start:end])
+			runs = append(runs, textRun{text: text, font: current, face: faces[current], advance: lineAdvance(faces[current], text)})
+		}
+		start = end
+	}
+	for k := 0; k < len(runes); {
+		if f.emoji != nil {
+			if emoji, n := f.emoji.match(runes[k:]); emoji != nil {
+				flush(k)
+				runs = append(runs, textRun{emoji: emoji, advance: fixed.Int26_6(math.Round(size * 64))})
+				k += n
+				start = k
+				continue
+			}
+		}
+		if source := f.fontFor(runes[k]); source != current {
+			flush(k)
+			current = source
+			if faces[source] == nil {
+				faces[source] = source.newFace(size)
+			}
+		}
+		k++
+	}
+	flush(len(runes))
+	return runs
+}
+
+// emojiRect returns the square an emoji is drawn in for a run starting at the dot: one font size wide,
+// centered between the ascent and descent of the font.
+func emojiRect(metrics font.Metrics, size float64, dot fixed.Point26_6) image.Rectangle {
+	side := int(math.Round(size))
+	top := (dot.Y - (metrics.Ascent-metrics.Descent)/2).Round() - side/2
+	return image.Rect(dot.X.Round(), top, dot.X.Round()+side, top+side)
+}
+
+// MeasureText measures text as Text draws it: the advance width including kerning, the ascent and descent
+// of the font, and the height of all lines down to the descenders of the last one.
+//
+// size: The font size to use for measuring the text.
+// text: The text to measure. Line breaks ("\n") start a new line.
+// opts: Optional settings; WithLineHeight sets the distance between lines, as for Text.
+//
+// Returns: The metrics of the text.
+func (f *Font) MeasureText(size float64, text string, opts ...Option) TextMetrics {
+	face := f.newFace(size)
+	metrics := face.Metrics()
+	lines := textLines(text)
+	m := TextMetrics{
+		Ascent:     float64(metrics.Ascent) / 64,
+		Descent:    float64(metrics.Descent) / 64,
+		LineHeight: size * NewOptions(opts...).LineHeight,
+		Lines:      len(lines),
+	}
+	for _, line := range lines {
+		var advance fixed.Int26_6
+		for _, run := range f.layoutLine(face, size, line) {
+			advance += run.advance
+		}
+		m.Width = math.Max(m.Width, float64(advance)/64)
+	}
+	// Text draws the first baseline one font size below its offset.
+	m.Height = size + float64(len(lines)-1)*m.LineHeight + m.Descent
+	return m
+}
+
+// textOrigin returns where the top-left corner of text is drawn so that the anchor of the options lands on the offset.
+func (f *Font) textOrigin(o Offset, size float64, text string, options Options, opts []Option) (float64, float64) {
+	x, y := float64(o.W), float64(o.H)
+	if options.Anchor != AnchorTopLeft {
+		width, height := f.TextSize(size, text, opts...)
+		fx, fy := options.Anchor.fractions()
+		x -= math.Round(float64(width) * fx)
+		y -= math.Round(float64(height) * fy)
+	}
+	return x, y
+}
+
+// TextBounds returns the rectangle that the glyphs of text cover when drawn with Text at the offset, from the top
+// of the tallest glyph to the bottom of the lowest descender, such as for hit boxes or for checking what text overlaps.
+// The rectangle is in image coordinates, without the transformation of an image, and is clamped at 0.
+//
+// o: The offset the text is drawn at.
+// size: The font size to use for measuring the text.
+// text: The text to measure. Line breaks ("\n") start a new line.
+// opts: Optional settings; WithAnchor and WithLineHeight place the text as they do for Text.
+//
+// Returns: The rectangle covered by the glyphs, or an empty rectangle at the offset if the text draws nothing.
+func (f *Font) TextBounds(o Offset, size float64, text string, opts ...Option) Rect {
+	options := NewOptions(opts...)
+	x, y := f.textOrigin(o, size, text, options, opts)
+	fx, _ := options.Anchor.fractions()
+	face := f.newFace(size)
+	lines := textLines(text)
+	step := size * options.LineHeight
+	width, _ := f.TextSize(size, text, opts...)
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for k, line := range lines {
+		// Place every line the way drawTextLines does.
+		lineWidth, _ := f.TextSize(size, line)
+		dot := fixed.P(int(x+math.Round(float64(width-lineWidth)*fx)), int(y+float64(k)*step)+int(size))
+		for _, run := range f.layoutLine(face, size, line) {
+			if run.emoji != nil {
+				r := emojiRect(face.Metrics(), size, dot)
+				minX, minY = math.Min(minX, float64(r.Min.X)), math.Min(minY, float64(r.Min.Y))
+				maxX, maxY = math.Max(maxX, float64(r.Max.X)), math.Max(maxY, float64(r.Max.Y))
+				dot.X += run.advance
+				continue
+			}
+			glyphDot := dot
+			prev := rune(-1)
+			for _, r := range run.text {
+				if prev >= 0 {
+					glyphDot.X += run.face.Kern(prev, r)
+				}
+				bounds, advance, _ := run.face.GlyphBounds(r)
+				if bounds.Max.X > bounds.Min.X && bounds.Max.Y > bounds.Min.Y {
+					minX = math.Min(minX, float64(glyphDot.X+bounds.Min.X)/64)
+					minY = math.Min(minY, float64(glyphDot.Y+bounds.Min.Y)/64)
+					maxX = math.Max(maxX, float64(glyphDot.X+bounds.Max.X)/64)
+					maxY = math.Max(maxY, float64(glyphDot.Y+bounds.Max.Y)/64)
+				}
+				glyphDot.X += advance
+				prev = r
+			}
+			dot.X += run.advance
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return NewRect(o.W, o.H, o.W, o.H)
+	}
+	clamp := func(v float64) uint {
+		return uint(math.Max(0, v))
+	}
+	return NewRect(clamp(math.Floor(minX)), clamp(math.Floor(minY)), clamp(math.Ceil(maxX)), clamp(math.Ceil(maxY)))
+}
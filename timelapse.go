@@ -0,0 +1,126 @@
+package picrocess
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// FrameWithTime is a captured image together with the time it was taken.
+type FrameWithTime struct {
+	Image *Image
+	Time  time.Time
+}
+
+// TimelapseOptions configures how Timelapse assembles its frames.
+type TimelapseOptions struct {
+	// Delay is the delay of every frame, in 100ths of a second. If it is 0, AnimateDelay is used.
+	Delay int
+	// NormalizeExposure evens out the brightness of the frames, removing flicker from changing light or auto-exposure.
+	NormalizeExposure bool
+	// Font is used to burn the capture time into every frame. If it is nil, no timestamp is drawn.
+	Font *Font
+	// TimestampFormat is the time layout of the label, as used by time.Format. If it is empty, "2006-01-02 15:04" is used.
+	TimestampFormat string
+	// TimestampSize is the font size of the label. If it is 0, 16 is used.
+	TimestampSize float64
+	// TimestampColor is the color of the label. If it is fully transparent, white is used.
+	TimestampColor RGBA
+	// TimestampAnchor is the corner or edge of the frame the label is placed at.
+	TimestampAnchor Anchor
+}
+
+// Timelapse assembles captured frames into an animated GIF, ordered by the time they were taken.
+// The input images are not modified.
+//
+// frames: The captured frames, in any order. Frames without an image are skipped.
+// opts: The options of the time-lapse.
+//
+// Returns: A new GIF containing one frame per capture, or an error if the Renderer fails to render a frame.
+func Timelapse(frames []FrameWithTime, opts TimelapseOptions) (*GIF, error) {
+	sorted := make([]FrameWithTime, 0, len(frames))
+	for _, f := range frames {
+		if f.Image != nil {
+			sorted = append(sorted, f)
+		}
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return sorted[a].Time.Before(sorted[b].Time)
+	})
+	delay := opts.Delay
+	if delay <= 0 {
+		delay = AnimateDelay
+	}
+	var gains []float64
+	if opts.NormalizeExposure {
+		gains = exposureGains(sorted)
+	}
+	gf := NewGIF()
+	for k, f := range sorted {
+		frame := f.Image.clone()
+		if gains != nil {
+			frame.scaleChannels(gains[k], gains[k], gains[k])
+		}
+		if opts.Font != nil {
+			// A label that cannot be rendered is left out rather than dropping the frame.
+			_ = frame.burnTimestamp(f.Time, opts)
+		}
+		if err := gf.Append(frame, delay); err != nil {
+			return nil, err
+		}
+	}
+	return gf, nil
+}
+
+// exposureGains returns the brightness factor of every frame that brings its mean luminance
+// to the mean of all frames. Factors are limited to between 0.5 and 2 so dark scenes are not blown out.
+func exposureGains(frames []FrameWithTime) []float64 {
+	means := make([]float64, len(frames))
+	total := 0.0
+	for k, f := range frames {
+		lum := f.Image.luminance()
+		sum, n := 0.0, 0
+		for x := range lum {
+			for y := range lum[x] {
+				sum += lum[x][y]
+				n++
+			}
+		}
+		if n > 0 {
+			means[k] = sum / float64(n)
+		}
+		total += means[k]
+	}
+	target := total / float64(max(len(frames), 1))
+	gains := make([]float64, len(frames))
+	for k, m := range means {
+		gains[k] = 1
+		if m > 0 {
+			gains[k] = math.Max(0.5, math.Min(2, target/m))
+		}
+	}
+	return gains
+}
+
+// burnTimestamp draws the capture time onto the frame as configured by the options.
+func (i *Image) burnTimestamp(t time.Time, opts TimelapseOptions) error {
+	layout := opts.TimestampFormat
+	if layout == "" {
+		layout = "2006-01-02 15:04"
+	}
+	size := opts.TimestampSize
+	if size <= 0 {
+		size = 16
+	}
+	c := opts.TimestampColor
+	if c.A == 0 {
+		c = RGBA{255, 255, 255, 255}
+	}
+	margin := uint(size / 2)
+	fx, fy := opts.TimestampAnchor.fractions()
+	o := NewOffset(
+		margin+uint(fx*float64(i.Width-min(i.Width, 2*margin))),
+		margin+uint(fy*float64(i.Height-min(i.Height, 2*margin))),
+	)
+	return i.Text(opts.Font, c, o, size, t.Format(layout), WithAnchor(opts.TimestampAnchor))
+}
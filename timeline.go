@@ -0,0 +1,234 @@
+package picrocess
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+type LayerProperty int
+
+const (
+	// PropertyX is the horizontal position of the layer's center.
+	PropertyX LayerProperty = iota
+	// PropertyY is the vertical position of the layer's center.
+	PropertyY
+	// PropertyOpacity is the opacity of the layer, from 0 (invisible) to 1 (fully visible).
+	PropertyOpacity
+	// PropertyRotation is the clockwise rotation of the layer around its center, in degrees.
+	PropertyRotation
+	// PropertyScale is the uniform scale of the layer around its center (1 is the original size).
+	PropertyScale
+	// PropertyHue is the hue shift applied to the layer's colors, in degrees.
+	PropertyHue
+)
+
+type Keyframe struct {
+	Time  float64 // Seconds from the start of the timeline
+	Value float64
+	Ease  Easing // Easing used when moving from the previous keyframe to this one, Linear if nil
+}
+
+// Layer is an image placed on a Timeline, with properties that can be animated with keyframes.
+type Layer struct {
+	Image                               *Image
+	X, Y, Opacity, Rotation, Scale, Hue float64
+	Effects                             []LayerEffect
+	keyframes                           map[LayerProperty][]Keyframe
+}
+
+// NewLayer creates a new Layer showing the given image, fully opaque, unrotated, and at its original size.
+// The layer is centered at (0, 0) until its position is set or keyframed.
+//
+// img: The image shown by the layer.
+//
+// Returns: A pointer to a new Layer.
+func NewLayer(img *Image) *Layer {
+	return &Layer{
+		Image:     img,
+		Opacity:   1,
+		Scale:     1,
+		Effects:   make([]LayerEffect, 0),
+		keyframes: make(map[LayerProperty][]Keyframe),
+	}
+}
+
+// Keyframe sets the value of a layer property at a point in time. Between keyframes the value is
+// interpolated with the easing of the later keyframe; before the first and after the last keyframe it holds still.
+//
+// property: The property to animate.
+// time: The time of the keyframe, in seconds from the start of the timeline.
+// value: The value of the property at that time.
+// ease: The easing used when moving towards this keyframe (nil for linear).
+//
+// Returns: The layer, so calls can be chained.
+func (l *Layer) Keyframe(property LayerProperty, time, value float64, ease Easing) *Layer {
+	if l.keyframes == nil {
+		l.keyframes = make(map[LayerProperty][]Keyframe)
+	}
+	frames := append(l.keyframes[property], Keyframe{Time: time, Value: value, Ease: ease})
+	sort.SliceStable(frames, func(a, b int) bool {
+		return frames[a].Time < frames[b].Time
+	})
+	l.keyframes[property] = frames
+	return l
+}
+
+// ValueAt returns the value of a layer property at the given time, taking keyframes into account.
+// Properties without keyframes return the value stored in the layer.
+//
+// property: The property to look up.
+// time: The time, in seconds from the start of the timeline.
+//
+// Returns: The value of the property at that time.
+func (l *Layer) ValueAt(property LayerProperty, time float64) float64 {
+	frames := l.keyframes[property]
+	if len(frames) == 0 {
+		switch property {
+		case PropertyX:
+			return l.X
+		case PropertyY:
+			return l.Y
+		case PropertyOpacity:
+			return l.Opacity
+		case PropertyRotation:
+			return l.Rotation
+		case PropertyHue:
+			return l.Hue
+		default:
+			return l.Scale
+		}
+	}
+	if time <= frames[0].Time {
+		return frames[0].Value
+	}
+	for k := 1; k < len(frames); k++ {
+		if time > frames[k].Time {
+			continue
+		}
+		prev, next := frames[k-1], frames[k]
+		span := next.Time - prev.Time
+		if span <= 0 {
+			return next.Value
+		}
+		return Interpolate(prev.Value, next.Value, (time-prev.Time)/span, next.Ease)
+	}
+	return frames[len(frames)-1].Value
+}
+
+// valueAt returns the value of a layer property at the given time like ValueAt, unless overrides sets it,
+// such as to a value driven by audio.
+func (l *Layer) valueAt(property LayerProperty, time float64, overrides map[LayerProperty]float64) float64 {
+	if v, ok := overrides[property]; ok {
+		return v
+	}
+	return l.ValueAt(property, time)
+}
+
+// drawOn draws the layer onto the image with its properties evaluated at the given time.
+// The properties set in overrides take their value from there instead.
+func (l *Layer) drawOn(img *Image, source *Image, time float64, overrides map[LayerProperty]float64) {
+	opacity := math.Max(0, math.Min(1, l.valueAt(PropertyOpacity, time, overrides)))
+	if source == nil || opacity == 0 {
+		return
+	}
+	if hue := l.valueAt(PropertyHue, time, overrides); hue != 0 {
+		source = source.clone()
+		source.AdjustHSL(hue, 1, 1)
+	}
+	if opacity < 1 {
+		source = source.clone()
+		for x := range source.Pixel {
+			for y := range source.Pixel[x] {
+				source.Pixel[x][y].A = uint8(float64(source.Pixel[x][y].A) * opacity)
+			}
+		}
+	}
+	scale := l.valueAt(PropertyScale, time, overrides)
+	t, _ := img.activeTransform()
+	t = t.Translate(l.valueAt(PropertyX, time, overrides), l.valueAt(PropertyY, time, overrides)).
+		Rotate(l.valueAt(PropertyRotation, time, overrides)).
+		Scale(scale, scale).
+		Translate(-float64(source.Width)/2, -float64(source.Height)/2)
+	img.drawTransformed(source, t)
+}
+
+// frameDelay returns the delay of frame k at the given frame rate, in hundredths of a second. It is the difference
+// between the rounded start times of the next frame and this one, so the delays add up to the length of the animation
+// instead of drifting, such as 3, 4, and 3 at 30 frames per second.
+func frameDelay(k, fps int) int {
+	return int(math.Round(float64(k+1)*100/float64(fps))) - int(math.Round(float64(k)*100/float64(fps)))
+}
+
+// Timeline is a scene of layers whose properties are animated over time, rendered into GIF frames.
+type Timeline struct {
+	Width, Height uint
+	Background    RGBA
+	Layers        []*Layer
+}
+
+// NewTimeline creates a new, empty Timeline with the given frame size and background color.
+//
+// w: The width of every rendered frame.
+// h: The height of every rendered frame.
+// background: The color every frame is filled with before the layers are drawn.
+//
+// Returns: A pointer to a new Timeline.
+func NewTimeline(w, h uint, background RGBA) *Timeline {
+	return &Timeline{
+		Width:      w,
+		Height:     h,
+		Background: background,
+		Layers:     make([]*Layer, 0),
+	}
+}
+
+// Add places a layer on top of all layers already on the timeline.
+//
+// Returns: The added layer, so keyframes can be chained.
+func (tl *Timeline) Add(l *Layer) *Layer {
+	tl.Layers = append(tl.Layers, l)
+	return l
+}
+
+// renderFrame draws all layers at the given time, using the prepared layer images (with effects applied).
+// The layer properties set in overrides, by layer, take their value from there instead of their keyframes.
+func (tl *Timeline) renderFrame(sources []*Image, time float64, overrides map[*Layer]map[LayerProperty]float64) *Image {
+	frame := NewImage(tl.Width, tl.Height, tl.Background)
+	for k, l := range tl.Layers {
+		l.drawOn(frame, sources[k], time, overrides[l])
+	}
+	return frame
+}
+
+// prepare returns the image of every layer with its layer effects applied.
+func (tl *Timeline) prepare() []*Image {
+	sources := make([]*Image, len(tl.Layers))
+	for k, l := range tl.Layers {
+		sources[k] = l.Image
+		if l.Image != nil && len(l.Effects) > 0 {
+			sources[k] = l.Image.clone()
+			sources[k].ApplyEffects(l.Effects...)
+		}
+	}
+	return sources
+}
+
+// RenderFrame renders the timeline at a single point in time.
+//
+// time: The time, in seconds from the start of the timeline.
+//
+// Returns: A new Image of the scene at that time.
+func (tl *Timeline) RenderFrame(time float64) *Image {
+	return tl.renderFrame(tl.prepare(), time, nil)
+}
+
+// RenderTimeline renders the timeline into an animated GIF.
+//
+// fps: The number of frames per second.
+// duration: The length of the animation, in seconds.
+//
+// Returns: A new GIF containing one frame per time step, or an error if the Renderer fails to render a frame.
+func (tl *Timeline) RenderTimeline(fps int, duration float64) (*GIF, error) {
+	return tl.RenderTimelineContext(context.Background(), fps, duration)
+}
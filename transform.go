@@ -0,0 +1,236 @@
+package picrocess
+
+import "math"
+
+// Transform is a 2D affine transformation matrix.
+// A point (x, y) is mapped to (A*x + C*y + E, B*x + D*y + F).
+type Transform struct {
+	A, B, C, D, E, F float64
+}
+
+// NewTransform creates an identity Transform, which leaves every point unchanged.
+//
+// Returns: The identity Transform.
+func NewTransform() Transform {
+	return Transform{A: 1, D: 1}
+}
+
+// Multiply combines two transformations. The resulting Transform applies t2 first and then t.
+//
+// t2: The transformation to apply before t.
+//
+// Returns: The combined Transform.
+func (t Transform) Multiply(t2 Transform) Transform {
+	return Transform{
+		A: t.A*t2.A + t.C*t2.B,
+		B: t.B*t2.A + t.D*t2.B,
+		C: t.A*t2.C + t.C*t2.D,
+		D: t.B*t2.C + t.D*t2.D,
+		E: t.A*t2.E + t.C*t2.F + t.E,
+		F: t.B*t2.E + t.D*t2.F + t.F,
+	}
+}
+
+// Translate returns the Transform moved by (x, y) before the existing transformation is applied.
+func (t Transform) Translate(x, y float64) Transform {
+	return t.Multiply(Transform{A: 1, D: 1, E: x, F: y})
+}
+
+// Scale returns the Transform scaled by (sx, sy) before the existing transformation is applied.
+func (t Transform) Scale(sx, sy float64) Transform {
+	return t.Multiply(Transform{A: sx, D: sy})
+}
+
+// Rotate returns the Transform rotated clockwise by the given angle, in degrees,
+// before the existing transformation is applied.
+func (t Transform) Rotate(deg float64) Transform {
+	rad := deg * math.Pi / 180
+	sin, cos := math.Sincos(rad)
+	return t.Multiply(Transform{A: cos, B: sin, C: -sin, D: cos})
+}
+
+// Apply maps the point (x, y) through the transformation.
+//
+// Returns: The transformed x and y coordinates.
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	return t.A*x + t.C*y + t.E, t.B*x + t.D*y + t.F
+}
+
+// Invert returns the inverse transformation, which maps transformed points back to their original position.
+// If the Transform cannot be inverted (for example, when scaled by 0), the identity Transform is returned.
+//
+// Returns: The inverse Transform.
+func (t Transform) Invert() Transform {
+	det := t.A*t.D - t.B*t.C
+	if det == 0 {
+		return NewTransform()
+	}
+	return Transform{
+		A: t.D / det,
+		B: -t.B / det,
+		C: -t.C / det,
+		D: t.A / det,
+		E: (t.C*t.F - t.D*t.E) / det,
+		F: (t.B*t.E - t.A*t.F) / det,
+	}
+}
+
+// activeTransform returns the transformation applied to draw operations, combining the scale factor
+// of the image with the current transformation, and whether there is any (false means drawing happens
+// in plain image coordinates).
+func (i *Image) activeTransform() (Transform, bool) {
+	t := NewTransform()
+	scaled := i.scaleFactor > 0 && i.scaleFactor != 1
+	if scaled {
+		t = t.Scale(i.scaleFactor, i.scaleFactor)
+	}
+	if i.transform == nil {
+		return t, scaled
+	}
+	return t.Multiply(*i.transform), true
+}
+
+// currentTransform returns the transformation set with Translate, Scale, and Rotate, without the scale factor.
+func (i *Image) currentTransform() Transform {
+	if i.transform == nil {
+		return NewTransform()
+	}
+	return *i.transform
+}
+
+// Translate moves the origin of all following draw operations (lines, paths, text, overlays, and clips) by (x, y).
+// The movement is applied in the current transformed coordinate system.
+func (i *Image) Translate(x, y float64) {
+	t := i.currentTransform().Translate(x, y)
+	i.transform = &t
+}
+
+// Scale scales all following draw operations by (sx, sy) around the current origin.
+// It does not resize the image itself; use Resize for that.
+func (i *Image) Scale(sx, sy float64) {
+	t := i.currentTransform().Scale(sx, sy)
+	i.transform = &t
+}
+
+// Rotate rotates all following draw operations clockwise by the given angle, in degrees, around the current origin.
+// It does not rotate the image itself; use Rotate90 or RotateMinus90 for that.
+func (i *Image) Rotate(deg float64) {
+	t := i.currentTransform().Rotate(deg)
+	i.transform = &t
+}
+
+// NewScaledImage creates a new Image for high-density (retina) output. The image is laid out with the
+// given logical width and height, but its pixels are allocated at scaleFactor times that size, and all
+// draw operations (coordinates, stroke widths, and font sizes) are scaled to match, so layout code written
+// for a 1x image renders sharply at 2x or 3x without changes.
+//
+// w: The logical width of the image.
+// h: The logical height of the image.
+// color: The color to fill each pixel in the image.
+// scaleFactor: The number of pixels per logical unit, such as 2 or 3.
+//
+// Returns: A pointer to a new Image of size (w*scaleFactor, h*scaleFactor).
+func NewScaledImage(w, h uint, color RGBA, scaleFactor float64) *Image {
+	if scaleFactor <= 0 {
+		scaleFactor = 1
+	}
+	img := NewImage(uint(math.Round(float64(w)*scaleFactor)), uint(math.Round(float64(h)*scaleFactor)), color)
+	img.scaleFactor = scaleFactor
+	return img
+}
+
+// ScaleFactor returns the number of pixels per logical unit used by draw operations (1 for regular images).
+func (i *Image) ScaleFactor() float64 {
+	if i.scaleFactor <= 0 {
+		return 1
+	}
+	return i.scaleFactor
+}
+
+// Push saves the current drawing transformation so it can be restored later with Pop.
+func (i *Image) Push() {
+	i.transforms = append(i.transforms, i.transform)
+}
+
+// Pop restores the drawing transformation saved by the most recent Push.
+// If there is nothing to restore, the transformation is reset to the identity.
+func (i *Image) Pop() {
+	if len(i.transforms) == 0 {
+		i.transform = nil
+		return
+	}
+	i.transform = i.transforms[len(i.transforms)-1]
+	i.transforms = i.transforms[:len(i.transforms)-1]
+}
+
+// sampleBilinear returns the bilinearly interpolated color at the floating-point position (x, y).
+// Positions outside the image are fully transparent, and colors are interpolated with premultiplied alpha.
+func (i *Image) sampleBilinear(x, y float64) RGBA {
+	x -= 0.5
+	y -= 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	var r, g, b, a float64
+	for _, s := range [4][3]float64{{x0, y0, (1 - fx) * (1 - fy)}, {x0 + 1, y0, fx * (1 - fy)}, {x0, y0 + 1, (1 - fx) * fy}, {x0 + 1, y0 + 1, fx * fy}} {
+		if s[2] == 0 || s[0] < 0 || s[1] < 0 || s[0] >= float64(i.Width) || s[1] >= float64(i.Height) {
+			continue
+		}
+		p := i.Pixel[int(s[0])][int(s[1])]
+		weight := s[2] * float64(p.A)
+		r += float64(p.R) * weight
+		g += float64(p.G) * weight
+		b += float64(p.B) * weight
+		a += weight
+	}
+	if a <= 0 {
+		return RGBA{}
+	}
+	return RGBA{
+		R: clampUint8(r / a),
+		G: clampUint8(g / a),
+		B: clampUint8(b / a),
+		A: clampUint8(a),
+	}
+}
+
+// resampled returns a copy of the image scaled to the given size with bilinear interpolation.
+// Unlike drawing it with a scaling transformation, the edges of the image stay fully opaque.
+func (i *Image) resampled(w, h uint) *Image {
+	respond := NewImage(w, h, RGBA{})
+	if i.Width == 0 || i.Height == 0 {
+		return respond
+	}
+	sx, sy := float64(i.Width)/float64(w), float64(i.Height)/float64(h)
+	for x := range respond.Pixel {
+		px := math.Max(0.5, math.Min(float64(i.Width)-0.5, (float64(x)+0.5)*sx))
+		for y := range respond.Pixel[x] {
+			py := math.Max(0.5, math.Min(float64(i.Height)-0.5, (float64(y)+0.5)*sy))
+			respond.Pixel[x][y] = i.sampleBilinear(px, py)
+		}
+	}
+	return respond
+}
+
+// drawTransformed composites the source image onto the image, mapping source coordinates through the transformation.
+func (i *Image) drawTransformed(src *Image, t Transform) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [4][2]float64{{0, 0}, {float64(src.Width), 0}, {0, float64(src.Height)}, {float64(src.Width), float64(src.Height)}} {
+		x, y := t.Apply(corner[0], corner[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	inverse := t.Invert()
+	x1, y1 := max(0, int(math.Floor(minX))), max(0, int(math.Floor(minY)))
+	x2, y2 := min(int(i.Width), int(math.Ceil(maxX))), min(int(i.Height), int(math.Ceil(maxY)))
+	for x := x1; x < x2; x++ {
+		for y := y1; y < y2; y++ {
+			sx, sy := inverse.Apply(float64(x)+0.5, float64(y)+0.5)
+			c := src.sampleBilinear(sx, sy)
+			if c.A == 0 {
+				continue
+			}
+			i.Set(uint(x), uint(y), c.over(i.Pixel[x][y]))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package picrocess
+
+import (
+	"math"
+	"math/rand"
+)
+
+type TransitionKind int
+
+const (
+	// TransitionCrossfade fades the first image out while the second fades in.
+	TransitionCrossfade TransitionKind = iota
+	// TransitionSlide pushes the first image out to the left while the second slides in from the right.
+	TransitionSlide
+	// TransitionZoom grows the second image from the center on top of the first.
+	TransitionZoom
+	// TransitionDissolve swaps the pixels of the first image for the second in random order.
+	TransitionDissolve
+	// TransitionCircle reveals the second image through a circle that grows from the center.
+	TransitionCircle
+	// TransitionWipe reveals the second image behind a soft edge that sweeps across from left to right.
+	TransitionWipe
+)
+
+// Transition generates the in-between frames of a transition from image a to image b.
+// Every frame has the size of a; if b has a different size, it is scaled to fit.
+// The first frame is already slightly changed from a and the last frame shows b completely,
+// so a still frame of a can be placed right before the result.
+//
+// a: The image to transition from.
+// b: The image to transition to.
+// kind: The transition effect to use.
+// frames: The number of frames to generate.
+//
+// Returns: A slice of new images containing the transition frames.
+func Transition(a, b *Image, kind TransitionKind, frames int) []*Image {
+	respond := make([]*Image, 0, max(frames, 0))
+	if frames <= 0 {
+		return respond
+	}
+	if b.Width != a.Width || b.Height != a.Height {
+		b = b.resampled(a.Width, a.Height)
+	}
+	var order [][]float64
+	if kind == TransitionDissolve {
+		random := rand.New(rand.NewSource(1))
+		order = make([][]float64, a.Width)
+		for x := range order {
+			order[x] = make([]float64, a.Height)
+			for y := range order[x] {
+				order[x][y] = random.Float64()
+			}
+		}
+	}
+	for k := 0; k < frames; k++ {
+		t := float64(k+1) / float64(frames)
+		respond = append(respond, transitionFrame(a, b, kind, t, order))
+	}
+	return respond
+}
+
+// transitionFrame renders a single frame of a transition at progress t (0 to 1).
+// a and b must have the same size; order holds the random dissolve order of every pixel.
+func transitionFrame(a, b *Image, kind TransitionKind, t float64, order [][]float64) *Image {
+	w, h := float64(a.Width), float64(a.Height)
+	switch kind {
+	case TransitionSlide:
+		frame := NewImage(a.Width, a.Height, RGBA{})
+		shift := EaseInOut(t) * w
+		frame.drawTransformed(a, NewTransform().Translate(-shift, 0))
+		frame.drawTransformed(b, NewTransform().Translate(w-shift, 0))
+		return frame
+	case TransitionZoom:
+		frame := a.clone()
+		scale := EaseOut(t)
+		if scale <= 0 {
+			return frame
+		}
+		zoomed := b.clone()
+		for x := range zoomed.Pixel {
+			for y := range zoomed.Pixel[x] {
+				zoomed.Pixel[x][y].A = uint8(float64(zoomed.Pixel[x][y].A) * t)
+			}
+		}
+		frame.drawTransformed(zoomed, NewTransform().Translate(w/2, h/2).Scale(scale, scale).Translate(-w/2, -h/2))
+		return frame
+	case TransitionDissolve:
+		frame := a.clone()
+		for x := range frame.Pixel {
+			for y := range frame.Pixel[x] {
+				if order[x][y] < t {
+					frame.Pixel[x][y] = b.Pixel[x][y]
+				}
+			}
+		}
+		return frame
+	case TransitionWipe:
+		frame := a.clone()
+		// The edge is a tenth of the width wide, and starts and ends outside the image.
+		feather := math.Max(1, w/10)
+		edge := t * (w + feather)
+		for x := range frame.Pixel {
+			coverage := math.Max(0, math.Min(1, (edge-float64(x)-0.5)/feather))
+			if coverage == 0 {
+				continue
+			}
+			for y := range frame.Pixel[x] {
+				frame.Pixel[x][y] = lerpRGBA(a.Pixel[x][y], b.Pixel[x][y], coverage)
+			}
+		}
+		return frame
+	case TransitionCircle:
+		frame := a.clone()
+		radius := t * math.Hypot(w, h) / 2
+		for x := range frame.Pixel {
+			for y := range frame.Pixel[x] {
+				d := math.Hypot(float64(x)+0.5-w/2, float64(y)+0.5-h/2)
+				coverage := math.Max(0, math.Min(1, radius-d+0.5))
+				if coverage > 0 {
+					frame.Pixel[x][y] = lerpRGBA(a.Pixel[x][y], b.Pixel[x][y], coverage)
+				}
+			}
+		}
+		return frame
+	default:
+		frame := a.clone()
+		for x := range frame.Pixel {
+			for y := range frame.Pixel[x] {
+				frame.Pixel[x][y] = lerpRGBA(a.Pixel[x][y], b.Pixel[x][y], t)
+			}
+		}
+		return frame
+	}
+}
+
+// Transition appends the frames of a transition from one image to another to the GIF, such as between the slides
+// of a slideshow, instead of a hard cut. The frames are generated as the Transition function does.
+//
+// from: The image to transition from. If it is nil, the last frame of the GIF is used.
+// to: The image to transition to.
+// kind: The transition effect to use.
+// frames: The number of frames to append.
+// opts: Optional settings; WithDelay sets the delay of every appended frame.
+//
+// Returns: An error if the Renderer fails to render a frame; the frames before it are kept.
+func (gf *GIF) Transition(from, to *Image, kind TransitionKind, frames int, opts ...Option) error {
+	if from == nil {
+		if len(gf.Image) == 0 {
+			return nil
+		}
+		gf.flatten()
+		from = Render(gf.Image[len(gf.Image)-1])
+	}
+	delay := NewOptions(opts...).Delay
+	for _, frame := range Transition(from, to, kind, frames) {
+		if err := gf.Append(frame, delay); err != nil {
+			return err
+		}
+	}
+	return nil
+}